@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lukeocodes/go-trump/events"
+)
+
+var (
+	hashtagPattern         = regexp.MustCompile(`(^|\s)#(\w+)`)
+	trailingHashtagPattern = regexp.MustCompile(`(\s+#\w+)+\s*$`)
+	linkPattern            = regexp.MustCompile(`https?://\S+`)
+	spacesPattern          = regexp.MustCompile(`[ \t]{2,}`)
+)
+
+// adapter rewrites the canonical post for a destination, following the
+// platform's length limit, hashtag convention and link handling. Publishers
+// embed it to implement Adapt.
+type adapter struct {
+	platform  string
+	mode      string
+	maxLength int
+	hashtags  string
+	links     string
+
+	prefix        string
+	suffix        string
+	substitutions map[string]string
+	appendLink    string
+
+	// openAI rewrites posts when mode is llm
+	openAI *openAIAPI
+}
+
+// newAdapter returns the adapter of a destination, with the platform's
+// defaults for whatever the destination doesn't configure. A maxLength of 0
+// means no limit.
+func newAdapter(destination *events.Destination, platform string, maxLength int, hashtags string) adapter {
+	a := adapter{
+		platform:  platform,
+		mode:      destination.Adapt,
+		maxLength: maxLength,
+		hashtags:  hashtags,
+		links:     destination.Links,
+
+		prefix:        destination.Prefix,
+		suffix:        destination.Suffix,
+		substitutions: map[string]string{},
+		appendLink:    destination.AppendLink,
+
+		openAI: newOpenAIAPI(),
+	}
+	for from, to := range destination.HashtagSubstitutions {
+		a.substitutions[strings.ToLower(strings.TrimPrefix(from, "#"))] = strings.TrimPrefix(to, "#")
+	}
+	if a.mode == "" {
+		a.mode = events.AdaptRules
+	}
+	if destination.MaxLength > 0 {
+		a.maxLength = destination.MaxLength
+	}
+	if destination.Hashtags != "" {
+		a.hashtags = destination.Hashtags
+	}
+	if a.links == "" {
+		a.links = events.LinksKeep
+	}
+	return a
+}
+
+// ReportsLinks puts destinations that append another's link after the others
+func (a adapter) ReportsLinks() bool {
+	return a.appendLink != ""
+}
+
+func (a adapter) Adapt(ctx context.Context, post *OutgoingPost) (*OutgoingPost, error) {
+	return a.adapt(ctx, post, a.maxLength), nil
+}
+
+// adapt returns a copy of the post adapted to at most maxLength characters,
+// with the destination's prefix, suffix and appended link kept whole. An LLM
+// rewrite that fails or breaks the content policy falls back to the rules, so
+// adaptation never stops a post going out.
+func (a adapter) adapt(ctx context.Context, post *OutgoingPost, maxLength int) *OutgoingPost {
+	head, tail := a.decorations(post)
+	if maxLength > 0 {
+		maxLength = max(maxLength-utf8.RuneCountInString(head+tail), 1)
+	}
+
+	text := post.Text
+	if a.mode == events.AdaptLLM {
+		rewritten, err := a.rewrite(ctx, text, maxLength)
+		if err != nil {
+			logger(logGenerator).WarnContext(ctx, "Failed to adapt post, using the rules", "platform", a.platform, "err", err)
+		} else {
+			result := &ModerationResult{}
+			checkPolicyRules(rewritten, result)
+			if result.Flagged {
+				logger(logGenerator).WarnContext(ctx, "Adapted post was blocked, using the rules", "platform", a.platform, "reasons", blockedReasons(result))
+			} else {
+				text = rewritten
+			}
+		}
+	}
+
+	// The rules always apply, so the limits hold whatever the LLM returned
+	text = a.substituteHashtags(applyHashtags(text, a.hashtags))
+	if a.links == events.LinksStrip {
+		text = linkPattern.ReplaceAllString(text, "")
+	}
+	text = strings.TrimSpace(spacesPattern.ReplaceAllString(text, " "))
+	if maxLength > 0 {
+		text = fitText(text, maxLength)
+	}
+
+	return withText(post, head+text+tail)
+}
+
+// decorations returns what goes before and after the adapted text: the prefix,
+// and the suffix followed by the appended link
+func (a adapter) decorations(post *OutgoingPost) (head, tail string) {
+	if a.prefix != "" {
+		head = a.prefix + " "
+	}
+
+	extras := []string{}
+	if a.suffix != "" {
+		extras = append(extras, a.suffix)
+	}
+	if link := webLink(post.Links[a.appendLink]); a.appendLink != "" && link != "" {
+		extras = append(extras, link)
+	}
+	if len(extras) > 0 {
+		tail = "\n\n" + strings.Join(extras, " ")
+	}
+	return head, tail
+}
+
+// substituteHashtags swaps hashtags for the destination's own
+func (a adapter) substituteHashtags(text string) string {
+	if len(a.substitutions) == 0 {
+		return text
+	}
+	return hashtagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		space, name, _ := strings.Cut(tag, "#")
+		if to, ok := a.substitutions[strings.ToLower(name)]; ok {
+			return space + "#" + to
+		}
+		return tag
+	})
+}
+
+// webLink turns a Bluesky AT URI into a link to the post on bsky.app, leaving
+// other links as they are
+func webLink(link string) string {
+	rest, ok := strings.CutPrefix(link, "at://")
+	if !ok {
+		return link
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[1] != "app.bsky.feed.post" {
+		return link
+	}
+	return "https://bsky.app/profile/" + parts[0] + "/post/" + parts[2]
+}
+
+// rewrite has a cheap LLM rewrite the post for the platform, with the model
+// configured as adapt_model
+func (a adapter) rewrite(ctx context.Context, text string, maxLength int) (string, error) {
+	// The mock generator leaves the post to the rules
+	if conf.Generator == generatorMock {
+		return text, nil
+	}
+	model := conf.AdaptModel
+
+	rules := []string{
+		fmt.Sprintf("You adapt a countdown bot's social media posts for %s.", a.platform),
+		"Rewrite the post to read naturally there, keeping its facts, numbers and tone.",
+	}
+	if maxLength > 0 {
+		rules = append(rules, fmt.Sprintf("It must be no more than %d characters.", maxLength))
+	}
+	switch a.hashtags {
+	case events.HashtagsFirst:
+		rules = append(rules, "Use at most one hashtag.")
+	case events.HashtagsStrip:
+		rules = append(rules, "Don't use hashtags.")
+	}
+	if a.links == events.LinksStrip {
+		rules = append(rules, "Leave out any links.")
+	}
+	rules = append(rules, "Only respond with the rewritten post.")
+
+	completion, err := a.openAI.chatCompletion(ctx, model, strings.Join(rules, " "), text)
+	if err != nil {
+		return "", err
+	}
+	rewritten := strings.Trim(strings.TrimSpace(completion.Text), `"`)
+	if rewritten == "" {
+		return "", fmt.Errorf("empty rewrite")
+	}
+	return rewritten, nil
+}
+
+// applyHashtags follows a hashtag convention. Stripping drops the hashtags
+// trailing a post, and turns those within a sentence into plain words.
+func applyHashtags(text, convention string) string {
+	switch convention {
+	case events.HashtagsStrip:
+		text = trailingHashtagPattern.ReplaceAllString(text, "")
+		return hashtagPattern.ReplaceAllString(text, "$1$2")
+	case events.HashtagsFirst:
+		seen := false
+		return hashtagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+			if !seen {
+				seen = true
+				return tag
+			}
+			return ""
+		})
+	}
+	return text
+}
+
+// fitText shortens text to at most limit characters, at a word boundary where
+// it can, marking the cut with an ellipsis
+func fitText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	cut := string(runes[:limit-1])
+	if !unicode.IsSpace(runes[limit-1]) {
+		// Drop the word that was cut part way through
+		if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	return strings.TrimRightFunc(cut, unicode.IsSpace) + "…"
+}
+
+// graphemeCount counts the user-perceived characters in text, as Bluesky
+// counts them towards its limit: a base character with its combining marks,
+// an emoji with its modifiers and zero-width-joined parts, a flag's pair of
+// regional indicators and a CRLF each count once
+func graphemeCount(text string) int {
+	count := 0
+	var prev rune
+	flagHalf := false
+	for i, r := range text {
+		joins := false
+		switch {
+		case i == 0:
+		case prev == '\r' && r == '\n':
+			joins = true
+		case prev == '\u200d' && !unicode.IsSpace(r):
+			joins = true
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+			joins = true
+		case r == '\u200d', r >= 0x1f3fb && r <= 0x1f3ff, r >= 0xe0020 && r <= 0xe007f:
+			// Zero-width joiners, skin tones and the tags of subdivision flags
+			joins = true
+		case r >= 0x1f1e6 && r <= 0x1f1ff && flagHalf:
+			joins = true
+		}
+
+		if r >= 0x1f1e6 && r <= 0x1f1ff {
+			flagHalf = !joins
+		} else if !joins {
+			flagHalf = false
+		}
+		if !joins {
+			count++
+		}
+		prev = r
+	}
+	return count
+}
+
+// withText returns a copy of the post with different text
+func withText(post *OutgoingPost, text string) *OutgoingPost {
+	adapted := *post
+	adapted.Text = text
+	return &adapted
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestGraphemeCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"826 days", 8},
+		{"café", 4},
+		{"cafe\u0301", 4},
+		{"👍🏽", 1},
+		{"👩‍👩‍👧‍👦 family", 8},
+		{"🇺🇸🇬🇧", 2},
+		{"🇺🇸🇬", 2},
+		{"❤️", 1},
+		{"🏴\U000E0067\U000E0062\U000E0073\U000E0063\U000E0074\U000E007F", 1},
+		{"a\r\nb", 3},
+	}
+	for _, tt := range tests {
+		if got := graphemeCount(tt.text); got != tt.want {
+			t.Errorf("graphemeCount(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+)
+
+// blueskyMessageLength is the most characters a Bluesky direct message may
+// have
+const blueskyMessageLength = 1000
+
+// alertOperator raises an operator alert. It is always logged, and is also sent
+// to operator_alert_webhook_url (Slack or Discord compatible), as a Bluesky
+// direct message to alerts.bluesky_dm and by email to alerts.email_to, when
+// they're configured. Failing to send it to one doesn't stop the others. A
+// dry run only logs it.
+func alertOperator(subject, detail string) {
+	log := logger(logModeration)
+	log.Error("Operator alert", "subject", subject, "detail", detail)
+	if conf.DryRun {
+		return
+	}
+
+	ctx := context.Background()
+	if conf.OperatorAlertWebhookURL != "" {
+		if err := sendAlertWebhook(ctx, conf.OperatorAlertWebhookURL, subject, detail); err != nil {
+			log.Error("Failed to send alert to the webhook", "err", err)
+		}
+	}
+	if conf.Alerts.BlueskyDM != "" {
+		if err := sendAlertMessage(ctx, conf.Alerts.BlueskyDM, subject, detail); err != nil {
+			log.Error("Failed to send alert as a Bluesky message", "recipient", conf.Alerts.BlueskyDM, "err", err)
+		}
+	}
+	if conf.Alerts.EmailTo != "" {
+		if err := sendAlertEmail(ctx, subject, detail); err != nil {
+			log.Error("Failed to email alert", "err", err)
+		}
+	}
+}
+
+// sendAlertWebhook posts an alert to a Slack or Discord compatible webhook
+func sendAlertWebhook(ctx context.Context, webhookURL, subject, detail string) error {
+	text := fmt.Sprintf("go-trump alert: %s\n%s", subject, detail)
+	bodyBytes, err := json.Marshal(map[string]string{
+		"text":    text,
+		"content": text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert body: %w", err)
+	}
+
+	ctx, cancel := httpRequest(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("alert request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendAlertMessage sends an alert from the bot's default Bluesky account to
+// recipient as a direct message, cut short to fit
+func sendAlertMessage(ctx context.Context, recipient, subject, detail string) error {
+	username, password := config.Getenv("BLUESKY_USERNAME"), config.Getenv("BLUESKY_PASSWORD")
+	if username == "" || password == "" {
+		return fmt.Errorf("BLUESKY_USERNAME and BLUESKY_PASSWORD environment variables must be set")
+	}
+
+	bsky := newBlueskyAPI()
+	auth, err := bsky.authenticate(ctx, username, password)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	text := fmt.Sprintf("go-trump alert: %s\n\n%s", subject, detail)
+	if runes := []rune(text); len(runes) > blueskyMessageLength {
+		text = string(runes[:blueskyMessageLength-1]) + "…"
+	}
+	return bsky.sendDirectMessage(ctx, auth.AccessJwt, recipient, text)
+}
+
+// sendAlertEmail emails an alert through alerts.smtp_addr, authenticating
+// with SMTP_USERNAME and SMTP_PASSWORD when they're set
+func sendAlertEmail(ctx context.Context, subject, detail string) error {
+	var recipients []string
+	for _, addr := range strings.Split(conf.Alerts.EmailTo, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			recipients = append(recipients, addr)
+		}
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", conf.Alerts.EmailFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "go-trump alert: "+subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(detail, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	ctx, cancel := httpRequest(ctx)
+	defer cancel()
+
+	// Alerts are sent as the email destinations send posts
+	sender := &emailPublisher{
+		addr:        conf.Alerts.SMTPAddr,
+		from:        conf.Alerts.EmailFrom,
+		usernameEnv: "SMTP_USERNAME",
+		passwordEnv: "SMTP_PASSWORD",
+	}
+	return sender.send(ctx, recipients, msg.Bytes())
+}
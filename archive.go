@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lukeocodes/go-trump/events"
+	"github.com/lukeocodes/go-trump/state"
+)
+
+// Archive represents the static site of every post made, as written to
+// posts.json and rendered into index.html
+type Archive struct {
+	Title       string            `json:"title"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Countdown   *ArchiveCountdown `json:"countdown,omitempty"`
+	Posts       []ArchivePost     `json:"posts"`
+}
+
+// ArchiveCountdown represents the countdown shown at the top of the archive
+type ArchiveCountdown struct {
+	Event      string `json:"event"`
+	DaysLeft   int    `json:"days_left"`
+	TargetDate string `json:"target_date"`
+	AsOf       string `json:"as_of"`
+}
+
+// ArchivePost represents a post in the archive
+type ArchivePost struct {
+	Date     string    `json:"date"`
+	PostType string    `json:"post_type"`
+	PostedAt time.Time `json:"posted_at"`
+	Text     string    `json:"text"`
+}
+
+var archiveTemplate = template.Must(template.New("archive").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="alternate" type="application/json" href="posts.json">
+<style>
+body { font-family: system-ui, sans-serif; max-width: 40rem; margin: 0 auto; padding: 2rem 1rem; line-height: 1.5; color: #222; }
+.countdown { text-align: center; padding: 3rem 0; border-bottom: 1px solid #ddd; }
+.countdown .days { font-size: 6rem; font-weight: 700; line-height: 1; }
+.countdown .as-of { color: #777; font-size: 0.875rem; }
+article { padding: 1rem 0; border-bottom: 1px solid #eee; white-space: pre-wrap; }
+article time { display: block; color: #777; font-size: 0.875rem; }
+</style>
+</head>
+<body>
+<header class="countdown">
+{{- with .Countdown}}
+<div class="days">{{.DaysLeft}}</div>
+<p>days until {{.Event}}, on {{.TargetDate}}</p>
+<p class="as-of">As of {{.AsOf}}</p>
+{{- else}}
+<p>The countdown is over.</p>
+{{- end}}
+</header>
+<main>
+{{- range .Posts}}
+<article><time datetime="{{.PostedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.Date}} · {{.PostType}}</time>{{.Text}}</article>
+{{- else}}
+<p>Nothing has been posted yet.</p>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+// archiveDir returns where the archive is written, archive.dir (default site)
+func archiveDir() string {
+	if dir := conf.Archive.Dir; dir != "" {
+		return dir
+	}
+	return "site"
+}
+
+// buildArchive lists every post in the history, newest first, under the
+// nearest countdown
+func buildArchive(cfg *events.File, st *state.State) *Archive {
+	a := &Archive{
+		Title:       conf.Feed.Title,
+		GeneratedAt: time.Now().UTC(),
+		Posts:       []ArchivePost{},
+	}
+
+	if upcoming := events.Upcoming(cfg.Events, now); len(upcoming) > 0 {
+		c := newCountdown(&upcoming[0], cfg)
+		a.Countdown = &ArchiveCountdown{
+			Event:      c.Name,
+			DaysLeft:   c.DaysLeft,
+			TargetDate: c.TargetDate,
+			AsOf:       now.Format("January 2, 2006"),
+		}
+	}
+
+	for i := len(st.History) - 1; i >= 0; i-- {
+		post := st.History[i]
+		if post.Skipped != "" {
+			continue
+		}
+		a.Posts = append(a.Posts, ArchivePost{
+			Date:     post.Date,
+			PostType: post.PostType,
+			PostedAt: post.PostedAt,
+			Text:     post.Text,
+		})
+	}
+
+	return a
+}
+
+// publishArchive renders the history into a static HTML and JSON site in
+// the archive directory, suitable for GitHub Pages
+func publishArchive(cfg *events.File, st *state.State) error {
+	dir := archiveDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	a := buildArchive(cfg, st)
+
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "posts.json"), data); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	var page bytes.Buffer
+	if err := archiveTemplate.Execute(&page, a); err != nil {
+		return fmt.Errorf("failed to render archive: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, "index.html"), page.Bytes()); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data, so readers never see a
+// partly written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// refreshArchive regenerates the archive after a post, when archive.dir is
+// set. An archive that can't be written is logged rather than failing the
+// post.
+func refreshArchive(cfg *events.File, st *state.State) {
+	if conf.Archive.Dir == "" {
+		return
+	}
+	if err := publishArchive(cfg, st); err != nil {
+		logger(logSite).Error("Failed to publish archive", "err", err)
+	}
+}
+
+// runPublishArchive renders the archive once, for publish-archive mode
+func runPublishArchive() error {
+	cfg, err := loadEvents()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+
+	loc, err := cfg.Location()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	now = now.In(loc)
+
+	st, err := state.Load(stateFile())
+	if err != nil {
+		return err
+	}
+
+	if err := publishArchive(cfg, st); err != nil {
+		return err
+	}
+	fmt.Printf("Archive written to %s\n", archiveDir())
+	return nil
+}
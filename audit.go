@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// redacted replaces secrets in what's recorded
+const redacted = "********"
+
+var (
+	// secretFields matches the values of JSON fields that hold credentials,
+	// even when the body was cut short before the closing quote
+	secretFields = regexp.MustCompile(`(?i)("(?:password|accessJwt|refreshJwt|token|access_token|refresh_token|api_key|secret|client_secret)"\s*:\s*")[^"]*("|$)`)
+
+	// secretParams matches the values of query and form parameters that
+	// hold credentials
+	secretParams = regexp.MustCompile(`(?i)((?:^|[?&])(?:password|token|access_token|refresh_token|api_key|key|secret|client_secret)=)[^&\s]*`)
+
+	// telegramToken matches the bot token in the path of Telegram requests
+	telegramToken = regexp.MustCompile(`/bot[0-9]+:[A-Za-z0-9_-]+`)
+)
+
+// AuditEntry represents an outbound API call in the audit file
+type AuditEntry struct {
+	Time         time.Time `json:"time"`
+	RunID        string    `json:"run_id,omitempty"`
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+	Status       int       `json:"status,omitempty"`
+	LatencyMS    int64     `json:"latency_ms"`
+	RequestBody  string    `json:"request_body,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// redactor keeps credentials out of what's recorded of outbound calls
+type redactor struct {
+	secrets *strings.Replacer
+}
+
+// newRedactor returns a redactor of the configured secrets
+func newRedactor() redactor {
+	// Longer secrets are replaced first, so one containing another is
+	// redacted whole
+	secrets := conf.Secrets()
+	slices.SortFunc(secrets, func(a, b string) int { return len(b) - len(a) })
+	var pairs []string
+	for _, secret := range secrets {
+		pairs = append(pairs, secret, redacted)
+	}
+	return redactor{strings.NewReplacer(pairs...)}
+}
+
+// redact replaces the configured secrets and anything that looks like a
+// credential in s
+func (r redactor) redact(s string) string {
+	s = r.secrets.Replace(s)
+	s = secretFields.ReplaceAllString(s, "${1}"+redacted+"${2}")
+	s = secretParams.ReplaceAllString(s, "${1}"+redacted)
+	return telegramToken.ReplaceAllString(s, "/bot"+redacted)
+}
+
+// auditTransport records each request made through it in the audit file,
+// with credentials redacted and bodies cut short at limit bytes
+type auditTransport struct {
+	redactor
+
+	next  http.RoundTripper
+	limit int
+	file  *auditFile
+}
+
+// auditFile is the audit file the clients' transports share
+type auditFile struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// setupAudit records the calls the bot makes of the APIs it uses, through
+// blueskyClient, openAIClient and httpClient, in audit.file, when it's set.
+// Other libraries' requests, such as the AWS SDK's or the trace exporter's,
+// aren't recorded, as the redactor doesn't know their credentials.
+func setupAudit() {
+	if conf.Audit.File == "" {
+		return
+	}
+
+	redactor := newRedactor()
+	file := &auditFile{out: &lumberjack.Logger{
+		Filename:   conf.Audit.File,
+		MaxSize:    conf.Audit.MaxSize,
+		MaxBackups: conf.Audit.MaxBackups,
+		Compress:   true,
+	}}
+	audit := func(next http.RoundTripper) http.RoundTripper {
+		return &auditTransport{redactor: redactor, next: next, limit: conf.Audit.BodyLimit, file: file}
+	}
+	blueskyClient = wrapTransport(blueskyClient, audit)
+	openAIClient = wrapTransport(openAIClient, audit)
+	httpClient = wrapTransport(httpClient, audit)
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := AuditEntry{
+		Time:   time.Now().UTC(),
+		RunID:  runIDFrom(req.Context()),
+		Method: req.Method,
+		URL:    t.redact(req.URL.String()),
+	}
+	if req.GetBody != nil && textual(req.Header.Get("Content-Type")) {
+		if body, err := req.GetBody(); err == nil {
+			entry.RequestBody = t.body(body)
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	entry.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		entry.Error = t.redact(err.Error())
+		t.write(entry)
+		return resp, err
+	}
+	entry.Status = resp.StatusCode
+
+	// The start of the response body is read for the entry, then put back
+	// for the caller
+	if textual(resp.Header.Get("Content-Type")) {
+		head := make([]byte, t.limit+1)
+		n, _ := io.ReadFull(resp.Body, head)
+		entry.ResponseBody = t.body(bytes.NewReader(head[:n]))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head[:n]), resp.Body), resp.Body}
+	}
+	t.write(entry)
+	return resp, nil
+}
+
+// body reads up to limit bytes of a body, redacted
+func (t *auditTransport) body(r io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(r, int64(t.limit)+1))
+	truncated := len(data) > t.limit
+	if truncated {
+		data = data[:t.limit]
+	}
+	body := t.redact(string(data))
+	if truncated {
+		body += "…"
+	}
+	return body
+}
+
+// write appends an entry to the audit file as a line of JSON
+func (t *auditTransport) write(entry AuditEntry) {
+	var line bytes.Buffer
+	enc := json.NewEncoder(&line)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return
+	}
+
+	t.file.mu.Lock()
+	defer t.file.mu.Unlock()
+	if _, err := t.file.out.Write(line.Bytes()); err != nil {
+		logger(logAudit).Error("Failed to write the audit file", "err", err)
+	}
+}
+
+// textual reports whether a body of contentType is text worth recording,
+// rather than an image or the like
+func textual(contentType string) bool {
+	return strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "text/") ||
+		strings.Contains(contentType, "form-urlencoded") ||
+		strings.Contains(contentType, "xml")
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/lukeocodes/go-trump/store"
+)
+
+// Files in a backup archive
+const (
+	backupManifest = "manifest.json"
+	backupState    = "state.json"
+	backupRuns     = "runs.jsonl"
+)
+
+// BackupManifest represents what a backup archive holds
+type BackupManifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	Store     string    `json:"store"`
+	Runs      int       `json:"runs"`
+	State     bool      `json:"state"`
+}
+
+// runBackup snapshots the state file and the store's run history, as JSONL
+// that any backend can restore, into a gzipped tar archive at path
+func runBackup(ctx context.Context, path string) error {
+	manifest := BackupManifest{CreatedAt: time.Now().UTC(), Store: conf.Store.Backend}
+
+	stateData, err := os.ReadFile(stateFile())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	manifest.State = err == nil
+
+	var runs []store.Run
+	s, err := openStore(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	if s != nil {
+		if runs, err = s.RecentRuns(ctx, math.MaxInt32); err != nil {
+			return err
+		}
+		// Oldest first, so a restore saves them in order
+		slices.Reverse(runs)
+	}
+	manifest.Runs = len(runs)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	files := map[string][]byte{
+		backupManifest: manifestData,
+		backupRuns:     store.MarshalJSONL(runs),
+	}
+	if manifest.State {
+		files[backupState] = stateData
+	}
+	for _, name := range []string{backupManifest, backupState, backupRuns} {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	// Prompts and responses in the history are decrypted, so only the owner
+	// can read the backup
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	fmt.Printf("Backed up %d runs", manifest.Runs)
+	if manifest.State {
+		fmt.Print(" and the state file")
+	}
+	fmt.Printf(" to %s\n", path)
+	return nil
+}
+
+// runRestore restores a backup archive made by runBackup into the state file
+// and the configured store. It refuses to overwrite an existing state file or
+// add to a store that already has runs.
+func runRestore(ctx context.Context, path string) error {
+	files, err := readBackup(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := files[backupManifest]; !ok {
+		return fmt.Errorf("%s is not a backup, it has no %s", path, backupManifest)
+	}
+
+	runs, err := store.UnmarshalJSONL(files[backupRuns])
+	if err != nil {
+		return fmt.Errorf("failed to read backup runs: %w", err)
+	}
+	stateData, hasState := files[backupState]
+
+	if hasState {
+		if _, err := os.Stat(stateFile()); err == nil {
+			return fmt.Errorf("state file %s already exists, move it aside to restore", stateFile())
+		}
+	}
+
+	s, err := openStore(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	if s == nil && len(runs) > 0 {
+		return fmt.Errorf("the backup has %d runs but STORE is none", len(runs))
+	}
+	if s != nil {
+		existing, err := s.RecentRuns(ctx, 1)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return errors.New("the store already has runs, restore into an empty one")
+		}
+	}
+
+	if hasState {
+		if err := writeFileAtomic(stateFile(), stateData); err != nil {
+			return fmt.Errorf("failed to write state file: %w", err)
+		}
+	}
+	for i := range runs {
+		if err := s.SaveRun(ctx, &runs[i]); err != nil {
+			return fmt.Errorf("restored %d of %d runs: %w", i, len(runs), err)
+		}
+	}
+
+	fmt.Printf("Restored %d runs", len(runs))
+	if hasState {
+		fmt.Printf(" and the state file to %s", stateFile())
+	}
+	fmt.Println()
+	return nil
+}
+
+// readBackup reads the files in a backup archive
+func readBackup(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		files[hdr.Name] = data
+	}
+}
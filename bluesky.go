@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// destinationBluesky is the destination type of a Bluesky account
+const destinationBluesky = "bluesky"
+
+// blueskyMaxLength is the most characters a Bluesky post may have
+const blueskyMaxLength = 300
+
+// blueskyConfig represents a bluesky destination's own settings
+type blueskyConfig struct {
+	// UsernameEnv and PasswordEnv name the environment variables holding the
+	// account's credentials (default BLUESKY_USERNAME and BLUESKY_PASSWORD)
+	UsernameEnv string `json:"username_env"`
+	PasswordEnv string `json:"password_env"`
+}
+
+// blueskySettings decodes a bluesky destination's own settings
+func blueskySettings(destination *events.Destination) (blueskyConfig, error) {
+	var c blueskyConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if c.UsernameEnv == "" {
+		c.UsernameEnv = "BLUESKY_USERNAME"
+	}
+	if c.PasswordEnv == "" {
+		c.PasswordEnv = "BLUESKY_PASSWORD"
+	}
+	return c, nil
+}
+
+// blueskyPublisher publishes to a Bluesky account
+type blueskyPublisher struct {
+	adapter
+
+	api         *blueskyAPI
+	name        string
+	usernameEnv string
+	passwordEnv string
+}
+
+func init() {
+	registerPublisher(destinationBluesky, validateSettings(blueskySettings), factoryOf(newBlueskyPublisher))
+}
+
+func newBlueskyPublisher(destination *events.Destination) (*blueskyPublisher, error) {
+	settings, err := blueskySettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &blueskyPublisher{
+		adapter:     newAdapter(destination, "Bluesky", blueskyMaxLength, events.HashtagsKeep),
+		api:         newBlueskyAPI(),
+		name:        destination.Name,
+		usernameEnv: settings.UsernameEnv,
+		passwordEnv: settings.PasswordEnv,
+	}, nil
+}
+
+func (p *blueskyPublisher) Name() string {
+	return p.name
+}
+
+// CheckCredentials signs in to the account
+func (p *blueskyPublisher) CheckCredentials(ctx context.Context) error {
+	username, password := config.Getenv(p.usernameEnv), config.Getenv(p.passwordEnv)
+	if username == "" {
+		return fmt.Errorf("%s environment variable not set", p.usernameEnv)
+	}
+	if password == "" {
+		return fmt.Errorf("%s environment variable not set", p.passwordEnv)
+	}
+	if _, err := p.api.authenticate(ctx, username, password); err != nil {
+		return failure(errAuth, fmt.Errorf("authentication failed: %w", err))
+	}
+	return nil
+}
+
+func (p *blueskyPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	username := config.Getenv(p.usernameEnv)
+	if username == "" {
+		return Published{}, fmt.Errorf("%s environment variable not set", p.usernameEnv)
+	}
+
+	password := config.Getenv(p.passwordEnv)
+	if password == "" {
+		return Published{}, fmt.Errorf("%s environment variable not set", p.passwordEnv)
+	}
+
+	// Authenticate and obtain access token
+	authResponse, err := p.api.authenticate(ctx, username, password)
+	health.recordAuth(err)
+	if err != nil {
+		return Published{}, failure(errAuth, fmt.Errorf("authentication failed: %w", err))
+	}
+
+	// Milestone posts may carry an image
+	var image *PostImage
+	if post.Image != "" {
+		image, err = p.api.uploadImage(ctx, authResponse.AccessJwt, post.Image, post.ImageAlt)
+		if err != nil {
+			return Published{}, fmt.Errorf("failed to upload milestone image: %w", err)
+		}
+	}
+
+	// Post message using access token. Once publishing starts it isn't
+	// cancelled by a shutdown signal, so the post is either made and recorded
+	// or not made at all, though it's still bound by the publish deadline.
+	postCtx, cancel := detach(ctx)
+	defer cancel()
+	ref, err := p.api.postMessage(postCtx, authResponse.AccessJwt, authResponse.Did, post.Text, image)
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to post message: %w", err)
+	}
+
+	return Published{Link: ref.URI, CID: ref.CID}, nil
+}
+
+// runDelete deletes the Bluesky post at an AT URI, signing in as whichever
+// configured Bluesky destination owns it
+func runDelete(ctx context.Context, uri string) error {
+	rest, ok := strings.CutPrefix(uri, "at://")
+	parts := strings.Split(rest, "/")
+	if !ok || len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return fmt.Errorf("%q is not an AT URI like at://did:plc:.../app.bsky.feed.post/...", uri)
+	}
+	repo, collection, rkey := parts[0], parts[1], parts[2]
+
+	cfg, err := loadEvents()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	destinations := cfg.Destinations
+	if len(destinations) == 0 {
+		destinations = []events.Destination{defaultDestination}
+	}
+
+	for i := range destinations {
+		if destinations[i].Type != destinationBluesky {
+			continue
+		}
+		p, err := newBlueskyPublisher(&destinations[i])
+		if err != nil {
+			return err
+		}
+		username, password := config.Getenv(p.usernameEnv), config.Getenv(p.passwordEnv)
+		if username == "" || password == "" {
+			continue
+		}
+
+		authResponse, err := p.api.authenticate(ctx, username, password)
+		if err != nil {
+			return failure(errAuth, fmt.Errorf("authentication failed for %s: %w", p.name, err))
+		}
+		if authResponse.Did != repo && authResponse.Handle != repo {
+			continue
+		}
+
+		if err := p.api.deleteRecord(ctx, authResponse.AccessJwt, authResponse.Did, collection, rkey); err != nil {
+			return fmt.Errorf("failed to delete post: %w", err)
+		}
+		fmt.Printf("Deleted %s\n", uri)
+		return nil
+	}
+
+	return fmt.Errorf("no Bluesky destination is signed in as %s", repo)
+}
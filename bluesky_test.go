@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lukeocodes/go-trump/pdstest"
+)
+
+// newPDS starts a fake PDS, returning it and the Bluesky API that sends
+// requests to it
+func newPDS(t *testing.T) (*pdstest.Server, *blueskyAPI) {
+	pds := pdstest.NewServer(t)
+	return pds, &blueskyAPI{client: pds.Client()}
+}
+
+// withPDS sends the Bluesky requests of the whole pipeline, which uses the
+// default client, to a fake PDS
+func withPDS(t *testing.T) *pdstest.Server {
+	pds := pdstest.NewServer(t)
+	client := blueskyClient
+	blueskyClient = pds.Client()
+	t.Cleanup(func() { blueskyClient = client })
+	return pds
+}
+
+func TestAuthenticate(t *testing.T) {
+	pds, bsky := newPDS(t)
+
+	auth, err := bsky.authenticate(context.Background(), pds.Handle, pds.Password)
+	if err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	if auth.Did != pds.DID || auth.Handle != pds.Handle || auth.AccessJwt == "" {
+		t.Errorf("authenticate() = %+v, want a session for %s", auth, pds.DID)
+	}
+
+	_, err = bsky.authenticate(context.Background(), pds.Handle, "wrong")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 401 {
+		t.Errorf("authenticate() with the wrong password error = %v, want a 401 APIError", err)
+	}
+}
+
+func TestPostMessage(t *testing.T) {
+	pds, bsky := newPDS(t)
+	auth, err := bsky.authenticate(context.Background(), pds.Handle, pds.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := bsky.postMessage(context.Background(), auth.AccessJwt, auth.Did, "826 days to go", nil)
+	if err != nil {
+		t.Fatalf("postMessage() error = %v", err)
+	}
+
+	records := pds.Records()
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	record := records[0]
+	if ref.URI != record.URI || ref.CID != record.CID {
+		t.Errorf("postMessage() = %+v, want the record's URI %s and CID %s", ref, record.URI, record.CID)
+	}
+	if record.Collection != "app.bsky.feed.post" || record.Value["text"] != "826 days to go" {
+		t.Errorf("created %s record %v, want an app.bsky.feed.post with the text", record.Collection, record.Value)
+	}
+	if _, ok := record.Value["embed"]; ok {
+		t.Errorf("created record %v has an embed, want none", record.Value)
+	}
+}
+
+func TestPostMessageWithImage(t *testing.T) {
+	pds, bsky := newPDS(t)
+	auth, err := bsky.authenticate(context.Background(), pds.Handle, pds.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "milestone.png")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	if err := os.WriteFile(path, png, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	image, err := bsky.uploadImage(context.Background(), auth.AccessJwt, path, "A calendar")
+	if err != nil {
+		t.Fatalf("uploadImage() error = %v", err)
+	}
+	if blobs := pds.Blobs(); len(blobs) != 1 || string(blobs[0]) != string(png) {
+		t.Fatalf("uploaded blobs %q, want the image", blobs)
+	}
+	if got := pds.Requests(pdstest.UploadBlob)[0].Header.Get("Content-Type"); got != "image/png" {
+		t.Errorf("uploaded the image as %q, want image/png", got)
+	}
+
+	if _, err := bsky.postMessage(context.Background(), auth.AccessJwt, auth.Did, "100 days to go", image); err != nil {
+		t.Fatalf("postMessage() error = %v", err)
+	}
+	embed, _ := pds.Records()[0].Value["embed"].(map[string]any)
+	images, _ := embed["images"].([]any)
+	if embed["$type"] != "app.bsky.embed.images" || len(images) != 1 {
+		t.Fatalf("created record embeds %v, want the image", embed)
+	}
+	if alt := images[0].(map[string]any)["alt"]; alt != "A calendar" {
+		t.Errorf("embedded image alt = %v, want %q", alt, "A calendar")
+	}
+}
+
+func TestPostMessageFailure(t *testing.T) {
+	pds, bsky := newPDS(t)
+	auth, err := bsky.authenticate(context.Background(), pds.Handle, pds.Password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pds.Fail(pdstest.CreateRecord, 502, "UpstreamFailure", "Upstream Failure")
+	_, err = bsky.postMessage(context.Background(), auth.AccessJwt, auth.Did, "826 days to go", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 502 {
+		t.Fatalf("postMessage() error = %v, want a 502 APIError", err)
+	}
+	if len(pds.Records()) != 0 {
+		t.Fatalf("a record was created by a failed request")
+	}
+
+	// The failure was scripted once, so the retry goes through
+	if _, err := bsky.postMessage(context.Background(), auth.AccessJwt, auth.Did, "826 days to go", nil); err != nil {
+		t.Errorf("postMessage() after the failure error = %v", err)
+	}
+
+	if _, err := bsky.postMessage(context.Background(), "expired", auth.Did, "826 days to go", nil); err == nil {
+		t.Errorf("postMessage() with an invalid token succeeded")
+	}
+}
+
+func TestBlueskyPublisherPublish(t *testing.T) {
+	pds, bsky := newPDS(t)
+	t.Setenv("BLUESKY_USERNAME", pds.Handle)
+	t.Setenv("BLUESKY_PASSWORD", pds.Password)
+
+	pub, err := newBlueskyPublisher(&defaultDestination)
+	if err != nil {
+		t.Fatalf("newBlueskyPublisher() error = %v", err)
+	}
+	pub.api = bsky
+	published, err := pub.Publish(context.Background(), &OutgoingPost{Text: "826 days to go"})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+
+	records := pds.Records()
+	if len(records) != 1 || published.Link != records[0].URI || published.CID != records[0].CID {
+		t.Errorf("Publish() = %+v, want the link and CID of the one record in %+v", published, records)
+	}
+	if n := len(pds.Requests(pdstest.CreateSession)); n != 1 {
+		t.Errorf("signed in %d times, want 1", n)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/vcr"
+)
+
+// cassette returns a recorder of the cassette name in testdata/cassettes.
+// Each of env's variables is set to its stand-in when replaying, and its real
+// value swapped for the stand-in when recording, so the cassette doesn't
+// depend on whose account recorded it. Credentials are redacted as they are
+// in the audit log.
+func cassette(t *testing.T, name string, env map[string]string) *vcr.Recorder {
+	rec := vcr.New(t, filepath.Join("testdata", "cassettes", name+".json"))
+
+	var pairs []string
+	for variable, standIn := range env {
+		if !rec.Recording() {
+			t.Setenv(variable, standIn)
+			continue
+		}
+		value := config.Getenv(variable)
+		if value == "" {
+			t.Skipf("%s must be set to record the %s cassette", variable, name)
+		}
+		pairs = append(pairs, value, standIn)
+	}
+	standIns := strings.NewReplacer(pairs...)
+	redactor := newRedactor()
+	rec.Sanitize = func(s string) string {
+		return standIns.Replace(redactor.redact(s))
+	}
+	rec.Volatile = []string{"createdAt"}
+	return rec
+}
+
+func TestChatCompletionCassette(t *testing.T) {
+	rec := cassette(t, "openai_chat_completion", map[string]string{"OPENAI_API_KEY": "sk-test"})
+	openAI := &openAIAPI{client: rec.Client()}
+
+	completion, err := openAI.chatCompletion(context.Background(), "gpt-4o-mini",
+		"You post a countdown on Bluesky. Reply with the post only.",
+		"Write today's post: 826 days until the end of the term.")
+	if err != nil {
+		t.Fatalf("chatCompletion() error = %v", err)
+	}
+	if completion.Text == "" || !strings.HasPrefix(completion.Model, "gpt-4o-mini") {
+		t.Errorf("chatCompletion() = %q from %q, want a post from gpt-4o-mini", completion.Text, completion.Model)
+	}
+	if completion.PromptTokens == 0 || completion.CompletionTokens == 0 {
+		t.Errorf("chatCompletion() used %d prompt and %d completion tokens, want both counted", completion.PromptTokens, completion.CompletionTokens)
+	}
+}
+
+func TestBlueskyPostCassette(t *testing.T) {
+	rec := cassette(t, "bluesky_post", map[string]string{
+		"BLUESKY_USERNAME": "countdown.bsky.social",
+		"BLUESKY_PASSWORD": "test-app-password",
+	})
+	bsky := &blueskyAPI{client: rec.Client()}
+
+	auth, err := bsky.authenticate(context.Background(), config.Getenv("BLUESKY_USERNAME"), config.Getenv("BLUESKY_PASSWORD"))
+	if err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	ref, err := bsky.postMessage(context.Background(), auth.AccessJwt, auth.Did, "826 days until the end of the term.", nil)
+	if err != nil {
+		t.Fatalf("postMessage() error = %v", err)
+	}
+	if !strings.HasPrefix(ref.URI, "at://"+auth.Did+"/app.bsky.feed.post/") || ref.CID == "" {
+		t.Errorf("postMessage() = %+v, want a post in %s", ref, auth.Did)
+	}
+}
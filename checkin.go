@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Signals of a check-in, besides success
+const (
+	checkInStart = "start"
+	checkInFail  = "fail"
+)
+
+// checkIn pings check_in.url, when it's set, so a dead man's switch such as
+// healthchecks.io or Dead Man's Snitch alerts when a run is missed. signal is
+// "" for success, or checkInStart or checkInFail, which are pinged at the URL
+// with the signal appended when check_in.signals is set. body, such as the
+// error of a failed run, is sent with the ping. Failing to check in is only
+// logged.
+func checkIn(ctx context.Context, signal, body string) {
+	url := conf.CheckIn.URL
+	if url == "" || (signal != "" && !conf.CheckIn.Signals) {
+		return
+	}
+	if signal != "" {
+		url = strings.TrimSuffix(url, "/") + "/" + signal
+	}
+
+	// Check-ins are still sent as the bot shuts down
+	ctx, cancel := httpRequest(context.WithoutCancel(ctx))
+	defer cancel()
+
+	if err := ping(ctx, url, body); err != nil {
+		logger(logScheduler).WarnContext(ctx, "Failed to check in", "signal", signal, "err", err)
+	}
+}
+
+// ping posts body to url, as text
+func ping(ctx context.Context, url, body string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create check-in request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("check-in request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("check-in responded %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/spf13/cobra"
+)
+
+// annotationNoConfig marks commands that run without loading the settings
+const annotationNoConfig = "no-config"
+
+// newRootCommand returns the go-trump command and its subcommands. Run
+// without one, it does what the settings say: keeps running on the schedules
+// when daemon is set, serves triggers when server.enabled is, and otherwise
+// makes a single post, so existing deployments launch as they always have.
+// The mode flags from before the commands, such as --once, still work, hidden
+// and deprecated.
+func newRootCommand() *cobra.Command {
+	var configPath, profile, logFormat, summary string
+	var settings map[string]string
+	var helpEnv, debug, dryRun bool
+	var legacy legacyFlags
+
+	root := &cobra.Command{
+		Use:   "go-trump",
+		Short: "Count down to the dates of Trump's second term on Bluesky and elsewhere",
+		Args:  cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if helpEnv || cmd.Annotations[annotationNoConfig] != "" {
+				return nil
+			}
+
+			// Only load .env file in development environment
+			if config.Getenv("ENVIRONMENT") != "production" {
+				if err := godotenv.Load(); err != nil {
+					return fmt.Errorf("error loading .env file: %w", err)
+				}
+			}
+
+			// The profile is chosen first, as it picks the variables of
+			// its own credentials and settings
+			confProfile = cmp.Or(profile, config.Getenv("PROFILE"))
+			config.UseProfile(confProfile)
+
+			// Secrets are resolved first, so settings can reference them too
+			if err := resolveSecrets(cmd.Context()); err != nil {
+				return err
+			}
+
+			var err error
+			// Flags that are settings apply as --set would
+			settings = maps.Clone(settings)
+			if settings == nil {
+				settings = map[string]string{}
+			}
+			if logFormat != "" {
+				settings["log_format"] = logFormat
+			}
+			if debug {
+				settings["debug"] = "true"
+			}
+			if dryRun {
+				settings["dry_run"] = "true"
+			}
+			if summary != "" {
+				settings["summary_file"] = summary
+			}
+			confFile, confFlags = configFile(configPath), settings
+			if conf, err = config.Load(confFile, confProfile, confFlags); err != nil {
+				return failure(errConfig, err)
+			}
+			if err := setupLogging(); err != nil {
+				return err
+			}
+			if err := setupSentry(); err != nil {
+				return err
+			}
+			setupAudit()
+			setupDebug()
+			return setupTracing(cmd.Context())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if helpEnv {
+				return printEnvHelp(cmd.OutOrStdout())
+			}
+
+			// Lambda builds hand control to the Lambda runtime
+			if startLambda != nil {
+				startLambda()
+				return nil
+			}
+
+			if ok, err := legacy.run(cmd.Context()); ok {
+				return err
+			}
+
+			switch {
+			case conf.Daemon:
+				return runDaemon(cmd.Context())
+			case conf.Server.Enabled:
+				return runServer(cmd.Context())
+			}
+			return runOnce(cmd.Context(), oneShotPostType(), false)
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return failure(errConfig, err)
+	})
+	root.Flags().BoolVar(&helpEnv, "help-env", false, "list every environment variable the bot recognises, and exit")
+	legacy.register(root)
+	root.PersistentFlags().StringVar(&configPath, "config", "", "read settings from this YAML or TOML file (default $CONFIG_FILE, or go-trump.yaml or go-trump.toml if present)")
+	root.PersistentFlags().StringVar(&profile, "profile", "", "use this profile of the config file, and its TRUMPBOT_<PROFILE>_ variables (default $PROFILE)")
+	root.PersistentFlags().StringVar(&logFormat, "log-format", "", "log as text or json, one object a line (default $LOG_FORMAT, or text)")
+	root.PersistentFlags().StringVar(&summary, "summary", "", "append a JSON summary of each run to this file, or write it to stdout with - (default $SUMMARY_FILE)")
+	root.PersistentFlags().BoolVar(&debug, "debug", false, "log outbound requests and responses in full, with credentials masked (default $DEBUG)")
+	root.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "generate, check and adapt posts, and print them instead of publishing them (default $DRY_RUN)")
+	root.PersistentFlags().StringToStringVar(&settings, "set", nil, "override a setting by its config file key, such as --set store.backend=file, over the file and environment")
+
+	root.AddCommand(
+		newRunCommand(),
+		newServeCommand(),
+		newPostCommand(),
+		newPreviewCommand(),
+		newDeleteCommand(),
+		newListCommand(),
+		newLatencyCommand(),
+		newStatusReportCommand(),
+		newExportCommand(),
+		newConfigCommand(&configPath),
+		newArchiveCommand(),
+		newMigrateCommand(),
+		newBackupCommand(),
+		newRestoreCommand(),
+	)
+	return root
+}
+
+func newRunCommand() *cobra.Command {
+	var daemon bool
+	var postType string
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Make the post due today and exit, as under cron",
+		Long: `Make the post due today and exit, as under cron. A post that was already
+made today, or falls on a skip day, is skipped.
+
+With --daemon, keep running instead and post on the schedules in the events
+file, catching up on a post that was due while the bot was down.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if daemon {
+				return runDaemon(cmd.Context())
+			}
+			return runOnce(cmd.Context(), postTypeOr(postType), false)
+		},
+	}
+	cmd.Flags().BoolVar(&daemon, "daemon", false, "keep running and post on the configured schedules")
+	cmd.Flags().StringVar(&postType, "type", "", "the post type to make (default post_type)")
+	return cmd
+}
+
+func newServeCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Serve HTTP triggers, e.g. from Cloud Scheduler on Cloud Run",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServer(cmd.Context())
+		},
+	}
+}
+
+func newPostCommand() *cobra.Command {
+	var postType string
+
+	cmd := &cobra.Command{
+		Use:   "post",
+		Short: "Make a post now, even if it was already made today",
+		Long: `Make a post now, even if it was already made today or today is a skip
+day. No lock is taken, so use it to repost by hand after a post went wrong.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runOnce(cmd.Context(), postTypeOr(postType), true)
+		},
+	}
+	cmd.Flags().StringVar(&postType, "type", "", "the post type to make (default post_type)")
+	return cmd
+}
+
+func newPreviewCommand() *cobra.Command {
+	var postType, date string
+	var samples int
+
+	cmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Generate today's post and print it for each destination without publishing it",
+		Long: `Generate sample posts for today, or another date, and print the prompt they
+were written from, then each with its length in graphemes, how it fares
+against the length limit, content policy and duplicate policy, and how each
+destination would get it. Nothing is published or recorded.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPreview(cmd.Context(), postTypeOr(postType), date, samples)
+		},
+	}
+	cmd.Flags().StringVar(&postType, "type", "", "the post type to generate (default post_type)")
+	cmd.Flags().StringVar(&date, "date", "", "generate the post for this date, as YYYY-MM-DD (default today)")
+	cmd.Flags().IntVarP(&samples, "samples", "n", 1, "how many posts to generate")
+	return cmd
+}
+
+func newDeleteCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <at-uri>",
+		Short: "Delete a Bluesky post, by its AT URI as shown by list",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDelete(cmd.Context(), args[0])
+		},
+	}
+}
+
+func newListCommand() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the latest runs and where they were published",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runList(cmd.Context(), limit)
+		},
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "how many runs to list")
+	return cmd
+}
+
+func newLatencyCommand() *cobra.Command {
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "latency",
+		Short: "Report the p50 and p95 latency of OpenAI and each destination across recent runs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLatency(cmd.Context(), limit)
+		},
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "n", 100, "how many of the latest runs to report on")
+	return cmd
+}
+
+func newStatusReportCommand() *cobra.Command {
+	var send bool
+
+	cmd := &cobra.Command{
+		Use:   "status-report",
+		Short: "Report the posts, failures, followers and engagement of the last week",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatusReport(cmd.Context(), send)
+		},
+	}
+	cmd.Flags().BoolVar(&send, "send", false, "post or message the report as status_report is configured to, instead of printing it")
+	return cmd
+}
+
+func newExportCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:       "export csv|json",
+		Short:     "Write the post history with engagement metrics to stdout",
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"csv", "json"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runExport(cmd.Context(), args[0])
+		},
+	}
+}
+
+func newConfigCommand(configPath *string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Check and show the configuration",
+	}
+
+	var online bool
+	validate := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the settings, events, prompt templates, schedules and destinations",
+		Long: `Check the settings, events, prompt templates, schedules and destinations,
+and print a report, so a mistake shows up before the next scheduled post.
+It exits non-zero if any check fails.
+
+With --online, also sign in to OpenAI, the store and every destination that
+can check its credentials without publishing.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigValidate(cmd.Context(), configFile(*configPath), online)
+		},
+	}
+	validate.Flags().BoolVar(&online, "online", false, "also check credentials against OpenAI, the store and the destinations")
+
+	var resolved bool
+	show := &cobra.Command{
+		Use:   "show",
+		Short: "Print the settings that aren't left at their defaults",
+		Long: `Print the settings that aren't left at their defaults. With --resolved,
+print every setting as a run sees it instead, and where its value came from.
+Each setting comes from the first of these that sets it:
+
+  1. a --set flag
+  2. its environment variable, with the TRUMPBOT_<PROFILE>_ or TRUMPBOT_
+     prefix, or without one
+  3. the selected profile in the config file
+  4. the config file
+  5. its default
+
+Settings that can hold credentials are masked.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printSettings(cmd.OutOrStdout(), resolved)
+		},
+	}
+	show.Flags().BoolVar(&resolved, "resolved", false, "print the effective value of every setting and where it came from")
+
+	schema := &cobra.Command{
+		Use:   "schema",
+		Short: "Print a JSON Schema of the config file",
+		Long: `Print a JSON Schema of the config file, for editors and CI to check a file
+before it's deployed. The schema applies to YAML and TOML files alike, and is
+also published as go-trump.schema.json.`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{annotationNoConfig: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(config.Schema())
+		},
+	}
+
+	cmd.AddCommand(validate, show, schema)
+	return cmd
+}
+
+func newArchiveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "archive",
+		Short: "Render every post into a static HTML and JSON site",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPublishArchive()
+		},
+	}
+}
+
+func newMigrateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending store schema migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate(cmd.Context())
+		},
+	}
+}
+
+func newBackupCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup <path>",
+		Short: "Write the state file and run history to a .tar.gz archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBackup(cmd.Context(), args[0])
+		},
+	}
+}
+
+func newRestoreCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <path>",
+		Short: "Restore a backup archive into an empty state file and store",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRestore(cmd.Context(), args[0])
+		},
+	}
+}
+
+// legacyFlags are the root flags that picked the mode before there were
+// commands, kept so cron lines like go-trump --once still run
+type legacyFlags struct {
+	once, daemon, archive, migrate bool
+	export, backup, restore        string
+}
+
+// register adds the flags to root, hidden and deprecated in favour of the
+// commands that replaced them
+func (l *legacyFlags) register(root *cobra.Command) {
+	flags := root.Flags()
+	flags.BoolVar(&l.once, "once", false, "make a single post and exit, as under cron")
+	flags.BoolVar(&l.daemon, "daemon", false, "keep running and post on the configured schedules")
+	flags.BoolVar(&l.archive, "publish-archive", false, "render every post into a static HTML and JSON site and exit")
+	flags.StringVar(&l.export, "export", "", "write the post history with engagement metrics to stdout as csv or json and exit")
+	flags.BoolVar(&l.migrate, "migrate", false, "apply pending store schema migrations and exit")
+	flags.StringVar(&l.backup, "backup", "", "write the state file and run history to a .tar.gz archive at this path and exit")
+	flags.StringVar(&l.restore, "restore", "", "restore a backup archive into an empty state file and store and exit")
+
+	for flag, command := range map[string]string{
+		"once":            "run",
+		"daemon":          "run --daemon",
+		"publish-archive": "archive",
+		"export":          "export",
+		"migrate":         "migrate",
+		"backup":          "backup",
+		"restore":         "restore",
+	} {
+		flags.MarkDeprecated(flag, fmt.Sprintf("use go-trump %s instead", command))
+	}
+}
+
+// run does what the flag given asks, as its command would, reporting whether
+// one was given. Like the commands, they win over the daemon and
+// server.enabled settings, and only one can be used at a time.
+func (l *legacyFlags) run(ctx context.Context) (bool, error) {
+	set := 0
+	for _, chosen := range []bool{l.once, l.daemon, l.archive, l.export != "", l.migrate, l.backup != "", l.restore != ""} {
+		if chosen {
+			set++
+		}
+	}
+
+	switch {
+	case set == 0:
+		return false, nil
+	case set > 1:
+		return true, failure(errConfig, errors.New("only one of --once, --daemon, --publish-archive, --export, --migrate, --backup and --restore can be used"))
+	case l.backup != "":
+		return true, runBackup(ctx, l.backup)
+	case l.restore != "":
+		return true, runRestore(ctx, l.restore)
+	case l.migrate:
+		return true, runMigrate(ctx)
+	case l.export != "":
+		return true, runExport(ctx, l.export)
+	case l.archive:
+		return true, runPublishArchive()
+	case l.daemon:
+		return true, runDaemon(ctx)
+	}
+	return true, runOnce(ctx, oneShotPostType(), false)
+}
+
+// postTypeOr returns postType, or the configured one-shot post type when
+// it's ""
+func postTypeOr(postType string) string {
+	if postType != "" {
+		return postType
+	}
+	return oneShotPostType()
+}
+
+// printEnvHelp lists every environment variable the bot recognises, with the
+// config file key and default of those that are settings
+func printEnvHelp(out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Every variable can also be given with the %s prefix, which wins over the one without it.\n", config.EnvPrefix)
+	fmt.Fprintf(w, "With a profile, %s<PROFILE>_ wins over both, such as %sSTAGING_BLUESKY_PASSWORD.\n\n", config.EnvPrefix, config.EnvPrefix)
+
+	fmt.Fprintln(w, "SETTING\tCONFIG KEY\tDEFAULT\tDESCRIPTION")
+	for _, v := range config.Vars() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Name, v.Key, v.Default, v.Help)
+	}
+
+	fmt.Fprintln(w, "\nENVIRONMENT ONLY\t\tDEFAULT\tDESCRIPTION")
+	for _, v := range config.EnvOnly {
+		fmt.Fprintf(w, "%s\t\t%s\t%s\n", v.Name, v.Default, v.Help)
+	}
+	fmt.Fprintln(w, "\nCredentials can be read from a file named by <NAME>_FILE, or be a secretsmanager:, ssm:, vault:, keychain: or op:// reference.")
+	return w.Flush()
+}
+
+// printSettings prints the settings that aren't defaults or, when resolved is
+// set, every setting with its source
+func printSettings(out io.Writer, resolved bool) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if resolved {
+		fmt.Fprintln(w, "CONFIG KEY\tVALUE\tSOURCE")
+	} else {
+		fmt.Fprintln(w, "CONFIG KEY\tVALUE")
+	}
+	for _, s := range conf.Settings() {
+		switch {
+		case resolved && s.Source == config.SourceEnv:
+			fmt.Fprintf(w, "%s\t%s\t%s (%s)\n", s.Key, s.Value, s.Source, s.Env)
+		case resolved:
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+		case s.Source != config.SourceDefault:
+			fmt.Fprintf(w, "%s\t%s\n", s.Key, s.Value)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lukeocodes/go-trump/countdown"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// defaultCelebrationPrompt is posted once the final event's target has passed
+const defaultCelebrationPrompt = `Today is {{.Today}}. The countdown is over: {{.Event.Name}} arrived on {{.TargetDate}}. Write a short, joyful post celebrating the end of the countdown and thanking everyone who followed along. {{.Event.PromptHints}}`
+
+// defaultWrapUpPrompt is used for each post of a wrap-up series
+const defaultWrapUpPrompt = `Today is {{.Today}}. The countdown to {{.Event.Name}} ended on {{.TargetDate}}. This is post {{.WrapUpDay}} of a {{.WrapUpDays}} day wrap-up series, so write a short, reflective post looking back on the countdown
+{{- if eq .WrapUpDay .WrapUpDays}} and say goodbye, since it's the final post{{end}}. {{.Event.PromptHints}}`
+
+// waitForFinalMoment holds a run that starts shortly before the countdown ends
+// until the exact moment it does, within the configured final_post_wait, so the
+// completion post isn't a day early or late
+func waitForFinalMoment(ctx context.Context, cfg *events.File) {
+	wait, err := cfg.FinalPostWaitDuration()
+	if err != nil || wait <= 0 || cfg.OnComplete.ActionOrDefault() == events.CompleteShutdown {
+		return
+	}
+
+	final, ok := events.Last(cfg.Events)
+	if !ok {
+		return
+	}
+
+	remaining := final.Time().Sub(now)
+	if remaining <= 0 || remaining > wait {
+		return
+	}
+
+	logger(logScheduler).InfoContext(ctx, "Waiting for the countdown to end", "event", final.Name, "remaining", remaining.Round(time.Second))
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(remaining):
+	}
+	now = time.Now().In(now.Location())
+}
+
+// errCountdownOver is returned when there is nothing left to post and the bot
+// should shut down
+var errCountdownOver = errors.New("countdown is over, nothing left to post")
+
+// errSkipPost is returned when a post type has nothing to post this time, but
+// the bot should keep running
+var errSkipPost = errors.New("nothing to post")
+
+// getCompletionPost decides what to post once every event's target has passed,
+// according to the on_complete config. It returns errCountdownOver when there
+// is nothing left to post and the bot should shut down.
+func getCompletionPost(ctx context.Context, cfg *events.File, schedule *events.Schedule) (*Completion, *events.Milestone, error) {
+	final, ok := events.Last(cfg.Events)
+	if !ok {
+		return nil, nil, fmt.Errorf("no enabled events configured: %w", errCountdownOver)
+	}
+
+	action := cfg.OnComplete.ActionOrDefault()
+
+	// Only the daily post carries on once the countdown is over
+	if action != events.CompleteSwitch && schedule.Name != events.PostTypeDaily {
+		return nil, nil, fmt.Errorf("countdown to %s is over, skipping %s post: %w", final.Name, schedule.Name, errSkipPost)
+	}
+
+	if action == events.CompleteSwitch {
+		upcoming := events.Upcoming(cfg.OnComplete.Events, now)
+		if len(upcoming) == 0 {
+			return nil, nil, fmt.Errorf("every event, including those switched to, has passed: %w", errCountdownOver)
+		}
+		logger(logGenerator).InfoContext(ctx, "Countdown complete, switching events", "event", final.Name, "next_event", upcoming[0].Name)
+
+		return getPost(ctx, cfg, upcoming, schedule)
+	}
+
+	day := countdown.DaysBetween(final.Time().In(now.Location()), now) + 1
+	seriesDays := cfg.OnComplete.SeriesDays()
+	if day > seriesDays {
+		return nil, nil, fmt.Errorf("countdown to %s completed on %s: %w", final.Name, final.Time().Format("January 2, 2006"), errCountdownOver)
+	}
+
+	data := newPromptData(ctx, cfg, []events.Event{*final})
+	data.WrapUpDay = day
+	data.WrapUpDays = seriesDays
+
+	tmpl := cfg.OnComplete.Prompt
+	variant := "on_complete:" + action
+	if tmpl == "" {
+		tmpl = defaultCelebrationPrompt
+		if action == events.CompleteWrapUp {
+			tmpl = defaultWrapUpPrompt
+		}
+	}
+
+	prompt, err := renderPrompt(tmpl, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := requestPost(ctx, prompt, final.Hashtag, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get AI response: %w", err)
+	}
+
+	response.Variant = variant
+	return response, nil, nil
+}
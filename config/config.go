@@ -0,0 +1,829 @@
+// Package config holds the bot's settings, read from an optional YAML or TOML
+// file and overridden by environment variables. Credentials aren't part of
+// it: they're only ever read from the environment.
+//
+// Each setting is resolved in a fixed order, where a later source wins over
+// an earlier one: the defaults, then the config file, then the selected
+// profile of the config file, then the environment, then flags given on the
+// command line.
+package config
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents every setting of the bot. Each field can be set in the
+// config file under its yaml or toml key, and overridden by the environment
+// variable in its env tag, which help describes for --help-env. Settings
+// tagged secret can hold credentials, and are masked when shown, and enum
+// lists the values of settings that only take a few.
+type Config struct {
+	// EventsFile is the events file, or "" for events.json, falling back to
+	// the built-in events when there's none
+	EventsFile string `yaml:"events_file" toml:"events_file" env:"EVENTS_FILE" help:"events file, by default events.json or the built-in events"`
+	StateFile  string `yaml:"state_file" toml:"state_file" env:"STATE_FILE" help:"where the bot remembers what it has posted"`
+
+	// SummaryFile is where a JSON summary of each run is appended, "-" for
+	// stdout, or "" for nowhere
+	SummaryFile string `yaml:"summary_file" toml:"summary_file" env:"SUMMARY_FILE" help:"file that a JSON summary of each run is appended to, or - for stdout"`
+
+	// PostType is the post made by a one-shot run
+	PostType string `yaml:"post_type" toml:"post_type" env:"POST_TYPE" help:"post type a one-shot run makes"`
+
+	// Daemon runs on the configured schedules when no mode flag is given
+	Daemon bool `yaml:"daemon" toml:"daemon" env:"DAEMON" help:"run on the schedules when no command is given"`
+
+	// HealthPort serves health checks and metrics alongside the daemon, when
+	// set
+	HealthPort string `yaml:"health_port" toml:"health_port" env:"HEALTH_PORT" help:"port to serve health checks and metrics on alongside the daemon"`
+
+	// PublishConcurrency is how many destinations are published to at once
+	PublishConcurrency int `yaml:"publish_concurrency" toml:"publish_concurrency" env:"PUBLISH_CONCURRENCY" help:"how many destinations are published to at once"`
+
+	// Generator is what writes the posts: "openai", or "mock", which renders
+	// MockText instead, so the bot runs end to end without an OpenAI key
+	Generator string `yaml:"generator" toml:"generator" env:"GENERATOR" enum:"openai,mock" help:"openai, or mock for posts rendered from mock_text without an OpenAI key"`
+
+	// MockText is the template the mock generator renders as each post, with
+	// the same data as the prompts, or canned text (default the day count)
+	MockText string `yaml:"mock_text" toml:"mock_text" env:"MOCK_TEXT" help:"template the mock generator renders as each post"`
+
+	// AdaptModel is the model that rewrites posts for other platforms
+	AdaptModel string `yaml:"adapt_model" toml:"adapt_model" env:"ADAPT_MODEL" help:"model that rewrites posts for other platforms"`
+
+	// ModerationMode is "rules", "llm" or "both"
+	ModerationMode string `yaml:"moderation_mode" toml:"moderation_mode" env:"MODERATION_MODE" enum:"rules,llm,both" help:"rules, llm or both"`
+
+	// LogLevel is the least severe level logged
+	LogLevel string `yaml:"log_level" toml:"log_level" env:"LOG_LEVEL" enum:"debug,info,warn,error" help:"debug, info, warn or error"`
+
+	// LogFormat is "text", key=value pairs, or "json", one object a line
+	LogFormat string `yaml:"log_format" toml:"log_format" env:"LOG_FORMAT" enum:"text,json" help:"text or json"`
+
+	// LogFile is where logs are written instead of stderr, rotated by
+	// LogRotation
+	LogFile     string      `yaml:"log_file" toml:"log_file" env:"LOG_FILE" help:"file that logs are written to instead of stderr"`
+	LogRotation LogRotation `yaml:"log_rotation" toml:"log_rotation"`
+
+	// Debug logs outbound requests and responses in full, and everything
+	// else at the debug level
+	Debug bool `yaml:"debug" toml:"debug" env:"DEBUG" help:"log outbound requests and responses in full, with credentials masked"`
+
+	// DryRun generates, checks and adapts posts, then prints them rather
+	// than publishing them, without signing in to any destination
+	DryRun bool `yaml:"dry_run" toml:"dry_run" env:"DRY_RUN" help:"print posts instead of publishing them, recording nothing"`
+
+	// OperatorAlertWebhookURL is a Slack or Discord compatible webhook that
+	// alerts are sent to
+	OperatorAlertWebhookURL string `yaml:"operator_alert_webhook_url" toml:"operator_alert_webhook_url" env:"OPERATOR_ALERT_WEBHOOK_URL" secret:"true" help:"Slack or Discord compatible webhook for operator alerts"`
+
+	// SentryDSN is the Sentry project that failed runs and panics are
+	// reported to
+	SentryDSN string `yaml:"sentry_dsn" toml:"sentry_dsn" env:"SENTRY_DSN" secret:"true" help:"Sentry project that failures are reported to"`
+
+	// RedisURL is the Redis server of the redis store and lock backends
+	RedisURL string `yaml:"redis_url" toml:"redis_url" env:"REDIS_URL" secret:"true" help:"Redis server of the redis store and lock"`
+
+	Schedule   Schedule   `yaml:"schedule" toml:"schedule"`
+	Server     Server     `yaml:"server" toml:"server"`
+	Store      Store      `yaml:"store" toml:"store"`
+	Lock       Lock       `yaml:"lock" toml:"lock"`
+	Feed       Feed       `yaml:"feed" toml:"feed"`
+	Archive    Archive    `yaml:"archive" toml:"archive"`
+	Retry      Retry      `yaml:"retry" toml:"retry"`
+	Duplicates Duplicates `yaml:"duplicates" toml:"duplicates"`
+	Timeouts   Timeouts   `yaml:"timeouts" toml:"timeouts"`
+	Metrics    Metrics    `yaml:"metrics" toml:"metrics"`
+	Alerts     Alerts     `yaml:"alerts" toml:"alerts"`
+	Audit      Audit      `yaml:"audit" toml:"audit"`
+	CheckIn    CheckIn    `yaml:"check_in" toml:"check_in"`
+
+	StatusReport StatusReport `yaml:"status_report" toml:"status_report"`
+
+	// sources records where each setting that isn't a default came from,
+	// by its file key
+	sources map[string]Source
+}
+
+// Source represents where the value of a setting came from
+type Source string
+
+// Sources of settings, from the weakest to the strongest
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceProfile Source = "profile"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Schedule represents the daemon's defaults for schedules that don't set
+// their own
+type Schedule struct {
+	// PostTime is when the daily post is made, as HH:MM, when the events
+	// file has no schedules
+	PostTime    string        `yaml:"post_time" toml:"post_time" env:"POST_TIME" help:"daily post time, HH:MM, when there are no schedules"`
+	Jitter      time.Duration `yaml:"jitter" toml:"jitter" env:"POST_JITTER" help:"default random jitter on posting times"`
+	MaxLateness time.Duration `yaml:"max_lateness" toml:"max_lateness" env:"MAX_LATENESS" help:"default lateness after which a missed post is skipped"`
+}
+
+// Server represents the HTTP trigger server
+type Server struct {
+	// Enabled serves triggers when no mode flag is given
+	Enabled            bool   `yaml:"enabled" toml:"enabled" env:"SERVER" help:"serve HTTP triggers when no command is given"`
+	Port               string `yaml:"port" toml:"port" env:"PORT" help:"port the trigger server listens on"`
+	OIDCAudience       string `yaml:"oidc_audience" toml:"oidc_audience" env:"OIDC_AUDIENCE" help:"audience of the OIDC tokens triggers must carry"`
+	OIDCServiceAccount string `yaml:"oidc_service_account" toml:"oidc_service_account" env:"OIDC_SERVICE_ACCOUNT" help:"service account the OIDC tokens must be for"`
+}
+
+// Store backends
+const (
+	StoreSQLite   = "sqlite"
+	StoreFile     = "file"
+	StorePostgres = "postgres"
+	StoreRedis    = "redis"
+	StoreDynamoDB = "dynamodb"
+	StoreS3       = "s3"
+	StoreGCS      = "gcs"
+	StoreNone     = "none"
+)
+
+// Store represents where the run history is kept
+type Store struct {
+	Backend string `yaml:"backend" toml:"backend" env:"STORE" enum:"sqlite,file,postgres,redis,dynamodb,s3,gcs,none" help:"sqlite, file, postgres, redis, dynamodb, s3, gcs or none"`
+
+	// Path is the SQLite database, JSONL file or object key, or "" for the
+	// backend's default
+	Path          string `yaml:"path" toml:"path" env:"STORE_PATH" help:"SQLite database, JSONL file or object key"`
+	DatabaseURL   string `yaml:"database_url" toml:"database_url" env:"DATABASE_URL" secret:"true" help:"PostgreSQL connection URL"`
+	KeyPrefix     string `yaml:"key_prefix" toml:"key_prefix" env:"STORE_KEY_PREFIX" help:"key prefix of the redis store"`
+	Table         string `yaml:"table" toml:"table" env:"STORE_TABLE" help:"DynamoDB table of the dynamodb store"`
+	Bucket        string `yaml:"bucket" toml:"bucket" env:"STORE_BUCKET" help:"bucket of the s3 and gcs stores"`
+	AutoMigrate   bool   `yaml:"auto_migrate" toml:"auto_migrate" env:"STORE_AUTO_MIGRATE" help:"apply pending schema migrations on startup"`
+	RetentionDays int    `yaml:"retention_days" toml:"retention_days" env:"STORE_RETENTION_DAYS" help:"prune runs older than this many days, or 0 to keep them"`
+	RetentionRuns int    `yaml:"retention_runs" toml:"retention_runs" env:"STORE_RETENTION_RUNS" help:"keep only this many runs, or 0 for all"`
+}
+
+// Lock represents the distributed lock taken before each post
+type Lock struct {
+	// Backend is "redis", "dynamodb" or "store", or "" for no lock
+	Backend   string `yaml:"backend" toml:"backend" env:"LOCK_BACKEND" enum:",redis,dynamodb,store" help:"redis, dynamodb or store, or none"`
+	Table     string `yaml:"table" toml:"table" env:"LOCK_TABLE" help:"DynamoDB table of the dynamodb lock"`
+	KeyPrefix string `yaml:"key_prefix" toml:"key_prefix" env:"LOCK_KEY_PREFIX" help:"key prefix of locks"`
+}
+
+// Feed represents the Atom or RSS feed of the latest posts
+type Feed struct {
+	Title string `yaml:"title" toml:"title" env:"FEED_TITLE" help:"title of the feed"`
+	URL   string `yaml:"url" toml:"url" env:"FEED_URL" help:"public URL of the feed"`
+
+	// Format is "atom" or "rss"
+	Format string `yaml:"format" toml:"format" env:"FEED_FORMAT" enum:"atom,rss" help:"atom or rss"`
+
+	// File is regenerated after each post, when set
+	File string `yaml:"file" toml:"file" env:"FEED_FILE" help:"feed file regenerated after each post"`
+}
+
+// Archive represents the static site of every post
+type Archive struct {
+	// Dir is regenerated after each post, when set
+	Dir string `yaml:"dir" toml:"dir" env:"ARCHIVE_DIR" help:"static site regenerated after each post"`
+}
+
+// Retry represents how often and how patiently a failed post is retried
+type Retry struct {
+	Attempts int           `yaml:"attempts" toml:"attempts" env:"RETRY_ATTEMPTS" help:"attempts at a failed post"`
+	Delay    time.Duration `yaml:"delay" toml:"delay" env:"RETRY_DELAY" help:"delay before the first retry"`
+	MaxDelay time.Duration `yaml:"max_delay" toml:"max_delay" env:"RETRY_MAX_DELAY" help:"longest delay between retries"`
+}
+
+// Duplicates represents how a post too similar to a recent one is caught
+type Duplicates struct {
+	// Window is how many recent posts are compared against, or 0 for none
+	Window int `yaml:"window" toml:"window" env:"DUPLICATE_WINDOW" help:"how many recent posts a post is compared against"`
+
+	// Threshold is the similarity, from 0 to 1, at which a post is
+	// regenerated
+	Threshold float64 `yaml:"threshold" toml:"threshold" env:"DUPLICATE_THRESHOLD" help:"similarity, from 0 to 1, at which a post is regenerated"`
+
+	// Regenerations is how many times a post is regenerated before it's
+	// posted anyway
+	Regenerations int `yaml:"regenerations" toml:"regenerations" env:"DUPLICATE_REGENERATIONS" help:"regenerations before a similar post is made anyway"`
+}
+
+// Timeouts represents the deadlines of outbound requests
+type Timeouts struct {
+	Bluesky time.Duration `yaml:"bluesky" toml:"bluesky" env:"BLUESKY_TIMEOUT" help:"deadline of each Bluesky request"`
+	OpenAI  time.Duration `yaml:"openai" toml:"openai" env:"OPENAI_TIMEOUT" help:"deadline of each OpenAI request"`
+	HTTP    time.Duration `yaml:"http" toml:"http" env:"HTTP_TIMEOUT" help:"deadline of other outbound requests"`
+
+	// Publish is shared by every destination a post is published to
+	Publish time.Duration `yaml:"publish" toml:"publish" env:"PUBLISH_TIMEOUT" help:"deadline of publishing a post to every destination"`
+}
+
+// Metrics represents where the metrics of one-shot runs are pushed. The
+// daemon and trigger server serve them on /metrics instead.
+type Metrics struct {
+	PushgatewayURL string `yaml:"pushgateway_url" toml:"pushgateway_url" env:"PUSHGATEWAY_URL" help:"Prometheus Pushgateway that one-shot runs push metrics to"`
+	Job            string `yaml:"job" toml:"job" env:"METRICS_JOB" help:"job that metrics are pushed as"`
+}
+
+// Alerts represents where operator alerts are sent, besides
+// operator_alert_webhook_url
+type Alerts struct {
+	// BlueskyDM is the handle or DID that the bot's Bluesky account
+	// messages alerts to
+	BlueskyDM string `yaml:"bluesky_dm" toml:"bluesky_dm" env:"ALERT_BLUESKY_DM" help:"Bluesky handle that the bot's account messages alerts to"`
+
+	// EmailTo lists, separated by commas, the addresses alerts are emailed
+	// to from EmailFrom, through the SMTP server at SMTPAddr
+	EmailTo   string `yaml:"email_to" toml:"email_to" env:"ALERT_EMAIL_TO" help:"addresses, separated by commas, that alerts are emailed to"`
+	EmailFrom string `yaml:"email_from" toml:"email_from" env:"ALERT_EMAIL_FROM" help:"sender of alert emails"`
+	SMTPAddr  string `yaml:"smtp_addr" toml:"smtp_addr" env:"ALERT_SMTP_ADDR" help:"SMTP server, as host:port, that alert emails are sent through"`
+}
+
+// LogRotation represents when log_file is rotated, once it reaches MaxSize
+// megabytes or every Interval, and how long the rotated files are kept
+type LogRotation struct {
+	MaxSize    int           `yaml:"max_size" toml:"max_size" env:"LOG_MAX_SIZE" help:"megabytes the log file reaches before it's rotated"`
+	Interval   time.Duration `yaml:"interval" toml:"interval" env:"LOG_ROTATE_INTERVAL" help:"how often the log file is rotated whatever its size, or 0 for only by size"`
+	MaxAge     int           `yaml:"max_age" toml:"max_age" env:"LOG_MAX_AGE" help:"days rotated log files are kept, or 0 for no limit"`
+	MaxBackups int           `yaml:"max_backups" toml:"max_backups" env:"LOG_MAX_BACKUPS" help:"rotated log files kept, or 0 for no limit"`
+	Compress   bool          `yaml:"compress" toml:"compress" env:"LOG_COMPRESS" help:"gzip rotated log files"`
+}
+
+// Audit represents the file that outbound API calls are recorded in, which is
+// rotated once it reaches MaxSize megabytes, keeping MaxBackups compressed
+// files
+type Audit struct {
+	File       string `yaml:"file" toml:"file" env:"AUDIT_FILE" help:"file that outbound API calls are recorded in"`
+	MaxSize    int    `yaml:"max_size" toml:"max_size" env:"AUDIT_MAX_SIZE" help:"megabytes the audit file reaches before it's rotated"`
+	MaxBackups int    `yaml:"max_backups" toml:"max_backups" env:"AUDIT_MAX_BACKUPS" help:"rotated audit files kept, or 0 for all"`
+
+	// BodyLimit is how much of each request and response body is recorded
+	BodyLimit int `yaml:"body_limit" toml:"body_limit" env:"AUDIT_BODY_LIMIT" help:"bytes of each request and response body recorded"`
+}
+
+// CheckIn represents the dead man's switch, such as a healthchecks.io check,
+// that each run pings so a missed run raises an alert
+type CheckIn struct {
+	URL string `yaml:"url" toml:"url" env:"CHECK_IN_URL" secret:"true" help:"URL that each run pings, such as a healthchecks.io check"`
+
+	// Signals pings URL/start as a run starts and URL/fail when it fails,
+	// which Dead Man's Snitch doesn't support
+	Signals bool `yaml:"signals" toml:"signals" env:"CHECK_IN_SIGNALS" help:"also ping /start as a run starts and /fail when it fails"`
+}
+
+// StatusReport represents the weekly report on the bot's health, which the
+// daemon posts from the bot's Bluesky account, sends to BlueskyDM, or both
+type StatusReport struct {
+	Post      bool   `yaml:"post" toml:"post" env:"STATUS_REPORT_POST" help:"post the weekly status report from the bot's Bluesky account"`
+	BlueskyDM string `yaml:"bluesky_dm" toml:"bluesky_dm" env:"STATUS_REPORT_BLUESKY_DM" help:"Bluesky handle that the bot's account messages the weekly status report to"`
+
+	// Cron is when the daemon makes the report, in the reference timezone
+	Cron string `yaml:"cron" toml:"cron" env:"STATUS_REPORT_CRON" help:"cron expression for when the daemon makes the status report"`
+}
+
+// Enabled reports whether the status report goes anywhere
+func (s StatusReport) Enabled() bool {
+	return s.Post || s.BlueskyDM != ""
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() *Config {
+	return &Config{
+		StateFile:          "state.json",
+		PostType:           "daily",
+		PublishConcurrency: 4,
+		Generator:          "openai",
+		AdaptModel:         "gpt-4o-mini",
+		ModerationMode:     "both",
+		LogLevel:           "info",
+		LogFormat:          "text",
+		LogRotation: LogRotation{
+			MaxSize:    100,
+			MaxAge:     90,
+			MaxBackups: 10,
+			Compress:   true,
+		},
+		Schedule: Schedule{PostTime: "09:00"},
+		Server:   Server{Port: "8080"},
+		Store: Store{
+			Backend:     StoreSQLite,
+			KeyPrefix:   "go-trump:",
+			AutoMigrate: true,
+		},
+		Lock: Lock{KeyPrefix: "go-trump:"},
+		Feed: Feed{Title: "Go Trump countdown", Format: "atom"},
+		Retry: Retry{
+			Attempts: 3,
+			Delay:    30 * time.Second,
+			MaxDelay: 5 * time.Minute,
+		},
+		Duplicates: Duplicates{Window: 7, Threshold: 0.6, Regenerations: 2},
+		Timeouts: Timeouts{
+			Bluesky: 30 * time.Second,
+			OpenAI:  60 * time.Second,
+			HTTP:    15 * time.Second,
+			Publish: 5 * time.Minute,
+		},
+		Metrics: Metrics{Job: "go-trump"},
+		Audit:   Audit{MaxSize: 10, MaxBackups: 5, BodyLimit: 2048},
+		CheckIn: CheckIn{Signals: true},
+
+		StatusReport: StatusReport{Cron: "0 9 * * 1"},
+	}
+}
+
+// Load reads the config file at path over the defaults, unless path is "",
+// and the settings of its profile, unless profile is "". It then applies
+// overrides from the environment, then flags, keyed by the setting's file
+// key, and validates the result. The file is YAML or TOML, by its extension.
+func Load(path, profile string, flags map[string]string) (*Config, error) {
+	c := Default()
+	if path != "" {
+		if err := c.read(path, profile); err != nil {
+			return nil, err
+		}
+	} else if profile != "" {
+		return nil, fmt.Errorf("profile %q needs a config file to be defined in", profile)
+	}
+	if err := c.applyEnv(LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := c.applyFlags(flags); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// read decodes the config file at path, then the settings of its profile,
+// unless profile is "". Keys that aren't settings are rejected, in every
+// profile, so a typo isn't silently ignored.
+func (c *Config) read(path, profile string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if data, err = decryptSOPS(path, data); err != nil {
+			return err
+		}
+		err = c.readYAML(data, profile)
+	case ".toml":
+		if err := rejectSOPSTOML(path, data); err != nil {
+			return err
+		}
+		err = c.readTOML(data, profile)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml, not %q", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) readYAML(data []byte, profile string) error {
+	file := struct {
+		Config   `yaml:",inline"`
+		Profiles map[string]yaml.Node `yaml:"profiles"`
+	}{Config: *c}
+	if err := decodeYAML(data, &file); err != nil {
+		return err
+	}
+
+	var keys map[string]any
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	delete(keys, "profiles")
+	file.setYAMLSources(keys, "", SourceFile)
+
+	for name, node := range file.Profiles {
+		profileData, err := yaml.Marshal(&node)
+		if err != nil {
+			return err
+		}
+		settings := Default()
+		if name == profile {
+			settings = &file.Config
+		}
+		if err := decodeYAML(profileData, settings); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+		if name == profile {
+			var keys map[string]any
+			if err := node.Decode(&keys); err != nil {
+				return err
+			}
+			settings.setYAMLSources(keys, "", SourceProfile)
+		}
+	}
+	if _, ok := file.Profiles[profile]; profile != "" && !ok {
+		return fmt.Errorf("no profile %q", profile)
+	}
+
+	*c = file.Config
+	return nil
+}
+
+// decodeYAML decodes data into v, rejecting keys that aren't fields of v
+func decodeYAML(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func (c *Config) readTOML(data []byte, profile string) error {
+	file := struct {
+		Config
+		Profiles map[string]toml.Primitive `toml:"profiles"`
+	}{Config: *c}
+	md, err := toml.Decode(string(data), &file)
+	if err != nil {
+		return err
+	}
+
+	for name, primitive := range file.Profiles {
+		settings := Default()
+		if name == profile {
+			settings = &file.Config
+		}
+		if err := md.PrimitiveDecode(primitive, settings); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	if _, ok := file.Profiles[profile]; profile != "" && !ok {
+		return fmt.Errorf("no profile %q", profile)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("unknown setting %q", undecoded[0].String())
+	}
+
+	for _, key := range md.Keys() {
+		name, ok := strings.CutPrefix(key.String(), "profiles.")
+		if !ok {
+			file.setSource(key.String(), SourceFile)
+		} else if key, ok := strings.CutPrefix(name, profile+"."); ok && profile != "" {
+			file.setSource(key, SourceProfile)
+		}
+	}
+
+	*c = file.Config
+	return nil
+}
+
+// applyEnv overrides each setting whose environment variable is set and not
+// empty
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	walk(reflect.ValueOf(c).Elem(), "", func(v reflect.Value, key string, field reflect.StructField) {
+		env := field.Tag.Get("env")
+		value, ok := lookup(env)
+		if !ok || value == "" {
+			return
+		}
+		if err := setValue(v, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q, %w", env, value, err))
+			return
+		}
+		c.setSource(key, SourceEnv)
+	})
+	return errors.Join(errs...)
+}
+
+// applyFlags overrides each setting in flags, by its file key, rejecting keys
+// that aren't settings
+func (c *Config) applyFlags(flags map[string]string) error {
+	var errs []error
+	known := make(map[string]bool, len(flags))
+	walk(reflect.ValueOf(c).Elem(), "", func(v reflect.Value, key string, field reflect.StructField) {
+		value, ok := flags[key]
+		if !ok {
+			return
+		}
+		known[key] = true
+		if err := setValue(v, value); err != nil {
+			errs = append(errs, fmt.Errorf("--set %s: invalid value %q, %w", key, value, err))
+			return
+		}
+		c.setSource(key, SourceFlag)
+	})
+	for key := range flags {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("--set %s: unknown setting", key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setYAMLSources records every setting in the decoded YAML keys as coming
+// from source
+func (c *Config) setYAMLSources(keys map[string]any, prefix string, source Source) {
+	for key, value := range keys {
+		if nested, ok := value.(map[string]any); ok {
+			c.setYAMLSources(nested, prefix+key+".", source)
+			continue
+		}
+		c.setSource(prefix+key, source)
+	}
+}
+
+func (c *Config) setSource(key string, source Source) {
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
+	}
+	c.sources[key] = source
+}
+
+// Setting represents the resolved value of one setting, and where it came
+// from
+type Setting struct {
+	Key    string
+	Env    string
+	Value  string
+	Source Source
+}
+
+// Settings returns every setting with its value, masking those that can hold
+// credentials
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	walk(reflect.ValueOf(c).Elem(), "", func(v reflect.Value, key string, field reflect.StructField) {
+		value := formatValue(v)
+		if field.Tag.Get("secret") == "true" && value != "" {
+			value = "********"
+		}
+		settings = append(settings, Setting{
+			Key:    key,
+			Env:    field.Tag.Get("env"),
+			Value:  value,
+			Source: cmp.Or(c.sources[key], SourceDefault),
+		})
+	})
+	return settings
+}
+
+// Secrets returns the values of the secret settings and the credentials of
+// EnvOnly that are set, so they can be kept out of what's recorded
+func (c *Config) Secrets() []string {
+	var secrets []string
+	walk(reflect.ValueOf(c).Elem(), "", func(v reflect.Value, key string, field reflect.StructField) {
+		if field.Tag.Get("secret") == "true" && !v.IsZero() {
+			secrets = append(secrets, formatValue(v))
+		}
+	})
+	for _, v := range EnvOnly {
+		if value := Getenv(v.Name); v.Secret && value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	return secrets
+}
+
+// walk calls fn with every setting in v, its dotted file key and its field
+func walk(v reflect.Value, prefix string, fn func(v reflect.Value, key string, field reflect.StructField)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeFor[time.Duration]() {
+			walk(v.Field(i), key+".", fn)
+			continue
+		}
+		if field.Tag.Get("env") != "" {
+			fn(v.Field(i), key, field)
+		}
+	}
+}
+
+// setValue parses value into the setting v
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == reflect.TypeFor[time.Duration]() {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.New("expected a duration like 30s or 5m")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("expected true or false")
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("expected a whole number")
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("expected a number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// Validate checks every setting, returning an error naming each one that's
+// invalid by its file key and environment variable
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, env, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s (%s) %s", key, env, fmt.Sprintf(format, args...)))
+	}
+
+	if c.PublishConcurrency < 1 {
+		invalid("publish_concurrency", "PUBLISH_CONCURRENCY", "must be at least 1, not %d", c.PublishConcurrency)
+	}
+	switch c.Generator {
+	case "openai", "mock":
+	default:
+		invalid("generator", "GENERATOR", "must be openai or mock, not %q", c.Generator)
+	}
+	switch c.ModerationMode {
+	case "rules", "llm", "both":
+	default:
+		invalid("moderation_mode", "MODERATION_MODE", "must be rules, llm or both, not %q", c.ModerationMode)
+	}
+
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		invalid("log_level", "LOG_LEVEL", "must be debug, info, warn or error, not %q", c.LogLevel)
+	}
+	switch c.LogFormat {
+	case "text", "json":
+	default:
+		invalid("log_format", "LOG_FORMAT", "must be text or json, not %q", c.LogFormat)
+	}
+	if c.LogRotation.MaxSize < 1 {
+		invalid("log_rotation.max_size", "LOG_MAX_SIZE", "must be at least 1, not %d", c.LogRotation.MaxSize)
+	}
+	if c.LogRotation.Interval < 0 {
+		invalid("log_rotation.interval", "LOG_ROTATE_INTERVAL", "can't be negative")
+	}
+	if c.LogRotation.MaxAge < 0 {
+		invalid("log_rotation.max_age", "LOG_MAX_AGE", "can't be negative")
+	}
+	if c.LogRotation.MaxBackups < 0 {
+		invalid("log_rotation.max_backups", "LOG_MAX_BACKUPS", "can't be negative")
+	}
+
+	if _, err := time.Parse("15:04", c.Schedule.PostTime); err != nil {
+		invalid("schedule.post_time", "POST_TIME", "must be a time like 09:00, not %q", c.Schedule.PostTime)
+	}
+	if c.Schedule.Jitter < 0 {
+		invalid("schedule.jitter", "POST_JITTER", "can't be negative")
+	}
+	if c.Schedule.MaxLateness < 0 {
+		invalid("schedule.max_lateness", "MAX_LATENESS", "can't be negative")
+	}
+
+	switch c.Store.Backend {
+	case StoreSQLite, StoreFile, StoreNone:
+	case StorePostgres:
+		if c.Store.DatabaseURL == "" {
+			invalid("store.database_url", "DATABASE_URL", "is required by the postgres store")
+		}
+	case StoreRedis:
+		if c.RedisURL == "" {
+			invalid("redis_url", "REDIS_URL", "is required by the redis store")
+		}
+	case StoreDynamoDB:
+		if c.Store.Table == "" {
+			invalid("store.table", "STORE_TABLE", "is required by the dynamodb store")
+		}
+	case StoreS3, StoreGCS:
+		if c.Store.Bucket == "" {
+			invalid("store.bucket", "STORE_BUCKET", "is required by the %s store", c.Store.Backend)
+		}
+	default:
+		invalid("store.backend", "STORE", "must be sqlite, file, postgres, redis, dynamodb, s3, gcs or none, not %q", c.Store.Backend)
+	}
+	if c.Store.RetentionDays < 0 {
+		invalid("store.retention_days", "STORE_RETENTION_DAYS", "can't be negative")
+	}
+	if c.Store.RetentionRuns < 0 {
+		invalid("store.retention_runs", "STORE_RETENTION_RUNS", "can't be negative")
+	}
+
+	switch c.Lock.Backend {
+	case "":
+	case "redis":
+		if c.RedisURL == "" {
+			invalid("redis_url", "REDIS_URL", "is required by the redis lock")
+		}
+	case "dynamodb":
+		if c.Lock.Table == "" {
+			invalid("lock.table", "LOCK_TABLE", "is required by the dynamodb lock")
+		}
+	case "store":
+		if c.Store.Backend == StoreNone {
+			invalid("lock.backend", "LOCK_BACKEND", "can't be store when the store is none")
+		}
+	default:
+		invalid("lock.backend", "LOCK_BACKEND", "must be redis, dynamodb or store, not %q", c.Lock.Backend)
+	}
+
+	switch c.Feed.Format {
+	case "atom", "rss":
+	default:
+		invalid("feed.format", "FEED_FORMAT", "must be atom or rss, not %q", c.Feed.Format)
+	}
+
+	if c.Retry.Attempts < 1 {
+		invalid("retry.attempts", "RETRY_ATTEMPTS", "must be at least 1, not %d", c.Retry.Attempts)
+	}
+	if c.Retry.Delay < 0 {
+		invalid("retry.delay", "RETRY_DELAY", "can't be negative")
+	}
+	if c.Retry.MaxDelay < c.Retry.Delay {
+		invalid("retry.max_delay", "RETRY_MAX_DELAY", "can't be less than the delay, %s", c.Retry.Delay)
+	}
+
+	if c.Alerts.EmailTo != "" {
+		if c.Alerts.EmailFrom == "" {
+			invalid("alerts.email_from", "ALERT_EMAIL_FROM", "is required to email alerts")
+		}
+		if _, _, err := net.SplitHostPort(c.Alerts.SMTPAddr); err != nil {
+			invalid("alerts.smtp_addr", "ALERT_SMTP_ADDR", "must be a host:port to email alerts, not %q", c.Alerts.SMTPAddr)
+		}
+	}
+
+	if c.StatusReport.Enabled() && c.StatusReport.Cron == "" {
+		invalid("status_report.cron", "STATUS_REPORT_CRON", "is required to make the status report")
+	}
+
+	if c.Audit.MaxSize < 1 {
+		invalid("audit.max_size", "AUDIT_MAX_SIZE", "must be at least 1, not %d", c.Audit.MaxSize)
+	}
+	if c.Audit.MaxBackups < 0 {
+		invalid("audit.max_backups", "AUDIT_MAX_BACKUPS", "can't be negative")
+	}
+	if c.Audit.BodyLimit < 0 {
+		invalid("audit.body_limit", "AUDIT_BODY_LIMIT", "can't be negative")
+	}
+
+	if c.Duplicates.Window < 0 {
+		invalid("duplicates.window", "DUPLICATE_WINDOW", "can't be negative")
+	}
+	if c.Duplicates.Threshold <= 0 || c.Duplicates.Threshold > 1 {
+		invalid("duplicates.threshold", "DUPLICATE_THRESHOLD", "must be a fraction above 0 and up to 1, not %g", c.Duplicates.Threshold)
+	}
+	if c.Duplicates.Regenerations < 0 {
+		invalid("duplicates.regenerations", "DUPLICATE_REGENERATIONS", "can't be negative")
+	}
+
+	for _, t := range []struct {
+		key, env string
+		d        time.Duration
+	}{
+		{"timeouts.bluesky", "BLUESKY_TIMEOUT", c.Timeouts.Bluesky},
+		{"timeouts.openai", "OPENAI_TIMEOUT", c.Timeouts.OpenAI},
+		{"timeouts.http", "HTTP_TIMEOUT", c.Timeouts.HTTP},
+		{"timeouts.publish", "PUBLISH_TIMEOUT", c.Timeouts.Publish},
+	} {
+		if t.d <= 0 {
+			invalid(t.key, t.env, "must be positive, not %s", t.d)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
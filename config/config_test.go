@@ -0,0 +1,263 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func setting(t *testing.T, c *Config, key string) Setting {
+	t.Helper()
+	for _, s := range c.Settings() {
+		if s.Key == key {
+			return s
+		}
+	}
+	t.Fatalf("no setting %s", key)
+	return Setting{}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yamlFile := writeConfig(t, "go-trump.yaml", "post_type: weekly\nretry:\n  attempts: 5\n")
+	tomlFile := writeConfig(t, "go-trump.toml", "post_type = \"weekly\"\n\n[retry]\nattempts = 5\n")
+
+	tests := []struct {
+		name       string
+		path       string
+		env        map[string]string
+		flags      map[string]string
+		want       string
+		wantSource Source
+	}{
+		{
+			name:       "default",
+			want:       "daily",
+			wantSource: SourceDefault,
+		},
+		{
+			name:       "yaml file over default",
+			path:       yamlFile,
+			want:       "weekly",
+			wantSource: SourceFile,
+		},
+		{
+			name:       "toml file over default",
+			path:       tomlFile,
+			want:       "weekly",
+			wantSource: SourceFile,
+		},
+		{
+			name:       "environment over file",
+			path:       yamlFile,
+			env:        map[string]string{"POST_TYPE": "monthly"},
+			want:       "monthly",
+			wantSource: SourceEnv,
+		},
+		{
+			name:       "prefixed environment over unprefixed",
+			path:       yamlFile,
+			env:        map[string]string{"POST_TYPE": "monthly", "TRUMPBOT_POST_TYPE": "yearly"},
+			want:       "yearly",
+			wantSource: SourceEnv,
+		},
+		{
+			name:       "empty environment variable is ignored",
+			path:       yamlFile,
+			env:        map[string]string{"POST_TYPE": ""},
+			want:       "weekly",
+			wantSource: SourceFile,
+		},
+		{
+			name:       "flag over environment and file",
+			path:       yamlFile,
+			env:        map[string]string{"POST_TYPE": "monthly"},
+			flags:      map[string]string{"post_type": "milestone"},
+			want:       "milestone",
+			wantSource: SourceFlag,
+		},
+		{
+			name:       "flag over default",
+			flags:      map[string]string{"post_type": "milestone"},
+			want:       "milestone",
+			wantSource: SourceFlag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"POST_TYPE", "TRUMPBOT_POST_TYPE"} {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			c, err := Load(tt.path, "", tt.flags)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if c.PostType != tt.want {
+				t.Errorf("PostType = %q, want %q", c.PostType, tt.want)
+			}
+			if got := setting(t, c, "post_type").Source; got != tt.wantSource {
+				t.Errorf("post_type source = %q, want %q", got, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestLoadNestedFileSettings(t *testing.T) {
+	for _, path := range []string{
+		writeConfig(t, "go-trump.yaml", "retry:\n  attempts: 5\n"),
+		writeConfig(t, "go-trump.toml", "[retry]\nattempts = 5\n"),
+	} {
+		c, err := Load(path, "", nil)
+		if err != nil {
+			t.Fatalf("Load(%s) error = %v", path, err)
+		}
+		if got := setting(t, c, "retry.attempts"); got.Value != "5" || got.Source != SourceFile {
+			t.Errorf("retry.attempts from %s = %q from %s, want 5 from file", path, got.Value, got.Source)
+		}
+		if got := setting(t, c, "retry.delay"); got.Source != SourceDefault {
+			t.Errorf("retry.delay from %s source = %s, want default", path, got.Source)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownFlag(t *testing.T) {
+	if _, err := Load("", "", map[string]string{"retry.attempt": "5"}); err == nil {
+		t.Error("Load() with an unknown setting succeeded, want an error")
+	}
+	if _, err := Load("", "", map[string]string{"retry.attempts": "five"}); err == nil {
+		t.Error("Load() with an invalid value succeeded, want an error")
+	}
+}
+
+func TestSettingsMasksSecrets(t *testing.T) {
+	c, err := Load("", "", map[string]string{
+		"store.backend":      StorePostgres,
+		"store.database_url": "postgres://bot:hunter2@db/go-trump",
+	})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := setting(t, c, "store.database_url").Value; got != "********" {
+		t.Errorf("store.database_url = %q, want it masked", got)
+	}
+	if got := setting(t, c, "redis_url").Value; got != "" {
+		t.Errorf("unset redis_url = %q, want \"\"", got)
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	for _, path := range []string{
+		writeConfig(t, "go-trump.yaml", `post_type: weekly
+store:
+  backend: file
+  path: production.jsonl
+profiles:
+  staging:
+    store:
+      path: staging.jsonl
+`),
+		writeConfig(t, "go-trump.toml", `post_type = "weekly"
+
+[store]
+backend = "file"
+path = "production.jsonl"
+
+[profiles.staging.store]
+path = "staging.jsonl"
+`),
+	} {
+		c, err := Load(path, "staging", nil)
+		if err != nil {
+			t.Fatalf("Load(%s, staging) error = %v", path, err)
+		}
+		if got := setting(t, c, "store.path"); got.Value != "staging.jsonl" || got.Source != SourceProfile {
+			t.Errorf("store.path from %s = %q from %s, want staging.jsonl from profile", path, got.Value, got.Source)
+		}
+		if got := setting(t, c, "post_type"); got.Value != "weekly" || got.Source != SourceFile {
+			t.Errorf("post_type from %s = %q from %s, want weekly from file", path, got.Value, got.Source)
+		}
+
+		c, err = Load(path, "", nil)
+		if err != nil {
+			t.Fatalf("Load(%s) error = %v", path, err)
+		}
+		if c.Store.Path != "production.jsonl" {
+			t.Errorf("store.path from %s without a profile = %q, want production.jsonl", path, c.Store.Path)
+		}
+
+		if _, err := Load(path, "production", nil); err == nil {
+			t.Errorf("Load(%s, production) succeeded, want an error for the missing profile", path)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownProfileSetting(t *testing.T) {
+	for _, path := range []string{
+		writeConfig(t, "go-trump.yaml", "profiles:\n  staging:\n    store:\n      paht: staging.jsonl\n"),
+		writeConfig(t, "go-trump.toml", "[profiles.staging.store]\npaht = \"staging.jsonl\"\n"),
+	} {
+		if _, err := Load(path, "", nil); err == nil {
+			t.Errorf("Load(%s) with an unknown setting in an unused profile succeeded, want an error", path)
+		}
+	}
+}
+
+func TestLoadRejectsSOPSTOML(t *testing.T) {
+	for _, path := range []string{
+		writeConfig(t, "go-trump.toml", "[retry]\nattempts = \"ENC[AES256_GCM,data:Ng==,type:int]\"\n\n[sops]\nversion = \"3.9.0\"\n"),
+		writeConfig(t, "binary.toml", `{"data": "ENC[AES256_GCM,data:bm9wZQ==,type:str]", "sops": {"version": "3.9.0"}}`),
+	} {
+		_, err := Load(path, "", nil)
+		if err == nil || !strings.Contains(err.Error(), "encrypted with sops") {
+			t.Errorf("Load(%s) error = %v, want it rejected as encrypted with sops", path, err)
+		}
+	}
+}
+
+func TestLookupEnvProfile(t *testing.T) {
+	t.Setenv("BLUESKY_PASSWORD", "shared")
+	t.Setenv("TRUMPBOT_BLUESKY_PASSWORD", "")
+	t.Setenv("TRUMPBOT_STAGING_BLUESKY_PASSWORD", "staging")
+	t.Cleanup(func() { UseProfile("") })
+
+	if got := Getenv("BLUESKY_PASSWORD"); got != "shared" {
+		t.Errorf("without a profile, Getenv() = %q, want shared", got)
+	}
+	UseProfile("staging")
+	if got := Getenv("BLUESKY_PASSWORD"); got != "staging" {
+		t.Errorf("with the staging profile, Getenv() = %q, want staging", got)
+	}
+	UseProfile("production")
+	if got := Getenv("BLUESKY_PASSWORD"); got != "shared" {
+		t.Errorf("with the production profile, Getenv() = %q, want shared", got)
+	}
+}
+
+func TestSchemaIsPublished(t *testing.T) {
+	published, err := os.ReadFile("../go-trump.schema.json")
+	if err != nil {
+		t.Fatalf("failed to read go-trump.schema.json: %v", err)
+	}
+	want, err := json.MarshalIndent(Schema(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(published)) != string(want) {
+		t.Error("go-trump.schema.json is out of date, regenerate it with go-trump config schema > go-trump.schema.json")
+	}
+}
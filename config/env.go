@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// EnvPrefix namespaces the bot's environment variables. Every variable can be
+// given with it, such as TRUMPBOT_STORE, which wins over the same variable
+// without it, so existing deployments that set STORE keep working.
+const EnvPrefix = "TRUMPBOT_"
+
+// profilePrefix namespaces the variables of the selected profile, or is ""
+// when there's none
+var profilePrefix string
+
+// UseProfile makes LookupEnv look up each variable for the profile name
+// first, such as TRUMPBOT_STAGING_BLUESKY_PASSWORD for the staging profile,
+// so every profile can have its own credentials. An empty name selects none.
+func UseProfile(name string) {
+	profilePrefix = ""
+	if name != "" {
+		profilePrefix = EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	}
+}
+
+// TrimPrefix returns the environment variable name without the prefix of the
+// selected profile or EnvPrefix
+func TrimPrefix(name string) string {
+	if profilePrefix != "" {
+		if trimmed, ok := strings.CutPrefix(name, profilePrefix); ok {
+			return trimmed
+		}
+	}
+	return strings.TrimPrefix(name, EnvPrefix)
+}
+
+// LookupEnv looks up the environment variable name for the selected profile,
+// then with EnvPrefix, then without it
+func LookupEnv(name string) (string, bool) {
+	if profilePrefix != "" {
+		if value, ok := os.LookupEnv(profilePrefix + name); ok && value != "" {
+			return value, true
+		}
+	}
+	if value, ok := os.LookupEnv(EnvPrefix + name); ok && value != "" {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// Getenv returns the environment variable name as LookupEnv finds it
+func Getenv(name string) string {
+	value, _ := LookupEnv(name)
+	return value
+}
+
+// Var represents an environment variable the bot recognises. Key is the
+// setting's key in the config file, or "" for variables only read from the
+// environment. Secret marks credentials.
+type Var struct {
+	Name    string
+	Key     string
+	Default string
+	Help    string
+	Secret  bool
+}
+
+// EnvOnly lists the variables that are only read from the environment,
+// credentials among them. Destinations can name other variables for their
+// credentials in the events file; these are the defaults.
+var EnvOnly = []Var{
+	{Name: "ENVIRONMENT", Help: "production skips loading .env"},
+	{Name: "CONFIG_FILE", Help: "YAML or TOML config file, by default go-trump.yaml or go-trump.toml"},
+	{Name: "PROFILE", Help: "profile of the config file to use, as --profile"},
+	{Name: "OPENAI_API_KEY", Secret: true, Help: "OpenAI API key"},
+	{Name: "BLUESKY_USERNAME", Help: "Bluesky handle of the default destination"},
+	{Name: "BLUESKY_PASSWORD", Secret: true, Help: "Bluesky app password of the default destination"},
+	{Name: "TELEGRAM_BOT_TOKEN", Secret: true, Help: "Telegram bot token"},
+	{Name: "SLACK_WEBHOOK_URL", Secret: true, Help: "Slack incoming webhook"},
+	{Name: "THREADS_ACCESS_TOKEN", Secret: true, Help: "Threads access token"},
+	{Name: "MICROPUB_TOKEN", Secret: true, Help: "Micropub access token"},
+	{Name: "NTFY_TOKEN", Secret: true, Help: "ntfy access token"},
+	{Name: "MQTT_USERNAME", Help: "MQTT broker username"},
+	{Name: "MQTT_PASSWORD", Secret: true, Help: "MQTT broker password"},
+	{Name: "SMTP_USERNAME", Help: "SMTP username of email destinations"},
+	{Name: "SMTP_PASSWORD", Secret: true, Help: "SMTP password of email destinations"},
+	{Name: "WEBHOOK_SECRET", Secret: true, Help: "secret that signs webhook deliveries"},
+	{Name: "TRIGGER_TOKEN", Secret: true, Help: "bearer token HTTP triggers must carry"},
+	{Name: "STORE_ENCRYPTION_KEY", Secret: true, Help: "base64 32-byte key that encrypts stored prompts"},
+	{Name: "GCS_ACCESS_TOKEN", Secret: true, Help: "Cloud Storage token outside Google Cloud"},
+	{Name: "VAULT_ADDR", Help: "Vault server of vault: secret references"},
+	{Name: "VAULT_NAMESPACE", Help: "Vault namespace"},
+	{Name: "VAULT_TOKEN", Secret: true, Help: "Vault token"},
+	{Name: "VAULT_ROLE_ID", Help: "Vault AppRole role ID, when there's no token"},
+	{Name: "VAULT_SECRET_ID", Secret: true, Help: "Vault AppRole secret ID"},
+	{Name: "VAULT_APPROLE_MOUNT", Default: "approle", Help: "where Vault's AppRole auth method is mounted"},
+	{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Help: "OTLP/HTTP collector that traces are exported to, without TRUMPBOT_"},
+	{Name: "OTEL_SERVICE_NAME", Default: "go-trump", Help: "service name of exported traces, without TRUMPBOT_"},
+}
+
+// Vars returns the environment variable of every setting, with its key and
+// default
+func Vars() []Var {
+	var vars []Var
+	walk(reflect.ValueOf(Default()).Elem(), "", func(v reflect.Value, key string, field reflect.StructField) {
+		var def string
+		if !v.IsZero() {
+			def = formatValue(v)
+		}
+		vars = append(vars, Var{
+			Name:    field.Tag.Get("env"),
+			Key:     key,
+			Default: def,
+			Help:    field.Tag.Get("help"),
+			Secret:  field.Tag.Get("secret") == "true",
+		})
+	})
+	return vars
+}
+
+// formatValue formats a setting as it would be written in the environment
+func formatValue(v reflect.Value) string {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
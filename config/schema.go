@@ -0,0 +1,88 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaID identifies the JSON Schema of the config file, and is where the
+// copy in the repository, go-trump.schema.json, is published
+const SchemaID = "https://raw.githubusercontent.com/lukeocodes/go-trump/main/go-trump.schema.json"
+
+// Schema returns a JSON Schema of the config file, describing each setting
+// with its type, default and environment variable, so editors and CI can
+// check a file before it's deployed. The schema applies to YAML and TOML
+// files alike.
+func Schema() map[string]any {
+	schema := objectSchema(reflect.ValueOf(Default()).Elem())
+	schema["properties"].(map[string]any)["profiles"] = map[string]any{
+		"description":          "named profiles, selected with --profile, each overriding any of the other settings",
+		"type":                 "object",
+		"additionalProperties": map[string]any{"$ref": "#/$defs/settings"},
+	}
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = SchemaID
+	schema["title"] = "go-trump config file"
+	schema["$defs"] = map[string]any{"settings": objectSchema(reflect.ValueOf(Default()).Elem())}
+	return schema
+}
+
+// objectSchema describes the settings of the struct v, which holds their
+// defaults
+func objectSchema(v reflect.Value) map[string]any {
+	properties := map[string]any{}
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeFor[time.Duration]() {
+			properties[key] = objectSchema(v.Field(i))
+			continue
+		}
+		properties[key] = settingSchema(v.Field(i), field)
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// settingSchema describes a single setting, whose default is v
+func settingSchema(v reflect.Value, field reflect.StructField) map[string]any {
+	schema := map[string]any{
+		"description": field.Tag.Get("help") + " (" + field.Tag.Get("env") + ")",
+	}
+
+	switch {
+	case v.Type() == reflect.TypeFor[time.Duration]():
+		schema["type"] = "string"
+		schema["pattern"] = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+		schema["default"] = formatValue(v)
+	case v.Kind() == reflect.String:
+		schema["type"] = "string"
+		schema["default"] = v.String()
+	case v.Kind() == reflect.Bool:
+		schema["type"] = "boolean"
+		schema["default"] = v.Bool()
+	case v.Kind() == reflect.Int:
+		schema["type"] = "integer"
+		schema["default"] = v.Int()
+	case v.Kind() == reflect.Float64:
+		schema["type"] = "number"
+		schema["default"] = v.Float()
+	}
+
+	if enum, ok := field.Tag.Lookup("enum"); ok {
+		var values []any
+		for _, value := range strings.Split(enum, ",") {
+			values = append(values, value)
+		}
+		schema["enum"] = values
+	}
+	return schema
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/decrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// decryptSOPS decrypts a YAML config file encrypted with sops, returning data
+// unchanged when it isn't. sops finds the key the way its own CLI does, such
+// as an age key in SOPS_AGE_KEY or SOPS_AGE_KEY_FILE, or through AWS KMS.
+func decryptSOPS(path string, data []byte) ([]byte, error) {
+	var metadata struct {
+		SOPS any `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &metadata); err != nil || metadata.SOPS == nil {
+		return data, nil
+	}
+
+	plaintext, err := decrypt.DataWithFormat(data, formats.Yaml)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt config file %s: %w", path, err)
+	}
+	return plaintext, nil
+}
+
+// rejectSOPSTOML returns an error for a TOML config file encrypted with sops,
+// which can't decrypt TOML, rather than letting it fail as an unknown key.
+// sops encrypts a .toml file either as a binary file, leaving JSON with a sops
+// key behind, or, in releases that support TOML, with a sops table.
+func rejectSOPSTOML(path string, data []byte) error {
+	var metadata struct {
+		SOPS any `toml:"sops" yaml:"sops"`
+	}
+	if _, err := toml.Decode(string(data), &metadata); err != nil {
+		// JSON is YAML, so this finds the sops key of a binary file
+		if err := yaml.Unmarshal(data, &metadata); err != nil {
+			return nil
+		}
+	}
+	if metadata.SOPS == nil {
+		return nil
+	}
+	return fmt.Errorf("config file %s is encrypted with sops, which only decrypts YAML config files; convert it to YAML and encrypt that instead", path)
+}
@@ -0,0 +1,77 @@
+package countdown
+
+import "time"
+
+// BusinessDaysBetween returns the number of weekdays from from's date up to but
+// not including to's date, both taken in from's timezone, skipping any holidays.
+// Holidays are matched on their calendar date.
+func BusinessDaysBetween(from, to time.Time, holidays []time.Time) int {
+	loc := from.Location()
+	a := civil(from, loc)
+	b := civil(to, loc)
+
+	skip := make(map[time.Time]bool, len(holidays))
+	for _, h := range holidays {
+		y, m, d := h.Date()
+		skip[time.Date(y, m, d, 0, 0, 0, 0, time.UTC)] = true
+	}
+
+	count := 0
+	for d := a; d.Before(b); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday || skip[d] {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// USFederalHolidays returns the observed dates of US federal holidays in year,
+// at UTC midnight. Fixed-date holidays falling on a weekend are observed on
+// the nearest weekday.
+func USFederalHolidays(year int) []time.Time {
+	fixed := func(month time.Month, day int) time.Time {
+		return observed(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+	}
+
+	return []time.Time{
+		fixed(time.January, 1),
+		NthWeekday(year, time.January, time.Monday, 3),
+		NthWeekday(year, time.February, time.Monday, 3),
+		lastWeekday(year, time.May, time.Monday),
+		fixed(time.June, 19),
+		fixed(time.July, 4),
+		NthWeekday(year, time.September, time.Monday, 1),
+		NthWeekday(year, time.October, time.Monday, 2),
+		fixed(time.November, 11),
+		NthWeekday(year, time.November, time.Thursday, 4),
+		fixed(time.December, 25),
+	}
+}
+
+// observed moves a Saturday holiday to the Friday before and a Sunday holiday
+// to the Monday after
+func observed(t time.Time) time.Time {
+	switch t.Weekday() {
+	case time.Saturday:
+		return t.AddDate(0, 0, -1)
+	case time.Sunday:
+		return t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// NthWeekday returns the nth weekday of a month at UTC midnight. An n past
+// the end of the month rolls into the next one, like time.Date does.
+func NthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+(n-1)*7)
+}
+
+// lastWeekday returns the last weekday of a month at UTC midnight
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,63 @@
+package countdown
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessDaysBetween(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		holidays []time.Time
+		want     int
+	}{
+		{"same day", date(2026, time.October, 14), date(2026, time.October, 14), nil, 0},
+		{"backwards", date(2026, time.October, 14), date(2026, time.October, 1), nil, 0},
+		{"monday to friday", date(2026, time.October, 12), date(2026, time.October, 16), nil, 4},
+		{"monday to monday", date(2026, time.October, 12), date(2026, time.October, 19), nil, 5},
+		{"weekend only", date(2026, time.October, 17), date(2026, time.October, 19), nil, 0},
+		{"holiday skipped", date(2026, time.December, 21), date(2026, time.December, 28), []time.Time{date(2026, time.December, 25)}, 4},
+		{"weekend holiday ignored", date(2026, time.October, 12), date(2026, time.October, 19), []time.Time{date(2026, time.October, 17)}, 5},
+		{"time of day ignored", time.Date(2026, time.October, 12, 23, 0, 0, 0, time.UTC), time.Date(2026, time.October, 13, 1, 0, 0, 0, time.UTC), nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BusinessDaysBetween(tt.from, tt.to, tt.holidays); got != tt.want {
+				t.Errorf("BusinessDaysBetween(%v, %v) = %d, want %d", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUSFederalHolidays(t *testing.T) {
+	want := []string{
+		"2027-01-01", // New Year's Day
+		"2027-01-18", // Martin Luther King Jr. Day
+		"2027-02-15", // Washington's Birthday
+		"2027-05-31", // Memorial Day
+		"2027-06-18", // Juneteenth, observed on the Friday
+		"2027-07-05", // Independence Day, observed on the Monday
+		"2027-09-06", // Labor Day
+		"2027-10-11", // Columbus Day
+		"2027-11-11", // Veterans Day
+		"2027-11-25", // Thanksgiving Day
+		"2027-12-24", // Christmas Day, observed on the Friday
+	}
+
+	got := USFederalHolidays(2027)
+	if len(got) != len(want) {
+		t.Fatalf("USFederalHolidays(2027) returned %d holidays, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if d := got[i].Format("2006-01-02"); d != want[i] {
+			t.Errorf("holiday %d = %s, want %s", i, d, want[i])
+		}
+	}
+}
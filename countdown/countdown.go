@@ -0,0 +1,124 @@
+// Package countdown implements the calendar arithmetic behind the bot's day
+// counts. Everything here works on calendar dates rather than 24 hour blocks,
+// so DST transitions and leap days never shift a count.
+package countdown
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// civil returns t's calendar date in loc as a UTC midnight, which can be
+// subtracted safely because UTC has no DST
+func civil(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// DaysBetween returns the number of calendar days from from's date to to's
+// date, both taken in from's timezone. It is negative when to is earlier.
+func DaysBetween(from, to time.Time) int {
+	loc := from.Location()
+	a := civil(from, loc)
+	b := civil(to, loc)
+
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// Breakdown represents a calendar duration in years, months and days
+type Breakdown struct {
+	Years  int
+	Months int
+	Days   int
+}
+
+// BreakdownBetween splits the calendar time from from's date to to's date,
+// both taken in from's timezone, into whole years, months and days. A month is
+// counted once the same day of the month is reached, clamped to the end of
+// shorter months, so Jan 31 to Feb 29 is 29 days and Jan 31 to Mar 1 is
+// 1 month and 1 day in a leap year.
+func BreakdownBetween(from, to time.Time) Breakdown {
+	loc := from.Location()
+	a := civil(from, loc)
+	b := civil(to, loc)
+
+	if b.Before(a) {
+		return Breakdown{}
+	}
+
+	months := (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+	if b.Day() < a.Day() {
+		months--
+	}
+
+	anchor := addMonthsClamped(a, months)
+
+	return Breakdown{
+		Years:  months / 12,
+		Months: months % 12,
+		Days:   int(b.Sub(anchor).Hours() / 24),
+	}
+}
+
+// addMonthsClamped adds months to a UTC midnight date, clamping the day to the
+// last day of the resulting month instead of overflowing like time.AddDate
+func addMonthsClamped(t time.Time, months int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	if d > last {
+		d = last
+	}
+	return time.Date(first.Year(), first.Month(), d, 0, 0, 0, 0, time.UTC)
+}
+
+// String formats the breakdown as e.g. "3 years, 2 months, 11 days", leaving
+// out zero parts
+func (b Breakdown) String() string {
+	var parts []string
+	if b.Years > 0 {
+		parts = append(parts, plural(b.Years, "year"))
+	}
+	if b.Months > 0 {
+		parts = append(parts, plural(b.Months, "month"))
+	}
+	if b.Days > 0 || len(parts) == 0 {
+		parts = append(parts, plural(b.Days, "day"))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// plural formats a count with a singular or plural unit
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// Precise formats the exact time remaining as e.g. "2 days, 3 hours, 15 minutes",
+// for the final stretch of a countdown where whole days aren't precise enough.
+// Seconds are rounded up so a countdown never reads zero before it's over.
+func Precise(d time.Duration) string {
+	if d <= 0 {
+		return plural(0, "minute")
+	}
+
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	days := minutes / (24 * 60)
+	hours := minutes / 60 % 24
+	minutes %= 60
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, plural(days, "day"))
+	}
+	if hours > 0 {
+		parts = append(parts, plural(hours, "hour"))
+	}
+	if minutes > 0 || len(parts) == 0 {
+		parts = append(parts, plural(minutes, "minute"))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,276 @@
+package countdown
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestDaysBetween(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	london := mustLoad(t, "Europe/London")
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want int
+	}{
+		{
+			name: "same instant",
+			from: time.Date(2026, time.May, 1, 9, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.May, 1, 9, 0, 0, 0, time.UTC),
+			want: 0,
+		},
+		{
+			name: "same day, later time",
+			from: time.Date(2026, time.May, 1, 0, 0, 1, 0, time.UTC),
+			to:   time.Date(2026, time.May, 1, 23, 59, 59, 0, time.UTC),
+			want: 0,
+		},
+		{
+			name: "less than 24h but across midnight",
+			from: time.Date(2026, time.May, 1, 23, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.May, 2, 1, 0, 0, 0, time.UTC),
+			want: 1,
+		},
+		{
+			name: "more than 24h but only one midnight",
+			from: time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.May, 2, 23, 0, 0, 0, time.UTC),
+			want: 1,
+		},
+		{
+			name: "backwards",
+			from: time.Date(2026, time.May, 10, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.May, 3, 0, 0, 0, 0, time.UTC),
+			want: -7,
+		},
+		{
+			name: "across spring forward in New York",
+			from: time.Date(2026, time.March, 7, 12, 0, 0, 0, ny),
+			to:   time.Date(2026, time.March, 9, 0, 0, 0, 0, ny),
+			want: 2,
+		},
+		{
+			name: "just after midnight on a spring forward day",
+			from: time.Date(2026, time.March, 8, 0, 30, 0, 0, ny),
+			to:   time.Date(2026, time.March, 9, 0, 0, 0, 0, ny),
+			want: 1,
+		},
+		{
+			name: "across fall back in New York",
+			from: time.Date(2026, time.October, 31, 23, 30, 0, 0, ny),
+			to:   time.Date(2026, time.November, 2, 0, 0, 0, 0, ny),
+			want: 2,
+		},
+		{
+			name: "late on a fall back day",
+			from: time.Date(2026, time.November, 1, 23, 30, 0, 0, ny),
+			to:   time.Date(2026, time.November, 2, 0, 0, 0, 0, ny),
+			want: 1,
+		},
+		{
+			name: "across spring forward in London",
+			from: time.Date(2026, time.March, 28, 0, 0, 0, 0, london),
+			to:   time.Date(2026, time.April, 4, 0, 0, 0, 0, london),
+			want: 7,
+		},
+		{
+			name: "across a leap day",
+			from: time.Date(2028, time.February, 28, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2028, time.March, 1, 0, 0, 0, 0, time.UTC),
+			want: 2,
+		},
+		{
+			name: "no leap day",
+			from: time.Date(2027, time.February, 28, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC),
+			want: 1,
+		},
+		{
+			name: "leap year length",
+			from: time.Date(2028, time.January, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2029, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want: 366,
+		},
+		{
+			name: "full term",
+			from: time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2029, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want: 1461,
+		},
+		{
+			name: "target in another timezone uses from's calendar",
+			from: time.Date(2029, time.January, 19, 20, 0, 0, 0, ny),
+			to:   time.Date(2029, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want: 0,
+		},
+		{
+			name: "noon ET target counted on the New York calendar",
+			from: time.Date(2029, time.January, 18, 20, 0, 0, 0, ny),
+			to:   time.Date(2029, time.January, 20, 17, 0, 0, 0, time.UTC),
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DaysBetween(tt.from, tt.to); got != tt.want {
+				t.Errorf("DaysBetween(%v, %v) = %d, want %d", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakdownBetween(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want Breakdown
+	}{
+		{
+			name: "same day",
+			from: time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{},
+		},
+		{
+			name: "backwards is zero",
+			from: time.Date(2026, time.May, 2, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{},
+		},
+		{
+			name: "days only",
+			from: time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.May, 12, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Days: 11},
+		},
+		{
+			name: "exactly one month",
+			from: time.Date(2026, time.May, 15, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.June, 15, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Months: 1},
+		},
+		{
+			name: "not quite a month",
+			from: time.Date(2026, time.May, 15, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2026, time.June, 14, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Days: 30},
+		},
+		{
+			name: "years months and days",
+			from: time.Date(2025, time.November, 9, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2029, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Years: 3, Months: 2, Days: 11},
+		},
+		{
+			name: "month end clamps into leap February",
+			from: time.Date(2028, time.January, 31, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2028, time.March, 1, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Months: 1, Days: 1},
+		},
+		{
+			name: "month end clamps into short February",
+			from: time.Date(2027, time.January, 31, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Months: 1, Days: 1},
+		},
+		{
+			name: "late in the month to earlier day",
+			from: time.Date(2026, time.October, 30, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2027, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Months: 2, Days: 21},
+		},
+		{
+			name: "leap day to the following year",
+			from: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2029, time.February, 28, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Months: 11, Days: 30},
+		},
+		{
+			name: "leap day to March next year",
+			from: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2029, time.March, 1, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Years: 1, Days: 1},
+		},
+		{
+			name: "full term",
+			from: time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2029, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want: Breakdown{Years: 4},
+		},
+		{
+			name: "across DST uses local calendar dates",
+			from: time.Date(2026, time.March, 8, 23, 30, 0, 0, ny),
+			to:   time.Date(2026, time.April, 8, 0, 0, 0, 0, ny),
+			want: Breakdown{Months: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BreakdownBetween(tt.from, tt.to); got != tt.want {
+				t.Errorf("BreakdownBetween(%v, %v) = %+v, want %+v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakdownString(t *testing.T) {
+	tests := []struct {
+		in   Breakdown
+		want string
+	}{
+		{Breakdown{}, "0 days"},
+		{Breakdown{Days: 1}, "1 day"},
+		{Breakdown{Months: 1}, "1 month"},
+		{Breakdown{Years: 1, Days: 2}, "1 year, 2 days"},
+		{Breakdown{Years: 3, Months: 2, Days: 11}, "3 years, 2 months, 11 days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.in.String(); got != tt.want {
+				t.Errorf("%+v.String() = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrecise(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{-time.Hour, "0 minutes"},
+		{0, "0 minutes"},
+		{time.Second, "1 minute"},
+		{59 * time.Minute, "59 minutes"},
+		{time.Hour, "1 hour"},
+		{time.Hour + 30*time.Second, "1 hour, 1 minute"},
+		{24 * time.Hour, "1 day"},
+		{2*24*time.Hour + 3*time.Hour + 15*time.Minute, "2 days, 3 hours, 15 minutes"},
+		{3*24*time.Hour + 59*time.Second, "3 days, 1 minute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := Precise(tt.in); got != tt.want {
+				t.Errorf("Precise(%v) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+	"github.com/lukeocodes/go-trump/scheduler"
+	"github.com/lukeocodes/go-trump/state"
+)
+
+// runDaemon keeps the bot running and makes each configured post type when its
+// cron schedule fires. If a schedule's last fire time passed without a post
+// when it starts, it catches up immediately, unless that would be later than
+// the schedule's max lateness. It exits cleanly on SIGINT or SIGTERM, or once
+// the countdown is over. SIGHUP reloads its settings and schedules without
+// restarting it.
+func runDaemon(ctx context.Context) error {
+	// SIGHUP reloads the settings and schedules between posts. It's caught
+	// from the start, so one sent while catching up waits rather than killing
+	// the daemon.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	entries, err := loadSchedules()
+	if err != nil {
+		return err
+	}
+	logSchedules(entries)
+
+	// Serve health checks and metrics alongside the scheduler when asked to
+	if port := conf.HealthPort; port != "" {
+		mux := http.NewServeMux()
+		registerHealth(mux)
+		registerMetrics(mux)
+		go func() {
+			if err := serveHTTP(ctx, ":"+port, mux); err != nil {
+				logger(logServer).Error("Health server failed", "err", err)
+			}
+		}()
+	}
+
+	// Catch up on posts that were due while the host was down
+	st, err := state.Load(stateFile())
+	if err != nil {
+		return err
+	}
+	cfg, err := loadEvents()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	// Posts are recorded under their date in the reference timezone, whatever
+	// the schedule's own timezone, so that's the day checked for a missed one
+	loc, err := cfg.Location()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	current := time.Now()
+	for _, entry := range entries {
+		if entry.Name == statusReportType {
+			continue
+		}
+		prev, ok := entry.Prev(current)
+		if !ok {
+			continue
+		}
+		if day := prev.In(loc); st.PostedOn(entry.Name, day) || postedInStore(ctx, entry.Name, day.Format(time.DateOnly)) {
+			continue
+		}
+		if late := current.Sub(prev); entry.MaxLateness > 0 && late > entry.MaxLateness {
+			logger(logScheduler).WarnContext(ctx, "Skipping a missed post, later than allowed", "post_type", entry.Name, "due", prev, "late", late.Round(time.Minute), "max_lateness", entry.MaxLateness)
+			continue
+		}
+		logger(logScheduler).InfoContext(ctx, "Catching up on a missed post", "post_type", entry.Name, "due", prev)
+		if done := runScheduled(ctx, entry.Name); done {
+			return nil
+		}
+	}
+
+	planner := scheduler.NewPlanner(entries, time.Now())
+	for {
+		due := planner.Next()
+		logger(logScheduler).InfoContext(ctx, "Next post", "post_type", due.Entry.Name, "at", due.At)
+
+		timer := time.NewTimer(time.Until(due.At))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger(logScheduler).Info("Shutting down daemon")
+			return nil
+		case <-reload:
+			timer.Stop()
+			if reloaded, err := reloadDaemon(); err != nil {
+				logger(logScheduler).Error("Reload failed, keeping the current settings and schedules", "err", err)
+			} else {
+				planner.Update(reloaded, time.Now())
+			}
+			continue
+		case <-timer.C:
+		}
+
+		planner.Done(due)
+		if due.Entry.Name == statusReportType {
+			if err := sendStatusReport(ctx); err != nil {
+				logger(logScheduler).Error("Failed to send the status report", "err", err)
+			}
+			continue
+		}
+		if done := runScheduled(ctx, due.Entry.Name); done {
+			return nil
+		}
+	}
+}
+
+// reloadDaemon reloads the config file and the environment's overrides, over
+// which the --set flags still apply, and
+// the events file with its schedules and prompts, returning the schedules. If
+// anything is invalid, the current settings are kept. The store, lock and
+// health server stay as they were started.
+func reloadDaemon() ([]*scheduler.Entry, error) {
+	reloaded, err := config.Load(confFile, confProfile, confFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	// The schedules and logging are set up from the reloaded settings, and
+	// the current ones put back if either fails. setupLogging fails before
+	// it changes anything, so the logs carry on as they were.
+	current := conf
+	conf = reloaded
+	entries, err := loadSchedules()
+	if err == nil {
+		err = setupLogging()
+	}
+	if err != nil {
+		conf = current
+		return nil, err
+	}
+	logger(logScheduler).Info("Reloaded settings and events")
+	logSchedules(entries)
+	return entries, nil
+}
+
+// loadSchedules parses the configured schedules, falling back to a daily post
+// at POST_TIME in the reference timezone, and adds the status report's when
+// it's enabled
+func loadSchedules() ([]*scheduler.Entry, error) {
+	cfg, err := loadEvents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+	loc, err := cfg.Location()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+
+	schedules := cfg.Schedules
+	if len(schedules) == 0 {
+		at, err := time.Parse("15:04", conf.Schedule.PostTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid post time %q, expected HH:MM", conf.Schedule.PostTime)
+		}
+		schedules = []events.Schedule{{
+			Name: events.PostTypeDaily,
+			Cron: fmt.Sprintf("%d %d * * *", at.Minute(), at.Hour()),
+		}}
+	}
+
+	var entries []*scheduler.Entry
+	for _, schedule := range schedules {
+		scheduleLoc := loc
+		if schedule.Timezone != "" {
+			scheduleLoc, err = time.LoadLocation(schedule.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("schedule %q has invalid timezone: %w", schedule.Name, err)
+			}
+		}
+
+		entry, err := scheduler.Parse(schedule.Name, schedule.Cron, scheduleLoc)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Jitter = conf.Schedule.Jitter
+		if schedule.Jitter != "" {
+			entry.Jitter, err = time.ParseDuration(schedule.Jitter)
+			if err != nil {
+				return nil, fmt.Errorf("schedule %q has invalid jitter %q: %w", schedule.Name, schedule.Jitter, err)
+			}
+		}
+
+		entry.MaxLateness = conf.Schedule.MaxLateness
+		if schedule.MaxLateness != "" {
+			entry.MaxLateness, err = time.ParseDuration(schedule.MaxLateness)
+			if err != nil {
+				return nil, fmt.Errorf("schedule %q has invalid max lateness %q: %w", schedule.Name, schedule.MaxLateness, err)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if conf.StatusReport.Enabled() {
+		entry, err := scheduler.Parse(statusReportType, conf.StatusReport.Cron, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status report cron: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// runScheduled runs the pipeline for a scheduled post, alerting the operator on
+// failure. It reports whether the countdown is over and the daemon should stop.
+func runScheduled(ctx context.Context, postType string) bool {
+	now = time.Now()
+
+	log := logger(logScheduler).With("post_type", postType)
+	err := run(ctx, postType, false)
+	if errors.Is(err, errCountdownOver) {
+		log.Info("Countdown over", "reason", err)
+		return true
+	}
+	if errors.Is(err, errSkipPost) {
+		log.Info("Skipped post", "reason", err)
+		return false
+	}
+	if err != nil && ctx.Err() != nil {
+		log.Warn("Scheduled post aborted", "err", err)
+		return true
+	}
+	if err != nil {
+		log.Error("Scheduled post failed", "err", err)
+		alertOperator("Scheduled post failed", err.Error())
+	}
+	return false
+}
+
+// logSchedules logs when each schedule posts
+func logSchedules(entries []*scheduler.Entry) {
+	for _, entry := range entries {
+		logger(logScheduler).Info("Scheduled posts", "post_type", entry.Name, "schedule", entry.Expr, "location", entry.Location.String(), "jitter", entry.Jitter)
+	}
+}
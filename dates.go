@@ -0,0 +1,34 @@
+package main
+
+import (
+	"time"
+
+	"github.com/lukeocodes/go-trump/countdown"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// daysUntil returns the number of calendar days from today until target's date,
+// both taken in now's (reference) timezone
+func daysUntil(target time.Time) int {
+	return countdown.DaysBetween(now, target)
+}
+
+// businessDaysUntil returns the number of weekdays from today until target's
+// date, skipping the configured holidays
+func businessDaysUntil(target time.Time, cfg *events.File) int {
+	holidays, err := cfg.HolidayDates(now.Year(), target.Year())
+	if err != nil {
+		logger(logGenerator).Error("Failed to load holidays", "err", err)
+	}
+	return countdown.BusinessDaysBetween(now, target, holidays)
+}
+
+// totalDays returns the length of an event's countdown from its start, or zero
+// when the event has no start
+func totalDays(e *events.Event) int {
+	start, ok := e.StartTime()
+	if !ok {
+		return 0
+	}
+	return countdown.DaysBetween(start.In(now.Location()), e.Time())
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maskedHeaders carry credentials, so only their names are logged
+var maskedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Api-Key"}
+
+// debugTransport logs each request made through it and its response in
+// full, at the debug level, with credentials masked
+type debugTransport struct {
+	redactor
+
+	next http.RoundTripper
+}
+
+// setupDebug logs the requests made through blueskyClient and openAIClient
+// when debug is set, to troubleshoot requests that OpenAI or the PDS rejects
+func setupDebug() {
+	if !conf.Debug {
+		return
+	}
+	redactor := newRedactor()
+	debug := func(next http.RoundTripper) http.RoundTripper {
+		return &debugTransport{redactor: redactor, next: next}
+	}
+	blueskyClient = wrapTransport(blueskyClient, debug)
+	openAIClient = wrapTransport(openAIClient, debug)
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log := logger(logHTTP)
+
+	var reqBody string
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody = t.dumpBody(req.Header.Get("Content-Type"), body)
+			body.Close()
+		}
+	}
+	log.DebugContext(req.Context(), "Request",
+		"method", req.Method,
+		"url", t.redact(req.URL.String()),
+		"headers", t.dumpHeaders(req.Header),
+		"body", reqBody,
+	)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		log.DebugContext(req.Context(), "Request failed", "url", t.redact(req.URL.String()), "err", t.redact(err.Error()))
+		return resp, err
+	}
+
+	// The body is read whole to be logged, then put back for the caller
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return resp, err
+	}
+	log.DebugContext(req.Context(), "Response",
+		"url", t.redact(req.URL.String()),
+		"status", resp.StatusCode,
+		"latency", time.Since(start),
+		"headers", t.dumpHeaders(resp.Header),
+		"body", t.dumpBody(resp.Header.Get("Content-Type"), bytes.NewReader(data)),
+	)
+	return resp, nil
+}
+
+// dumpHeaders formats headers a line each, masking those with credentials
+func (t *debugTransport) dumpHeaders(header http.Header) string {
+	var lines []string
+	for name, values := range header {
+		for _, value := range values {
+			if slices.Contains(maskedHeaders, http.CanonicalHeaderKey(name)) {
+				value = redacted
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s", name, t.redact(value)))
+		}
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "\n")
+}
+
+// dumpBody returns a body with credentials masked, or describes it when
+// it's an image or the like
+func (t *debugTransport) dumpBody(contentType string, r io.Reader) string {
+	data, _ := io.ReadAll(r)
+	if len(data) == 0 {
+		return ""
+	}
+	if !textual(contentType) {
+		return fmt.Sprintf("(%d bytes of %s)", len(data), contentType)
+	}
+	return t.redact(string(data))
+}
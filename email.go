@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// destinationEmail is the destination type of an email subscriber list
+const destinationEmail = "email"
+
+const defaultEmailSubject = `Countdown for {{.Date}}`
+
+const defaultEmailText = `{{.Text}}
+{{if .ImageURL}}
+{{.ImageURL}}
+{{end}}`
+
+const defaultEmailHTML = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; max-width: 600px; margin: 0 auto;">
+<p style="font-size: 18px; line-height: 1.5;">{{.Text}}</p>
+{{if .ImageURL}}<p><img src="{{.ImageURL}}" alt="{{.ImageAlt}}" style="max-width: 100%;"></p>{{end}}
+<p style="color: #888; font-size: 12px;">{{.Date}}</p>
+</body>
+</html>`
+
+// EmailData represents what email templates can use
+type EmailData struct {
+	Text     string
+	ImageURL string
+	ImageAlt string
+	Date     string
+}
+
+// emailConfig represents an email destination's own settings
+type emailConfig struct {
+	// SMTPAddr is the host:port of the SMTP server emails are sent through,
+	// From the sender, and To and SubscribersFile (one address per line) the
+	// subscribers
+	SMTPAddr        string   `json:"smtp_addr"`
+	From            string   `json:"from"`
+	To              []string `json:"to"`
+	SubscribersFile string   `json:"subscribers_file"`
+
+	// UsernameEnv and PasswordEnv name the environment variables holding the
+	// SMTP credentials (default SMTP_USERNAME and SMTP_PASSWORD)
+	UsernameEnv string `json:"username_env"`
+	PasswordEnv string `json:"password_env"`
+
+	// Subject, TextTemplate and HTMLTemplate optionally override the built-in
+	// subject and body templates, the latter two by file path
+	Subject      string `json:"subject"`
+	TextTemplate string `json:"text_template"`
+	HTMLTemplate string `json:"html_template"`
+}
+
+// emailSettings decodes and checks an email destination's own settings
+func emailSettings(destination *events.Destination) (emailConfig, error) {
+	var c emailConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if c.SMTPAddr == "" || c.From == "" {
+		return c, fmt.Errorf("email destination %q needs an smtp_addr and a from address", destination.Name)
+	}
+	if len(c.To) == 0 && c.SubscribersFile == "" {
+		return c, fmt.Errorf("email destination %q needs subscribers in to or subscribers_file", destination.Name)
+	}
+	if c.UsernameEnv == "" {
+		c.UsernameEnv = "SMTP_USERNAME"
+	}
+	if c.PasswordEnv == "" {
+		c.PasswordEnv = "SMTP_PASSWORD"
+	}
+	if c.Subject == "" {
+		c.Subject = defaultEmailSubject
+	}
+	return c, nil
+}
+
+// emailPublisher emails each post to a subscriber list over SMTP, including
+// Amazon SES's SMTP interface
+type emailPublisher struct {
+	adapter
+
+	name            string
+	addr            string
+	from            string
+	to              []string
+	subscribersFile string
+	usernameEnv     string
+	passwordEnv     string
+
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+func init() {
+	registerPublisher(destinationEmail, validateSettings(emailSettings), factoryOf(newEmailPublisher))
+}
+
+func newEmailPublisher(destination *events.Destination) (*emailPublisher, error) {
+	settings, err := emailSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	p := &emailPublisher{
+		adapter:         newAdapter(destination, "email", 0, events.HashtagsStrip),
+		name:            destination.Name,
+		addr:            settings.SMTPAddr,
+		from:            settings.From,
+		to:              settings.To,
+		subscribersFile: settings.SubscribersFile,
+		usernameEnv:     settings.UsernameEnv,
+		passwordEnv:     settings.PasswordEnv,
+	}
+
+	textBody, err := templateSource(settings.TextTemplate, defaultEmailText)
+	if err != nil {
+		return nil, err
+	}
+	htmlBody, err := templateSource(settings.HTMLTemplate, defaultEmailHTML)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.subject, err = template.New("subject").Parse(settings.Subject); err != nil {
+		return nil, fmt.Errorf("destination %q has an invalid subject: %w", p.name, err)
+	}
+	if p.text, err = template.New("text").Parse(textBody); err != nil {
+		return nil, fmt.Errorf("destination %q has an invalid text template: %w", p.name, err)
+	}
+	if p.html, err = htmltemplate.New("html").Parse(htmlBody); err != nil {
+		return nil, fmt.Errorf("destination %q has an invalid HTML template: %w", p.name, err)
+	}
+
+	return p, nil
+}
+
+// templateSource reads a template file, or returns def when no path is given
+func templateSource(path, def string) (string, error) {
+	if path == "" {
+		return def, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read email template: %w", err)
+	}
+	return string(data), nil
+}
+
+func (p *emailPublisher) Name() string {
+	return p.name
+}
+
+// Publish sends one email with plain text and HTML bodies, addressed to every
+// subscriber without disclosing them to each other
+func (p *emailPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	recipients, err := p.recipients()
+	if err != nil {
+		return Published{}, err
+	}
+	if len(recipients) == 0 {
+		return Published{}, nil
+	}
+
+	msg, err := p.message(post)
+	if err != nil {
+		return Published{}, err
+	}
+
+	// Sending isn't cancelled by a shutdown signal once it starts
+	ctx, cancel := sendRequest(ctx)
+	defer cancel()
+
+	return Published{}, p.send(ctx, recipients, msg)
+}
+
+// recipients lists the configured subscribers and those in the subscribers file
+func (p *emailPublisher) recipients() ([]string, error) {
+	recipients := append([]string{}, p.to...)
+	if p.subscribersFile == "" {
+		return recipients, nil
+	}
+
+	f, err := os.Open(p.subscribersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscribers: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			recipients = append(recipients, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read subscribers: %w", err)
+	}
+	return recipients, nil
+}
+
+// message renders the post as a multipart/alternative email
+func (p *emailPublisher) message(post *OutgoingPost) ([]byte, error) {
+	data := EmailData{
+		Text:     post.Text,
+		ImageURL: post.ImageURL,
+		ImageAlt: post.ImageAlt,
+		Date:     now.Format("Monday, January 2, 2006"),
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := p.subject.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %w", err)
+	}
+	if err := p.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render email text: %w", err)
+	}
+	if err := p.html.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render email HTML: %w", err)
+	}
+
+	b := make([]byte, 12)
+	rand.Read(b)
+	boundary := hex.EncodeToString(b)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", p.from)
+	msg.WriteString("To: undisclosed-recipients:;\r\n")
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary)
+
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"text/plain", text.Bytes()},
+		{"text/html", html.Bytes()},
+	} {
+		fmt.Fprintf(&msg, "--%s\r\n", boundary)
+		fmt.Fprintf(&msg, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&msg)
+		qp.Write(part.body)
+		qp.Close()
+		msg.WriteString("\r\n")
+	}
+	fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+
+	return msg.Bytes(), nil
+}
+
+// send delivers the message over SMTP, upgrading to TLS when the server
+// supports it, within ctx's deadline
+func (p *emailPublisher) send(ctx context.Context, recipients []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(p.addr)
+	if err != nil {
+		return fmt.Errorf("invalid smtp_addr %q: %w", p.addr, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return fmt.Errorf("SMTP connection failed: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+
+	if username := config.Getenv(p.usernameEnv); username != "" {
+		auth := smtp.PlainAuth("", username, config.Getenv(p.passwordEnv), host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(p.from); err != nil {
+		return fmt.Errorf("SMTP sender rejected: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("SMTP recipient %s rejected: %w", rcpt, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP data failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Special day modes
+const (
+	// SpecialInstead replaces the daily post with the special day's post
+	SpecialInstead = "instead"
+	// SpecialExtra posts the special day's post as a separate special post,
+	// in addition to the daily one
+	SpecialExtra = "extra"
+)
+
+// PostTypeSpecial is the post type extra special day posts are made as. It
+// needs a schedule saying when to post, and is skipped on ordinary days.
+const PostTypeSpecial = "special"
+
+// SpecialDay represents a one-off date, such as a debate or election night,
+// that gets its own prompt
+type SpecialDay struct {
+	// Date is the calendar day in the reference timezone, as YYYY-MM-DD
+	Date   string `json:"date"`
+	Name   string `json:"name"`
+	Prompt string `json:"prompt"`
+	Mode   string `json:"mode,omitempty"`
+}
+
+// ModeOrDefault returns the special day's mode, defaulting to SpecialExtra
+func (s *SpecialDay) ModeOrDefault() string {
+	if s.Mode == "" {
+		return SpecialExtra
+	}
+	return s.Mode
+}
+
+// validate checks that the special day is well formed
+func (s *SpecialDay) validate() error {
+	if s.Name == "" || s.Prompt == "" {
+		return errors.New("special days need a name and a prompt")
+	}
+	if _, err := time.Parse("2006-01-02", s.Date); err != nil {
+		return fmt.Errorf("special day %q has invalid date %q, expected YYYY-MM-DD", s.Name, s.Date)
+	}
+	switch s.ModeOrDefault() {
+	case SpecialInstead, SpecialExtra:
+	default:
+		return fmt.Errorf("special day %q has unknown mode %q", s.Name, s.Mode)
+	}
+	return nil
+}
+
+// SpecialDayOn returns the special day of the given mode on date's calendar
+// day, if there is one
+func (f *File) SpecialDayOn(date time.Time, mode string) (*SpecialDay, bool) {
+	day := date.Format("2006-01-02")
+	for i := range f.SpecialDays {
+		if f.SpecialDays[i].Date == day && f.SpecialDays[i].ModeOrDefault() == mode {
+			return &f.SpecialDays[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Completion actions, taken once every event's target has passed
+const (
+	CompleteShutdown  = "shutdown"
+	CompleteCelebrate = "celebrate"
+	CompleteWrapUp    = "wrap_up"
+	CompleteSwitch    = "switch"
+)
+
+// defaultWrapUpDays is the length of a wrap-up series when not configured
+const defaultWrapUpDays = 7
+
+// Completion represents what the bot does when the countdown is over
+type Completion struct {
+	Action string  `json:"action"`
+	Days   int     `json:"days,omitempty"`
+	Prompt string  `json:"prompt,omitempty"`
+	Events []Event `json:"events,omitempty"`
+}
+
+// ActionOrDefault returns the configured action, defaulting to shutdown
+func (c *Completion) ActionOrDefault() string {
+	if c == nil || c.Action == "" {
+		return CompleteShutdown
+	}
+	return c.Action
+}
+
+// SeriesDays returns how many days of posts follow the end of the countdown: one
+// for a celebration, seven (unless configured) for a wrap-up series
+func (c *Completion) SeriesDays() int {
+	switch c.ActionOrDefault() {
+	case CompleteCelebrate:
+		return 1
+	case CompleteWrapUp:
+		if c.Days > 0 {
+			return c.Days
+		}
+		return defaultWrapUpDays
+	default:
+		return 0
+	}
+}
+
+// validate checks the completion config and parses any events to switch to
+func (c *Completion) validate() error {
+	switch c.ActionOrDefault() {
+	case CompleteShutdown, CompleteCelebrate, CompleteWrapUp:
+	case CompleteSwitch:
+		if len(c.Events) == 0 {
+			return fmt.Errorf("on_complete action %q needs events to switch to", CompleteSwitch)
+		}
+		for i := range c.Events {
+			if err := c.Events[i].parse(); err != nil {
+				return err
+			}
+		}
+		sort.SliceStable(c.Events, func(i, j int) bool {
+			return c.Events[i].target.Before(c.Events[j].target)
+		})
+	default:
+		return fmt.Errorf("unknown on_complete action %q", c.Action)
+	}
+	return nil
+}
+
+// Last returns the enabled event with the latest target, which marks the end of
+// the countdown. Recurring events never end, so are ignored.
+func Last(evts []Event) (*Event, bool) {
+	var last *Event
+	for i := range evts {
+		if !evts[i].IsEnabled() || evts[i].Recurrence != nil {
+			continue
+		}
+		if last == nil || evts[i].target.After(last.target) {
+			last = &evts[i]
+		}
+	}
+	return last, last != nil
+}
+
+// ExitDate returns the moment after which the bot has nothing left to post.
+// exit_after takes precedence; otherwise it is derived from the final event's
+// target plus any celebration or wrap-up series. There is no exit date while a
+// recurring event is enabled, since those never end.
+func (f *File) ExitDate() (time.Time, bool, error) {
+	if f.ExitAfter != "" {
+		loc, err := f.Location()
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		exit, ok := parseTime(f.ExitAfter, loc)
+		if !ok {
+			return time.Time{}, false, fmt.Errorf("invalid exit_after %q", f.ExitAfter)
+		}
+		return exit, true, nil
+	}
+
+	evts := f.Events
+	if f.OnComplete.ActionOrDefault() == CompleteSwitch {
+		evts = append(append([]Event{}, f.Events...), f.OnComplete.Events...)
+	}
+
+	for _, e := range evts {
+		if e.IsEnabled() && e.Recurrence != nil {
+			return time.Time{}, false, nil
+		}
+	}
+
+	final, ok := Last(evts)
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	return final.target.AddDate(0, 0, f.OnComplete.SeriesDays()), true, nil
+}
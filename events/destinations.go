@@ -0,0 +1,144 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// How a post is adapted to a destination: by rules alone, or rewritten by an
+// LLM first
+const (
+	AdaptRules = "rules"
+	AdaptLLM   = "llm"
+)
+
+// Hashtag conventions: keep every hashtag, keep only the first, or strip them
+const (
+	HashtagsKeep  = "keep"
+	HashtagsFirst = "first"
+	HashtagsStrip = "strip"
+)
+
+// Link handling: keep links in the text, or strip them
+const (
+	LinksKeep  = "keep"
+	LinksStrip = "strip"
+)
+
+// defaultMinInterval is the least time between two posts to a destination
+// when it doesn't configure one
+const defaultMinInterval = 30 * time.Second
+
+// Destination represents an account or platform that posts are published to.
+// It holds the settings every destination shares; the settings of its own
+// platform, such as where to post and the environment variables holding its
+// credentials, are read by that platform's publisher with Decode. Credentials
+// are never stored in the events file.
+type Destination struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// Primary destinations must be published to for a run to succeed;
+	// failures elsewhere are reported without failing it. When no destination
+	// is marked primary, the Bluesky ones are, or else the first.
+	Primary bool `json:"primary,omitempty"`
+
+	// Adapt is how posts are adapted to the destination (default rules).
+	// MaxLength, Hashtags and Links override the platform's length limit,
+	// hashtag convention and link handling.
+	Adapt     string `json:"adapt,omitempty"`
+	MaxLength int    `json:"max_length,omitempty"`
+	Hashtags  string `json:"hashtags,omitempty"`
+	Links     string `json:"links,omitempty"`
+
+	// Prefix and Suffix are added around the adapted text, HashtagSubstitutions
+	// swaps hashtags for the destination's own, and AppendLink names another
+	// destination whose link to the post is appended, e.g. a suffix of
+	// "🔁 from Bluesky" with the Bluesky post's link
+	Prefix               string            `json:"prefix,omitempty"`
+	Suffix               string            `json:"suffix,omitempty"`
+	HashtagSubstitutions map[string]string `json:"hashtag_substitutions,omitempty"`
+	AppendLink           string            `json:"append_link,omitempty"`
+
+	// MinInterval is the least time between two posts to the destination, so
+	// bursts don't trip anti-spam rules, and Burst how many may go at once
+	MinInterval string `json:"min_interval,omitempty"`
+	Burst       int    `json:"burst,omitempty"`
+
+	// settings is the destination as written in the events file, for its
+	// publisher to decode its own settings from
+	settings json.RawMessage
+}
+
+// UnmarshalJSON decodes the shared settings, and keeps the rest for Decode
+func (d *Destination) UnmarshalJSON(data []byte) error {
+	type destination Destination
+	if err := json.Unmarshal(data, (*destination)(d)); err != nil {
+		return err
+	}
+	d.settings = slices.Clone(data)
+	return nil
+}
+
+// Decode decodes the settings of the destination's own platform into v, a
+// pointer to the platform's settings struct. A destination that wasn't read
+// from an events file has none, leaving v as it is.
+func (d *Destination) Decode(v any) error {
+	if len(d.settings) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(d.settings, v); err != nil {
+		return fmt.Errorf("destination %q has invalid settings: %w", d.Name, err)
+	}
+	return nil
+}
+
+// MinIntervalDuration returns the least time between two posts to the
+// destination (default 30s), or zero when it isn't throttled
+func (d *Destination) MinIntervalDuration() (time.Duration, error) {
+	if d.MinInterval == "" {
+		return defaultMinInterval, nil
+	}
+	interval, err := time.ParseDuration(d.MinInterval)
+	if err != nil {
+		return 0, fmt.Errorf("destination %q has invalid min_interval %q: %w", d.Name, d.MinInterval, err)
+	}
+	return interval, nil
+}
+
+// validate checks the settings every destination shares. Its publisher
+// checks the rest.
+func (d *Destination) validate() error {
+	if d.Name == "" {
+		return errors.New("destinations need a name")
+	}
+	if d.Type == "" {
+		return fmt.Errorf("destination %q needs a type", d.Name)
+	}
+	switch d.Adapt {
+	case "", AdaptRules, AdaptLLM:
+	default:
+		return fmt.Errorf("destination %q has unknown adapt %q, expected rules or llm", d.Name, d.Adapt)
+	}
+	switch d.Hashtags {
+	case "", HashtagsKeep, HashtagsFirst, HashtagsStrip:
+	default:
+		return fmt.Errorf("destination %q has unknown hashtags %q, expected keep, first or strip", d.Name, d.Hashtags)
+	}
+	switch d.Links {
+	case "", LinksKeep, LinksStrip:
+	default:
+		return fmt.Errorf("destination %q has unknown links %q, expected keep or strip", d.Name, d.Links)
+	}
+	if d.MaxLength < 0 {
+		return fmt.Errorf("destination %q has negative max_length %d", d.Name, d.MaxLength)
+	}
+	if d.Burst < 0 {
+		return fmt.Errorf("destination %q has negative burst %d", d.Name, d.Burst)
+	}
+	_, err := d.MinIntervalDuration()
+	return err
+}
@@ -0,0 +1,443 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/lukeocodes/go-trump/countdown"
+)
+
+// targetLayouts are the accepted formats for an event's target datetime. Values
+// without an offset are interpreted in the event's timezone.
+var targetLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// Counting modes
+const (
+	ModeCountdown = "countdown"
+	ModeCountUp   = "countup"
+	ModeBoth      = "both"
+)
+
+// Event represents a single date the bot can count down to, or count up from
+type Event struct {
+	Name        string `json:"name"`
+	Mode        string `json:"mode,omitempty"`
+	StartName   string `json:"start_name,omitempty"`
+	Start       string `json:"start,omitempty"`
+	Target      string `json:"target"`
+	Timezone    string `json:"timezone"`
+	Hashtag     string `json:"hashtag"`
+	PromptHints string `json:"prompt_hints"`
+	Enabled     *bool  `json:"enabled,omitempty"`
+
+	// Recurrence computes the target from a rule instead of Target
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+
+	loc    *time.Location
+	start  time.Time
+	target time.Time
+}
+
+// File represents the structure of an events config file
+type File struct {
+	Timezone               string        `json:"timezone,omitempty"`
+	Prompt                 string        `json:"prompt,omitempty"`
+	IncludePercentComplete bool          `json:"include_percent_complete,omitempty"`
+	ProgressBarWidth       int           `json:"progress_bar_width,omitempty"`
+	OnThisDay              bool          `json:"on_this_day,omitempty"`
+	FinalStretchDays       int           `json:"final_stretch_days,omitempty"`
+	FinalPostWait          string        `json:"final_post_wait,omitempty"`
+	Locale                 string        `json:"locale,omitempty"`
+	SpellMilestoneNumbers  bool          `json:"spell_milestone_numbers,omitempty"`
+	Holidays               []string      `json:"holidays,omitempty"`
+	FederalHolidays        bool          `json:"federal_holidays,omitempty"`
+	Events                 []Event       `json:"events"`
+	Milestones             []Milestone   `json:"milestones,omitempty"`
+	OnComplete             *Completion   `json:"on_complete,omitempty"`
+	Schedules              []Schedule    `json:"schedules,omitempty"`
+	SpecialDays            []SpecialDay  `json:"special_days,omitempty"`
+	SkipDays               []SkipDay     `json:"skip_days,omitempty"`
+	SkipHolidays           bool          `json:"skip_holidays,omitempty"`
+	QuietHours             []QuietHours  `json:"quiet_hours,omitempty"`
+	Destinations           []Destination `json:"destinations,omitempty"`
+
+	// ExitAfter overrides the date after which the bot stops posting, which is
+	// otherwise derived from the events
+	ExitAfter string `json:"exit_after,omitempty"`
+}
+
+// Location returns the reference timezone that day counts are calculated in,
+// so "days left" flips at local midnight rather than UTC midnight
+func (f *File) Location() (*time.Location, error) {
+	if f.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(f.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference timezone %q: %w", f.Timezone, err)
+	}
+	return loc, nil
+}
+
+// defaultFinalStretchDays is how many days before a target posts switch to
+// exact hours and minutes remaining
+const defaultFinalStretchDays = 3
+
+// FinalStretch returns how many days before a target posts switch to exact
+// hours and minutes remaining
+func (f *File) FinalStretch() int {
+	if f.FinalStretchDays > 0 {
+		return f.FinalStretchDays
+	}
+	return defaultFinalStretchDays
+}
+
+// FinalPostWaitDuration returns how long a run may wait for the countdown to
+// end so the final post goes out at the exact moment, or zero when disabled
+func (f *File) FinalPostWaitDuration() (time.Duration, error) {
+	if f.FinalPostWait == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(f.FinalPostWait)
+	if err != nil {
+		return 0, fmt.Errorf("invalid final_post_wait %q: %w", f.FinalPostWait, err)
+	}
+	return d, nil
+}
+
+// HolidayDates returns the configured holidays between two years inclusive,
+// including US federal holidays when enabled, for business day counts
+func (f *File) HolidayDates(fromYear, toYear int) ([]time.Time, error) {
+	var holidays []time.Time
+	for _, h := range f.Holidays {
+		t, err := time.Parse("2006-01-02", h)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %q, expected YYYY-MM-DD", h)
+		}
+		holidays = append(holidays, t)
+	}
+
+	if f.FederalHolidays {
+		for year := fromYear; year <= toYear; year++ {
+			holidays = append(holidays, countdown.USFederalHolidays(year)...)
+		}
+	}
+
+	return holidays, nil
+}
+
+// Time returns the parsed target datetime of the event
+func (e *Event) Time() time.Time {
+	return e.target
+}
+
+// StartTime returns the parsed start datetime of the event, and whether one is set
+func (e *Event) StartTime() (time.Time, bool) {
+	return e.start, !e.start.IsZero()
+}
+
+// CountsDown reports whether posts for the event count the days remaining
+func (e *Event) CountsDown() bool {
+	return e.Mode == "" || e.Mode == ModeCountdown || e.Mode == ModeBoth
+}
+
+// CountsUp reports whether posts for the event count the days elapsed since its start
+func (e *Event) CountsUp() bool {
+	return e.Mode == ModeCountUp || e.Mode == ModeBoth
+}
+
+// IsActive reports whether the event should be posted about at now. Count-up
+// only events don't become active until their start.
+func (e *Event) IsActive(now time.Time) bool {
+	if !e.IsEnabled() || !now.Before(e.target) {
+		return false
+	}
+	if !e.CountsDown() && now.Before(e.start) {
+		return false
+	}
+	return true
+}
+
+// IsEnabled reports whether the event should be counted down to. Events are
+// enabled unless explicitly disabled in config.
+func (e *Event) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// PercentComplete returns how much of the time between the event's start and
+// target has elapsed at now, from 0 to 100, and whether the event has a start
+func (e *Event) PercentComplete(now time.Time) (float64, bool) {
+	if e.start.IsZero() {
+		return 0, false
+	}
+
+	total := e.target.Sub(e.start)
+	elapsed := now.Sub(e.start)
+	pct := float64(elapsed) / float64(total) * 100
+
+	return math.Max(0, math.Min(100, pct)), true
+}
+
+// parse resolves the event's target into a time.Time in its timezone
+func (e *Event) parse() error {
+	if e.Name == "" {
+		return errors.New("event is missing a name")
+	}
+
+	tz := e.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return fmt.Errorf("event %q has invalid timezone %q: %w", e.Name, tz, err)
+	}
+	e.loc = loc
+
+	if e.Recurrence != nil {
+		if err := e.Recurrence.validate(); err != nil {
+			return fmt.Errorf("event %q: %w", e.Name, err)
+		}
+		if e.Start != "" || (e.Mode != "" && e.Mode != ModeCountdown) {
+			return fmt.Errorf("event %q recurs so it can only count down, without a start", e.Name)
+		}
+		return nil
+	}
+
+	target, ok := parseTime(e.Target, loc)
+	if !ok {
+		return fmt.Errorf("event %q has invalid target %q", e.Name, e.Target)
+	}
+	e.target = target
+
+	if e.Start != "" {
+		start, ok := parseTime(e.Start, loc)
+		if !ok {
+			return fmt.Errorf("event %q has invalid start %q", e.Name, e.Start)
+		}
+		if !start.Before(target) {
+			return fmt.Errorf("event %q starts after its target", e.Name)
+		}
+		e.start = start
+	}
+
+	switch e.Mode {
+	case "", ModeCountdown:
+	case ModeCountUp, ModeBoth:
+		if e.start.IsZero() {
+			return fmt.Errorf("event %q counts up so it needs a start", e.Name)
+		}
+	default:
+		return fmt.Errorf("event %q has unknown mode %q", e.Name, e.Mode)
+	}
+
+	return nil
+}
+
+// parseTime parses a datetime in any of the accepted layouts
+func parseTime(value string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range targetLayouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Default returns the built-in events config, used when no events file is configured
+func Default() *File {
+	evts := []Event{
+		{
+			Name:        "Trump's inauguration",
+			Target:      "2025-01-20T12:00:00",
+			Timezone:    "America/New_York",
+			Hashtag:     "#TheFinalTrumpDown",
+			PromptHints: "Trump is not a good guy. Say something randomly positive to get people through this.",
+		},
+		{
+			Name:        "the end of Trump's 2nd term in office",
+			Start:       "2025-01-20T12:00:00",
+			Target:      "2029-01-20T12:00:00",
+			Timezone:    "America/New_York",
+			Hashtag:     "#TheFinalTrumpDown",
+			PromptHints: "Trump is not a good guy. Say something randomly positive to get people through this.",
+		},
+	}
+
+	for i := range evts {
+		// Built-in events are known to be valid
+		_ = evts[i].parse()
+	}
+
+	return &File{
+		Events:     evts,
+		Milestones: DefaultMilestones(),
+	}
+}
+
+// Load reads and validates event definitions from a JSON file
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events file: %w", err)
+	}
+
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode events file: %w", err)
+	}
+
+	if _, err := file.Location(); err != nil {
+		return nil, err
+	}
+
+	if _, err := file.FinalPostWaitDuration(); err != nil {
+		return nil, err
+	}
+
+	if _, _, err := file.ExitDate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := file.HolidayDates(0, -1); err != nil {
+		return nil, err
+	}
+
+	if len(file.Events) == 0 {
+		return nil, fmt.Errorf("events file %s defines no events", path)
+	}
+
+	for i := range file.Events {
+		if err := file.Events[i].parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(file.Events, func(i, j int) bool {
+		return file.Events[i].target.Before(file.Events[j].target)
+	})
+
+	if file.Milestones == nil {
+		file.Milestones = DefaultMilestones()
+	}
+	for i := range file.Milestones {
+		if err := file.Milestones[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if file.OnComplete != nil {
+		if err := file.OnComplete.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range file.SkipDays {
+		if err := file.SkipDays[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range file.QuietHours {
+		if err := file.QuietHours[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	destinations := map[string]bool{}
+	for i := range file.Destinations {
+		destination := &file.Destinations[i]
+		if err := destination.validate(); err != nil {
+			return nil, err
+		}
+		if destinations[destination.Name] {
+			return nil, fmt.Errorf("destination %q is defined more than once", destination.Name)
+		}
+		destinations[destination.Name] = true
+	}
+	for _, destination := range file.Destinations {
+		if destination.AppendLink == "" {
+			continue
+		}
+		if destination.AppendLink == destination.Name || !destinations[destination.AppendLink] {
+			return nil, fmt.Errorf("destination %q appends the link of unknown destination %q", destination.Name, destination.AppendLink)
+		}
+	}
+
+	days := map[string]bool{}
+	for i := range file.SpecialDays {
+		special := &file.SpecialDays[i]
+		if err := special.validate(); err != nil {
+			return nil, err
+		}
+		key := special.Date + " " + special.ModeOrDefault()
+		if days[key] {
+			return nil, fmt.Errorf("more than one %s special day on %s", special.ModeOrDefault(), special.Date)
+		}
+		days[key] = true
+	}
+
+	seen := map[string]bool{}
+	for _, schedule := range file.Schedules {
+		if schedule.Name == "" || schedule.Cron == "" {
+			return nil, errors.New("schedules need a name and a cron expression")
+		}
+		if seen[schedule.Name] {
+			return nil, fmt.Errorf("schedule %q is defined more than once", schedule.Name)
+		}
+		seen[schedule.Name] = true
+
+		if schedule.Prompt == "" && !HasBuiltinPrompt(schedule.Name) {
+			return nil, fmt.Errorf("schedule %q needs a prompt", schedule.Name)
+		}
+		if schedule.Jitter != "" {
+			if _, err := time.ParseDuration(schedule.Jitter); err != nil {
+				return nil, fmt.Errorf("schedule %q has invalid jitter %q: %w", schedule.Name, schedule.Jitter, err)
+			}
+		}
+		if schedule.Timezone != "" {
+			if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+				return nil, fmt.Errorf("schedule %q has invalid timezone %q: %w", schedule.Name, schedule.Timezone, err)
+			}
+		}
+	}
+
+	return &file, nil
+}
+
+// Upcoming returns every active event whose target has not yet passed, nearest
+// first. Recurring events are resolved to their next occurrence.
+func Upcoming(evts []Event, now time.Time) []Event {
+	var upcoming []Event
+	for _, e := range evts {
+		e = e.resolve(now)
+		if e.IsActive(now) {
+			upcoming = append(upcoming, e)
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].target.Before(upcoming[j].target)
+	})
+
+	return upcoming
+}
+
+// Next returns the first active event whose target has not yet passed
+func Next(evts []Event, now time.Time) (*Event, bool) {
+	upcoming := Upcoming(evts, now)
+	if len(upcoming) == 0 {
+		return nil, false
+	}
+	return &upcoming[0], true
+}
@@ -0,0 +1,88 @@
+package events
+
+import "fmt"
+
+// Milestone kinds
+const (
+	MilestoneDays      = "days"
+	MilestoneHalfway   = "halfway"
+	MilestoneFinalWeek = "final_week"
+)
+
+// Milestone represents a special day in a countdown that gets its own prompt
+// and, optionally, an image instead of the ordinary daily message
+type Milestone struct {
+	Kind     string `json:"kind"`
+	Days     int    `json:"days,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+	Image    string `json:"image,omitempty"`
+	ImageAlt string `json:"image_alt,omitempty"`
+
+	// ImageURL is where the image is publicly served, for destinations like
+	// Threads that fetch images themselves rather than taking an upload
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// Label describes the milestone for use in prompts and logs, with number
+// formatting the day count
+func (m *Milestone) Label(number func(int) string) string {
+	switch m.Kind {
+	case MilestoneHalfway:
+		return "the halfway point"
+	case MilestoneFinalWeek:
+		return "the final week"
+	default:
+		return fmt.Sprintf("%s days to go", number(m.Days))
+	}
+}
+
+// validate checks that the milestone is well formed
+func (m *Milestone) validate() error {
+	switch m.Kind {
+	case MilestoneDays:
+		if m.Days <= 0 {
+			return fmt.Errorf("days milestone must have a positive days value, got %d", m.Days)
+		}
+	case MilestoneHalfway, MilestoneFinalWeek:
+	default:
+		return fmt.Errorf("unknown milestone kind %q", m.Kind)
+	}
+	return nil
+}
+
+// DefaultMilestones returns the built-in milestones, used when the events file
+// doesn't configure any
+func DefaultMilestones() []Milestone {
+	return []Milestone{
+		{Kind: MilestoneDays, Days: 1000},
+		{Kind: MilestoneDays, Days: 500},
+		{Kind: MilestoneDays, Days: 365},
+		{Kind: MilestoneDays, Days: 100},
+		{Kind: MilestoneHalfway},
+		{Kind: MilestoneFinalWeek},
+	}
+}
+
+// DetectMilestone returns the milestone that falls on a day with daysLeft
+// remaining. totalDays is the length of the countdown from the event's start,
+// or zero when the event has no start, in which case halfway can't be detected.
+func DetectMilestone(milestones []Milestone, daysLeft, totalDays int) (*Milestone, bool) {
+	for i := range milestones {
+		m := &milestones[i]
+		switch m.Kind {
+		case MilestoneDays:
+			if daysLeft == m.Days {
+				return m, true
+			}
+		case MilestoneHalfway:
+			if totalDays > 0 && daysLeft == totalDays/2 {
+				return m, true
+			}
+		case MilestoneFinalWeek:
+			if daysLeft >= 1 && daysLeft <= 7 {
+				return m, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lukeocodes/go-trump/countdown"
+)
+
+// Recurrence rules
+const (
+	// RuleElectionDay is the first Tuesday after the first Monday in November,
+	// the date of US federal elections
+	RuleElectionDay = "election_day"
+	// RuleNthWeekday is the nth weekday of a month, e.g. the 4th Thursday of November
+	RuleNthWeekday = "nth_weekday"
+	// RuleFixed is the same month and day every cycle, e.g. January 20
+	RuleFixed = "fixed"
+)
+
+// yearPlaceholder in an event's name is replaced with the occurrence's year
+const yearPlaceholder = "{year}"
+
+// Recurrence represents a rule that computes an event's target, so recurring
+// events like midterms stay correct across cycles without manual date entry
+type Recurrence struct {
+	Rule    string `json:"rule"`
+	Every   int    `json:"every,omitempty"`
+	From    int    `json:"from,omitempty"`
+	Month   int    `json:"month,omitempty"`
+	Day     int    `json:"day,omitempty"`
+	Weekday string `json:"weekday,omitempty"`
+	N       int    `json:"n,omitempty"`
+	Time    string `json:"time,omitempty"`
+
+	weekday time.Weekday
+	hour    int
+	minute  int
+}
+
+// validate checks the recurrence rule and resolves its weekday and time of day
+func (r *Recurrence) validate() error {
+	if r.Every < 0 {
+		return fmt.Errorf("recurrence every must be positive, got %d", r.Every)
+	}
+
+	switch r.Rule {
+	case RuleElectionDay:
+	case RuleNthWeekday:
+		if r.Month < 1 || r.Month > 12 {
+			return fmt.Errorf("recurrence has invalid month %d", r.Month)
+		}
+		if r.N < 1 || r.N > 5 {
+			return fmt.Errorf("recurrence n must be between 1 and 5, got %d", r.N)
+		}
+		weekday, ok := parseWeekday(r.Weekday)
+		if !ok {
+			return fmt.Errorf("recurrence has invalid weekday %q", r.Weekday)
+		}
+		r.weekday = weekday
+	case RuleFixed:
+		if r.Month < 1 || r.Month > 12 {
+			return fmt.Errorf("recurrence has invalid month %d", r.Month)
+		}
+		// February is allowed its 29th, which falls on the 28th in common years
+		if r.Day < 1 || r.Day > daysIn(2000, time.Month(r.Month)) {
+			return fmt.Errorf("recurrence has invalid day %d for month %d", r.Day, r.Month)
+		}
+	default:
+		return fmt.Errorf("unknown recurrence rule %q", r.Rule)
+	}
+
+	if r.Time != "" {
+		t, err := time.Parse("15:04", r.Time)
+		if err != nil {
+			return fmt.Errorf("recurrence has invalid time %q, expected HH:MM", r.Time)
+		}
+		r.hour, r.minute = t.Hour(), t.Minute()
+	}
+
+	return nil
+}
+
+// inCycle reports whether an occurrence falls in year
+func (r *Recurrence) inCycle(year int) bool {
+	if r.Every <= 1 {
+		return true
+	}
+	diff := (year - r.From) % r.Every
+	return diff == 0
+}
+
+// occurrence returns the rule's date in year
+func (r *Recurrence) occurrence(year int, loc *time.Location) time.Time {
+	switch r.Rule {
+	case RuleElectionDay:
+		// The first Monday is between the 1st and 7th, so the Tuesday after
+		// it is between the 2nd and 8th
+		d := countdown.NthWeekday(year, time.November, time.Monday, 1).AddDate(0, 0, 1)
+		return time.Date(year, time.November, d.Day(), r.hour, r.minute, 0, 0, loc)
+	case RuleNthWeekday:
+		// A 5th weekday the month doesn't have is its last, the 4th
+		d := countdown.NthWeekday(year, time.Month(r.Month), r.weekday, r.N)
+		if d.Month() != time.Month(r.Month) {
+			d = d.AddDate(0, 0, -7)
+		}
+		return time.Date(year, d.Month(), d.Day(), r.hour, r.minute, 0, 0, loc)
+	default:
+		day := min(r.Day, daysIn(year, time.Month(r.Month)))
+		return time.Date(year, time.Month(r.Month), day, r.hour, r.minute, 0, 0, loc)
+	}
+}
+
+// daysIn returns the number of days in a month of year
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// Next returns the first occurrence that is not before now
+func (r *Recurrence) Next(now time.Time, loc *time.Location) time.Time {
+	year := now.In(loc).Year()
+	for {
+		if r.inCycle(year) {
+			t := r.occurrence(year, loc)
+			if !t.Before(now) {
+				return t
+			}
+		}
+		year++
+	}
+}
+
+// parseWeekday parses a weekday name like "tuesday" or "Tue"
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// resolve returns a copy of a recurring event with its target set to the next
+// occurrence at now and any {year} in its name filled in
+func (e Event) resolve(now time.Time) Event {
+	if e.Recurrence == nil {
+		return e
+	}
+	e.target = e.Recurrence.Next(now, e.loc)
+	e.Name = strings.ReplaceAll(e.Name, yearPlaceholder, strconv.Itoa(e.target.Year()))
+	return e
+}
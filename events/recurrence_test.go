@@ -0,0 +1,179 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		r    Recurrence
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "election day this year",
+			r:    Recurrence{Rule: RuleElectionDay},
+			now:  time.Date(2026, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2026, time.November, 3, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "election day when the 1st is a Tuesday",
+			r:    Recurrence{Rule: RuleElectionDay},
+			now:  time.Date(2033, time.January, 1, 0, 0, 0, 0, ny),
+			want: time.Date(2033, time.November, 8, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "presidential election every 4 years",
+			r:    Recurrence{Rule: RuleElectionDay, Every: 4, From: 2028, Time: "06:00"},
+			now:  time.Date(2026, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2028, time.November, 7, 6, 0, 0, 0, ny),
+		},
+		{
+			name: "midterms once this cycle's have passed",
+			r:    Recurrence{Rule: RuleElectionDay, Every: 4, From: 2026},
+			now:  time.Date(2026, time.November, 4, 0, 0, 0, 0, ny),
+			want: time.Date(2030, time.November, 5, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "at the occurrence",
+			r:    Recurrence{Rule: RuleElectionDay, Time: "06:00"},
+			now:  time.Date(2026, time.November, 3, 6, 0, 0, 0, ny),
+			want: time.Date(2026, time.November, 3, 6, 0, 0, 0, ny),
+		},
+		{
+			name: "4th Thursday of November",
+			r:    Recurrence{Rule: RuleNthWeekday, Month: 11, Weekday: "thursday", N: 4},
+			now:  time.Date(2026, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2026, time.November, 26, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "nth weekday next year",
+			r:    Recurrence{Rule: RuleNthWeekday, Month: 11, Weekday: "Thu", N: 4},
+			now:  time.Date(2026, time.November, 27, 0, 0, 0, 0, ny),
+			want: time.Date(2027, time.November, 25, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "5th weekday the month has",
+			r:    Recurrence{Rule: RuleNthWeekday, Month: 10, Weekday: "thursday", N: 5},
+			now:  time.Date(2026, time.October, 1, 0, 0, 0, 0, ny),
+			want: time.Date(2026, time.October, 29, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "5th weekday the month doesn't have is its last",
+			r:    Recurrence{Rule: RuleNthWeekday, Month: 11, Weekday: "monday", N: 5},
+			now:  time.Date(2026, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2026, time.November, 30, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "5th weekday in a four week month",
+			r:    Recurrence{Rule: RuleNthWeekday, Month: 2, Weekday: "friday", N: 5},
+			now:  time.Date(2026, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2027, time.February, 26, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "leap day in a leap year",
+			r:    Recurrence{Rule: RuleFixed, Month: 2, Day: 29},
+			now:  time.Date(2027, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2028, time.February, 29, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "leap day in a common year",
+			r:    Recurrence{Rule: RuleFixed, Month: 2, Day: 29},
+			now:  time.Date(2026, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2027, time.February, 28, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "fixed inauguration day",
+			r:    Recurrence{Rule: RuleFixed, Every: 4, From: 2029, Month: 1, Day: 20, Time: "12:00"},
+			now:  time.Date(2026, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2029, time.January, 20, 12, 0, 0, 0, ny),
+		},
+		{
+			name: "fixed later this year",
+			r:    Recurrence{Rule: RuleFixed, Month: 12, Day: 25},
+			now:  time.Date(2026, time.October, 14, 0, 0, 0, 0, ny),
+			want: time.Date(2026, time.December, 25, 0, 0, 0, 0, ny),
+		},
+		{
+			name: "year taken in the event's timezone",
+			r:    Recurrence{Rule: RuleFixed, Month: 1, Day: 1},
+			now:  time.Date(2027, time.January, 1, 3, 0, 0, 0, time.UTC),
+			want: time.Date(2027, time.January, 1, 0, 0, 0, 0, ny),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.r.validate(); err != nil {
+				t.Fatalf("validate() error = %v", err)
+			}
+			if got := tt.r.Next(tt.now, ny); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       Recurrence
+		wantErr bool
+	}{
+		{"election day", Recurrence{Rule: RuleElectionDay}, false},
+		{"nth weekday", Recurrence{Rule: RuleNthWeekday, Month: 5, Weekday: "mon", N: 5}, false},
+		{"fixed with time", Recurrence{Rule: RuleFixed, Month: 7, Day: 4, Time: "09:30"}, false},
+		{"unknown rule", Recurrence{Rule: "lunar"}, true},
+		{"negative every", Recurrence{Rule: RuleElectionDay, Every: -4}, true},
+		{"nth weekday without month", Recurrence{Rule: RuleNthWeekday, Weekday: "monday", N: 1}, true},
+		{"nth weekday n too large", Recurrence{Rule: RuleNthWeekday, Month: 5, Weekday: "monday", N: 6}, true},
+		{"nth weekday unknown weekday", Recurrence{Rule: RuleNthWeekday, Month: 5, Weekday: "mo", N: 1}, true},
+		{"fixed month 13", Recurrence{Rule: RuleFixed, Month: 13, Day: 1}, true},
+		{"fixed day 0", Recurrence{Rule: RuleFixed, Month: 1}, true},
+		{"fixed day 31 of a 30 day month", Recurrence{Rule: RuleFixed, Month: 4, Day: 31}, true},
+		{"fixed February 30", Recurrence{Rule: RuleFixed, Month: 2, Day: 30}, true},
+		{"fixed February 29", Recurrence{Rule: RuleFixed, Month: 2, Day: 29}, false},
+		{"invalid time", Recurrence{Rule: RuleFixed, Month: 1, Day: 20, Time: "noon"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.r.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	e := Event{Name: "the {year} midterms", Timezone: "America/New_York", Recurrence: &Recurrence{Rule: RuleElectionDay, Every: 4, From: 2026}}
+	if err := e.parse(); err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC), "the 2026 midterms"},
+		{time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC), "the 2030 midterms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := e.resolve(tt.now).Name; got != tt.want {
+				t.Errorf("resolve(%v).Name = %q, want %q", tt.now, got, tt.want)
+			}
+		})
+	}
+	if e.Name != "the {year} midterms" {
+		t.Errorf("resolve() changed the event's name to %q", e.Name)
+	}
+}
@@ -0,0 +1,55 @@
+package events
+
+// Post types with built-in prompts
+const (
+	// PostTypeDaily is the ordinary daily post, which milestones apply to
+	PostTypeDaily = "daily"
+	// PostTypeMorning is the first of two posts a day, which milestones apply to
+	PostTypeMorning = "morning"
+	// PostTypeEvening is the second of two posts a day
+	PostTypeEvening = "evening"
+	// PostTypeWeeklyRecap is a weekly recap post
+	PostTypeWeeklyRecap = "weekly_recap"
+)
+
+// HasBuiltinPrompt reports whether a post type has a built-in prompt. Special
+// posts take theirs from the special day.
+func HasBuiltinPrompt(postType string) bool {
+	switch postType {
+	case PostTypeDaily, PostTypeMorning, PostTypeEvening, PostTypeWeeklyRecap, PostTypeSpecial:
+		return true
+	}
+	return false
+}
+
+// HasMilestones reports whether milestone days change a post type's prompt
+func HasMilestones(postType string) bool {
+	return postType == PostTypeDaily || postType == PostTypeMorning
+}
+
+// Schedule represents when the daemon makes one type of post. The schedule's
+// name is its post type, and is tracked separately so each type posts once.
+type Schedule struct {
+	Name     string `json:"name"`
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+	Jitter   string `json:"jitter,omitempty"`
+
+	// MaxLateness is how late a missed post may still be caught up, e.g. "6h"
+	MaxLateness string `json:"max_lateness,omitempty"`
+}
+
+// ScheduleNamed returns the configured schedule for a post type. The daily
+// post type is always available, even when not scheduled.
+func (f *File) ScheduleNamed(name string) (*Schedule, bool) {
+	for i := range f.Schedules {
+		if f.Schedules[i].Name == name {
+			return &f.Schedules[i], true
+		}
+	}
+	if name == PostTypeDaily {
+		return &Schedule{Name: PostTypeDaily}, true
+	}
+	return nil, false
+}
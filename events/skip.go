@@ -0,0 +1,108 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SkipDay represents a date, or an inclusive range of dates, on which nothing
+// is posted, e.g. out of respect after a national tragedy
+type SkipDay struct {
+	// Date is the first calendar day skipped, as YYYY-MM-DD
+	Date string `json:"date"`
+	// Until optionally extends the skip to a later day, inclusive
+	Until  string `json:"until,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// QuietHours represents a daily window in the reference timezone during which
+// nothing is posted. A window may wrap past midnight, e.g. 22:00 to 07:00.
+type QuietHours struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// validate checks that the skip day is well formed
+func (s *SkipDay) validate() error {
+	from, err := time.Parse("2006-01-02", s.Date)
+	if err != nil {
+		return fmt.Errorf("invalid skip day %q, expected YYYY-MM-DD", s.Date)
+	}
+	if s.Until != "" {
+		until, err := time.Parse("2006-01-02", s.Until)
+		if err != nil {
+			return fmt.Errorf("invalid skip day until %q, expected YYYY-MM-DD", s.Until)
+		}
+		if until.Before(from) {
+			return fmt.Errorf("skip day until %s is before %s", s.Until, s.Date)
+		}
+	}
+	return nil
+}
+
+// covers reports whether the skip day includes the calendar day
+func (s *SkipDay) covers(day string) bool {
+	until := s.Until
+	if until == "" {
+		until = s.Date
+	}
+	// YYYY-MM-DD dates sort lexically
+	return day >= s.Date && day <= until
+}
+
+// validate checks that the quiet hours are well formed
+func (q *QuietHours) validate() error {
+	if _, err := time.Parse("15:04", q.From); err != nil {
+		return fmt.Errorf("invalid quiet hours from %q, expected HH:MM", q.From)
+	}
+	if _, err := time.Parse("15:04", q.To); err != nil {
+		return fmt.Errorf("invalid quiet hours to %q, expected HH:MM", q.To)
+	}
+	if q.From == q.To {
+		return errors.New("quiet hours must not start and end at the same time")
+	}
+	return nil
+}
+
+// contains reports whether the time of day falls within the quiet hours
+func (q *QuietHours) contains(t time.Time) bool {
+	clock := t.Format("15:04")
+	if q.From < q.To {
+		return clock >= q.From && clock < q.To
+	}
+	return clock >= q.From || clock < q.To
+}
+
+// SkipReason reports why nothing should be posted at t, which should be in the
+// reference timezone: a skip day, a holiday when skip_holidays is set, or
+// quiet hours
+func (f *File) SkipReason(t time.Time) (string, bool) {
+	day := t.Format("2006-01-02")
+
+	for i := range f.SkipDays {
+		if f.SkipDays[i].covers(day) {
+			if f.SkipDays[i].Reason != "" {
+				return f.SkipDays[i].Reason, true
+			}
+			return "skip day " + day, true
+		}
+	}
+
+	if f.SkipHolidays {
+		holidays, _ := f.HolidayDates(t.Year(), t.Year())
+		for _, h := range holidays {
+			if h.Format("2006-01-02") == day {
+				return "holiday " + day, true
+			}
+		}
+	}
+
+	for i := range f.QuietHours {
+		if f.QuietHours[i].contains(t) {
+			return fmt.Sprintf("quiet hours %s-%s", f.QuietHours[i].From, f.QuietHours[i].To), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSkipReason(t *testing.T) {
+	f := &File{
+		Holidays:     []string{"2026-12-25"},
+		SkipHolidays: true,
+		SkipDays: []SkipDay{
+			{Date: "2026-09-11", Reason: "a day of remembrance"},
+			{Date: "2026-10-20", Until: "2026-10-22"},
+		},
+		QuietHours: []QuietHours{
+			{From: "22:00", To: "07:00"},
+			{From: "12:00", To: "13:00"},
+		},
+	}
+	at := func(m time.Month, d, hour, minute int) time.Time {
+		return time.Date(2026, m, d, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		t      time.Time
+		want   string
+		wantOK bool
+	}{
+		{"ordinary day", at(time.October, 14, 9, 0), "", false},
+		{"skip day with reason", at(time.September, 11, 9, 0), "a day of remembrance", true},
+		{"first day of range", at(time.October, 20, 9, 0), "skip day 2026-10-20", true},
+		{"last day of range", at(time.October, 22, 9, 0), "skip day 2026-10-22", true},
+		{"day after range", at(time.October, 23, 9, 0), "", false},
+		{"holiday", at(time.December, 25, 9, 0), "holiday 2026-12-25", true},
+		{"quiet hours before midnight", at(time.October, 14, 23, 0), "quiet hours 22:00-07:00", true},
+		{"quiet hours after midnight", at(time.October, 14, 6, 59), "quiet hours 22:00-07:00", true},
+		{"end of quiet hours", at(time.October, 14, 7, 0), "", false},
+		{"midday quiet hours", at(time.October, 14, 12, 30), "quiet hours 12:00-13:00", true},
+		{"end of midday quiet hours", at(time.October, 14, 13, 0), "", false},
+		{"skip day wins over quiet hours", at(time.September, 11, 23, 0), "a day of remembrance", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := f.SkipReason(tt.t)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("SkipReason(%v) = %q, %v, want %q, %v", tt.t, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSkipReasonHolidaysNotSkipped(t *testing.T) {
+	f := &File{Holidays: []string{"2026-12-25"}, FederalHolidays: true}
+	for _, day := range []time.Time{
+		time.Date(2026, time.December, 25, 9, 0, 0, 0, time.UTC),
+		time.Date(2026, time.November, 26, 9, 0, 0, 0, time.UTC),
+	} {
+		if reason, ok := f.SkipReason(day); ok {
+			t.Errorf("SkipReason(%v) = %q without skip_holidays", day, reason)
+		}
+	}
+}
+
+func TestSkipDayValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       SkipDay
+		wantErr bool
+	}{
+		{"single day", SkipDay{Date: "2026-09-11"}, false},
+		{"range", SkipDay{Date: "2026-10-20", Until: "2026-10-22"}, false},
+		{"one day range", SkipDay{Date: "2026-10-20", Until: "2026-10-20"}, false},
+		{"invalid date", SkipDay{Date: "09/11/2026"}, true},
+		{"invalid until", SkipDay{Date: "2026-10-20", Until: "tomorrow"}, true},
+		{"until before date", SkipDay{Date: "2026-10-20", Until: "2026-10-19"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.s.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuietHoursValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       QuietHours
+		wantErr bool
+	}{
+		{"daytime", QuietHours{From: "12:00", To: "13:00"}, false},
+		{"past midnight", QuietHours{From: "22:00", To: "07:00"}, false},
+		{"invalid from", QuietHours{From: "10pm", To: "07:00"}, true},
+		{"invalid to", QuietHours{From: "22:00", To: "7"}, true},
+		{"empty window", QuietHours{From: "08:00", To: "08:00"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.q.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
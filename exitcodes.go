@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+)
+
+// Exit codes of the bot, by the class of failure, so cron wrappers and
+// orchestrators can react to each differently. Skipped posts and a finished
+// countdown exit 0.
+const (
+	exitFailure    = 1
+	exitConfig     = 2
+	exitAuth       = 3
+	exitGeneration = 4
+	exitModeration = 5
+	exitPublish    = 6
+)
+
+// Classes of failure that failure marks errors with
+var (
+	errConfig     = errors.New("invalid configuration")
+	errAuth       = errors.New("authentication failed")
+	errGeneration = errors.New("generation failed")
+	errBlocked    = errors.New("blocked by the content policy")
+	errPublish    = errors.New("publishing failed")
+)
+
+// classifiedError represents an error marked with its class of failure
+type classifiedError struct {
+	class error
+	err   error
+}
+
+// failure marks err as a failure of class, such as errAuth, without changing
+// its message
+func failure(class, err error) error {
+	return &classifiedError{class: class, err: err}
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.err, e.class}
+}
+
+// exitCode returns the code the bot exits with after err. A failure of more
+// than one class exits with the most specific, so a post that couldn't be
+// published because signing in failed exits with exitAuth.
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, errSkipPost), errors.Is(err, errCountdownOver):
+		return 0
+	case errors.Is(err, errConfig):
+		return exitConfig
+	case errors.Is(err, errAuth):
+		return exitAuth
+	case errors.Is(err, errBlocked):
+		return exitModeration
+	case errors.Is(err, errGeneration):
+		return exitGeneration
+	case errors.Is(err, errPublish):
+		return exitPublish
+	}
+	return exitFailure
+}
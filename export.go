@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lukeocodes/go-trump/state"
+	"github.com/lukeocodes/go-trump/store"
+)
+
+// getPostsURL looks up posts on the public Bluesky AppView, which needs no
+// authentication
+const getPostsURL = "https://public.api.bsky.app/xrpc/app.bsky.feed.getPosts"
+
+// getPostsBatch is the most posts getPosts looks up at once
+const getPostsBatch = 25
+
+// ExportedPost represents a post in the exported history
+type ExportedPost struct {
+	Date          string            `json:"date"`
+	PostType      string            `json:"post_type"`
+	PostedAt      time.Time         `json:"posted_at"`
+	Text          string            `json:"text"`
+	PromptVariant string            `json:"prompt_variant,omitempty"`
+	Model         string            `json:"model,omitempty"`
+	Tokens        int               `json:"tokens,omitempty"`
+	Links         map[string]string `json:"links,omitempty"`
+
+	// Engagement is the Bluesky post's counts at export time, when it was
+	// published there
+	Engagement *Engagement `json:"engagement,omitempty"`
+}
+
+// Engagement represents how people have interacted with a Bluesky post
+type Engagement struct {
+	Likes   int `json:"likes"`
+	Reposts int `json:"reposts"`
+	Replies int `json:"replies"`
+	Quotes  int `json:"quotes"`
+}
+
+// runExport writes the full post history, with engagement metrics, to stdout
+// as "csv" or "json"
+func runExport(ctx context.Context, format string) error {
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("unknown export format %q, expected csv or json", format)
+	}
+
+	posts, err := exportedPosts(ctx)
+	if err != nil {
+		return err
+	}
+	if err := addEngagement(ctx, newBlueskyAPI(), posts); err != nil {
+		// The history is still worth having without the metrics
+		logger(logBluesky).WarnContext(ctx, "Failed to fetch engagement metrics", "err", err)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(posts)
+	}
+	return writeExportCSV(os.Stdout, posts)
+}
+
+// exportedPosts lists every post made, oldest first, from the store or, when
+// there's none, the state file's history
+func exportedPosts(ctx context.Context) ([]ExportedPost, error) {
+	s, err := openStore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	posts := []ExportedPost{}
+	if s == nil {
+		st, err := state.Load(stateFile())
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range st.History {
+			if post.Skipped == "" {
+				posts = append(posts, ExportedPost{Date: post.Date, PostType: post.PostType, PostedAt: post.PostedAt, Text: post.Text})
+			}
+		}
+		return posts, nil
+	}
+
+	runs, err := s.RecentRuns(ctx, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if run.Status != store.StatusPosted {
+			continue
+		}
+		post := ExportedPost{
+			Date:          run.Date,
+			PostType:      run.PostType,
+			PostedAt:      run.FinishedAt,
+			Text:          run.Text,
+			PromptVariant: run.PromptVariant,
+			Model:         run.Model,
+			Tokens:        run.PromptTokens + run.CompletionTokens,
+			Links:         map[string]string{},
+		}
+		for _, result := range run.Results {
+			if result.Published() && result.Link != "" {
+				post.Links[result.Destination] = result.Link
+			}
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
+// blueskyURI returns the AT URI a post was published at on Bluesky
+func (p *ExportedPost) blueskyURI() string {
+	for _, link := range p.Links {
+		if strings.HasPrefix(link, "at://") {
+			return link
+		}
+	}
+	return ""
+}
+
+// addEngagement looks up the engagement of every post published to Bluesky,
+// through bsky
+func addEngagement(ctx context.Context, bsky *blueskyAPI, posts []ExportedPost) error {
+	byURI := map[string][]int{}
+	var uris []string
+	for i := range posts {
+		if uri := posts[i].blueskyURI(); uri != "" {
+			if _, ok := byURI[uri]; !ok {
+				uris = append(uris, uri)
+			}
+			byURI[uri] = append(byURI[uri], i)
+		}
+	}
+
+	for start := 0; start < len(uris); start += getPostsBatch {
+		batch := uris[start:min(start+getPostsBatch, len(uris))]
+		engagement, err := bsky.fetchEngagement(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for uri, e := range engagement {
+			for _, i := range byURI[uri] {
+				posts[i].Engagement = e
+			}
+		}
+	}
+	return nil
+}
+
+// fetchEngagement looks up the engagement of up to getPostsBatch posts by URI
+func (b *blueskyAPI) fetchEngagement(ctx context.Context, uris []string) (map[string]*Engagement, error) {
+	ctx, cancel := blueskyRequest(ctx)
+	defer cancel()
+
+	query := url.Values{"uris": uris}
+	req, err := http.NewRequestWithContext(ctx, "GET", getPostsURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create getPosts request: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("getPosts request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, blueskyError("getPosts", resp)
+	}
+
+	var response struct {
+		Posts []struct {
+			URI         string `json:"uri"`
+			LikeCount   int    `json:"likeCount"`
+			RepostCount int    `json:"repostCount"`
+			ReplyCount  int    `json:"replyCount"`
+			QuoteCount  int    `json:"quoteCount"`
+		} `json:"posts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode getPosts response: %w", err)
+	}
+
+	engagement := map[string]*Engagement{}
+	for _, p := range response.Posts {
+		engagement[p.URI] = &Engagement{Likes: p.LikeCount, Reposts: p.RepostCount, Replies: p.ReplyCount, Quotes: p.QuoteCount}
+	}
+	return engagement, nil
+}
+
+// writeExportCSV writes the posts as CSV with a header row, one post per row
+func writeExportCSV(w io.Writer, posts []ExportedPost) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "post_type", "posted_at", "text", "prompt_variant", "model", "tokens", "bluesky_uri", "likes", "reposts", "replies", "quotes"})
+
+	for _, post := range posts {
+		row := []string{
+			post.Date,
+			post.PostType,
+			post.PostedAt.Format(time.RFC3339),
+			post.Text,
+			post.PromptVariant,
+			post.Model,
+			strconv.Itoa(post.Tokens),
+			post.blueskyURI(),
+			"", "", "", "",
+		}
+		if e := post.Engagement; e != nil {
+			row[8], row[9], row[10], row[11] = strconv.Itoa(e.Likes), strconv.Itoa(e.Reposts), strconv.Itoa(e.Replies), strconv.Itoa(e.Quotes)
+		}
+		out.Write(row)
+	}
+
+	out.Flush()
+	return out.Error()
+}
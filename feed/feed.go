@@ -0,0 +1,140 @@
+// Package feed renders posts as Atom and RSS feeds, so the countdown can be
+// followed without a social account
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// Feed represents a feed of posts, newest first
+type Feed struct {
+	Title   string
+	Link    string
+	ID      string
+	Updated time.Time
+	Entries []Entry
+}
+
+// Entry represents a single post in a feed
+type Entry struct {
+	ID        string
+	Title     string
+	Content   string
+	Published time.Time
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link,omitempty"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom renders the feed as an Atom 1.0 document
+func (f *Feed) Atom() ([]byte, error) {
+	doc := atomFeed{
+		Title:   f.Title,
+		ID:      f.ID,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: f.Title},
+	}
+	if f.Link != "" {
+		doc.Links = []atomLink{{Href: f.Link, Rel: "self"}}
+	}
+	for _, e := range f.Entries {
+		published := e.Published.UTC().Format(time.RFC3339)
+		doc.Entries = append(doc.Entries, atomEntry{
+			Title:     e.Title,
+			ID:        e.ID,
+			Published: published,
+			Updated:   published,
+			Content:   atomContent{Type: "text", Body: e.Content},
+		})
+	}
+
+	return marshal(doc)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders the feed as an RSS 2.0 document
+func (f *Feed) RSS() ([]byte, error) {
+	doc := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Title,
+			LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, e := range f.Entries {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       e.Title,
+			Description: e.Content,
+			GUID:        rssGUID{Value: e.ID},
+			PubDate:     e.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	return marshal(doc)
+}
+
+func marshal(doc interface{}) ([]byte, error) {
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal feed: %w", err)
+	}
+	return append([]byte(xml.Header), append(out, '\n')...), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lukeocodes/go-trump/feed"
+	"github.com/lukeocodes/go-trump/state"
+)
+
+// feedEntries is how many of the latest posts a feed includes
+const feedEntries = 50
+
+// buildFeed lists the latest posts in the history, newest first
+func buildFeed(st *state.State) *feed.Feed {
+	f := &feed.Feed{
+		Title: conf.Feed.Title,
+		Link:  conf.Feed.URL,
+		ID:    conf.Feed.URL,
+	}
+	if f.ID == "" {
+		f.ID = "tag:go-trump,2025:feed"
+	}
+
+	for i := len(st.History) - 1; i >= 0 && len(f.Entries) < feedEntries; i-- {
+		post := st.History[i]
+		if post.Skipped != "" {
+			continue
+		}
+		if f.Updated.IsZero() {
+			f.Updated = post.PostedAt
+		}
+		f.Entries = append(f.Entries, feed.Entry{
+			ID:        fmt.Sprintf("tag:go-trump,%s:%s/%d", post.Date, post.PostType, post.PostedAt.Unix()),
+			Title:     feedTitle(post.Text),
+			Content:   post.Text,
+			Published: post.PostedAt,
+		})
+	}
+
+	if f.Updated.IsZero() {
+		f.Updated = time.Now()
+	}
+
+	return f
+}
+
+// feedTitle shortens a post to its first line, up to 80 characters
+func feedTitle(text string) string {
+	title, _, _ := strings.Cut(text, "\n")
+	if utf8.RuneCountInString(title) > 80 {
+		title = string([]rune(title)[:79]) + "…"
+	}
+	return title
+}
+
+// renderFeed renders the feed in feed.format, "atom" (the default) or "rss",
+// returning it with its content type
+func renderFeed(st *state.State) ([]byte, string, error) {
+	f := buildFeed(st)
+	switch format := conf.Feed.Format; format {
+	case "atom":
+		out, err := f.Atom()
+		return out, "application/atom+xml", err
+	case "rss":
+		out, err := f.RSS()
+		return out, "application/rss+xml", err
+	default:
+		return nil, "", fmt.Errorf("unknown feed format %q", format)
+	}
+}
+
+// writeFeed regenerates the feed file at feed.file, when set. A feed that
+// can't be written is logged rather than failing the post.
+func writeFeed(st *state.State) {
+	path := conf.Feed.File
+	if path == "" {
+		return
+	}
+
+	out, _, err := renderFeed(st)
+	if err != nil {
+		logger(logSite).Error("Failed to render feed", "err", err)
+		return
+	}
+
+	if err := writeFileAtomic(path, out); err != nil {
+		logger(logSite).Error("Failed to write feed", "err", err)
+	}
+}
+
+// handleFeed serves the feed of the latest posts
+func handleFeed(w http.ResponseWriter, r *http.Request) {
+	st, err := state.Load(stateFile())
+	if err != nil {
+		http.Error(w, "failed to load posts", http.StatusInternalServerError)
+		return
+	}
+
+	out, contentType, err := renderFeed(st)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Write(out)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// generatorMock is the generator that renders mock_text rather than asking a
+// model, so the whole pipeline, publishers included, can run in integration
+// tests without an OpenAI key
+const generatorMock = "mock"
+
+// defaultMockText is what the mock generator writes when mock_text isn't set
+const defaultMockText = `{{template "count" .}} {{.Event.Hashtag}}`
+
+// requestPost has the configured generator write a post from the rendered
+// prompt, or the mock generator write one from the prompt's data
+func requestPost(ctx context.Context, prompt, hashtag string, data PromptData) (*Completion, error) {
+	if conf.Generator == generatorMock {
+		return mockCompletion(prompt, data)
+	}
+	return newOpenAIAPI().writePost(ctx, prompt, hashtag)
+}
+
+// mockCompletion renders mock_text against data as a completion of prompt,
+// using no tokens
+func mockCompletion(prompt string, data PromptData) (*Completion, error) {
+	start := time.Now()
+	text, err := renderPrompt(cmp.Or(conf.MockText, defaultMockText), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render mock_text: %w", err)
+	}
+	return &Completion{
+		Text:    strings.TrimSpace(text),
+		Model:   generatorMock,
+		Prompt:  prompt,
+		Latency: time.Since(start),
+	}, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/httpclient"
+	"github.com/lukeocodes/go-trump/state"
+)
+
+const (
+	blueskyHealthURL = "https://bsky.social/xrpc/_health"
+	openAIModelsURL  = "https://api.openai.com/v1/models"
+
+	// probeTTL is how long provider reachability results are reused, so
+	// frequent probes don't hammer the providers
+	probeTTL = 30 * time.Second
+)
+
+// health tracks the outcome of the most recent Bluesky authentication
+var health healthTracker
+
+// healthTracker represents what the bot has learnt about its own health
+type healthTracker struct {
+	mu       sync.Mutex
+	authAt   time.Time
+	authErr  error
+	probedAt time.Time
+	probes   map[string]string
+}
+
+// HealthReport represents the body of /healthz and /readyz
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Session    string            `json:"session"`
+	LastAuthAt *time.Time        `json:"last_auth_at,omitempty"`
+	LastPostAt *time.Time        `json:"last_post_at,omitempty"`
+	LastPost   string            `json:"last_post_type,omitempty"`
+	Providers  map[string]string `json:"providers,omitempty"`
+}
+
+// recordAuth remembers whether the latest Bluesky authentication succeeded
+func (h *healthTracker) recordAuth(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.authAt = time.Now().UTC()
+	h.authErr = err
+}
+
+// session describes the Bluesky session: "unknown" until the first post
+// attempt, then "valid" or the authentication error
+func (h *healthTracker) session() (string, *time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case h.authAt.IsZero():
+		return "unknown", nil
+	case h.authErr != nil:
+		at := h.authAt
+		return "invalid: " + h.authErr.Error(), &at
+	}
+	at := h.authAt
+	return "valid", &at
+}
+
+// providers checks Bluesky and OpenAI are reachable, reusing recent results
+func (h *healthTracker) providers(ctx context.Context) map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.probes != nil && time.Since(h.probedAt) < probeTTL {
+		return h.probes
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	h.probes = map[string]string{
+		"bluesky": probe(ctx, blueskyClient, blueskyHealthURL, ""),
+	}
+	if conf.Generator != generatorMock {
+		h.probes["openai"] = probe(ctx, openAIClient, openAIModelsURL, config.Getenv("OPENAI_API_KEY"))
+	}
+	h.probedAt = time.Now()
+	return h.probes
+}
+
+// probe reports "ok" if url answers client with a 2xx status, or what went
+// wrong
+func probe(ctx context.Context, client httpclient.Doer, url, bearer string) string {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err.Error()
+	}
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err.Error()
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Sprintf("status %d", resp.StatusCode)
+	}
+	return "ok"
+}
+
+// registerHealth adds /healthz and /readyz to mux. /healthz reports the bot's
+// state and is healthy while the process can serve it; /readyz also checks
+// the providers and fails if any is unreachable or the session is invalid.
+func registerHealth(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, healthReport(nil), true)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		providers := health.providers(r.Context())
+		report := healthReport(providers)
+
+		ready := report.Session == "valid" || report.Session == "unknown"
+		for _, status := range providers {
+			if status != "ok" {
+				ready = false
+			}
+		}
+		writeHealth(w, report, ready)
+	})
+}
+
+func healthReport(providers map[string]string) HealthReport {
+	report := HealthReport{Providers: providers}
+	report.Session, report.LastAuthAt = health.session()
+
+	if st, err := state.Load(stateFile()); err == nil {
+		if post, ok := lastPost(context.Background(), st); ok {
+			report.LastPostAt = &post.PostedAt
+			report.LastPost = post.PostType
+		}
+	}
+
+	return report
+}
+
+func writeHealth(w http.ResponseWriter, report HealthReport, ok bool) {
+	report.Status = "ok"
+	status := http.StatusOK
+	if !ok {
+		report.Status = "unavailable"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
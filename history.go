@@ -0,0 +1,365 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/state"
+	"github.com/lukeocodes/go-trump/store"
+)
+
+var (
+	runStoreMu sync.Mutex
+	runStore   store.Store
+)
+
+// openStore returns the configured run store, with its schema up to date and
+// encrypting prompts with STORE_ENCRYPTION_KEY if it's set. It is opened
+// once and reused. Pending migrations are applied unless store.auto_migrate
+// is false, in which case they are left to the migrate command and the store
+// can't be used until then.
+func openStore(ctx context.Context) (store.Store, error) {
+	runStoreMu.Lock()
+	defer runStoreMu.Unlock()
+
+	if runStore != nil {
+		return runStore, nil
+	}
+
+	s, err := newStore(ctx)
+	if err != nil || s == nil {
+		return nil, err
+	}
+
+	if m, ok := s.(store.Migrator); ok {
+		migrateCtx, cancel := httpRequest(ctx)
+		defer cancel()
+
+		if !conf.Store.AutoMigrate {
+			pending, err := m.PendingMigrations(migrateCtx)
+			if err != nil {
+				s.Close()
+				return nil, err
+			}
+			if len(pending) > 0 {
+				s.Close()
+				return nil, fmt.Errorf("store schema is %d migrations behind, run go-trump migrate", len(pending))
+			}
+		} else {
+			applied, err := m.Migrate(migrateCtx)
+			if err != nil {
+				s.Close()
+				return nil, err
+			}
+			for _, migration := range applied {
+				logger(logStore).InfoContext(ctx, "Applied store migration", "migration", migration)
+			}
+		}
+	}
+
+	// Prompts and raw responses are encrypted when there's a key
+	if encoded := config.Getenv("STORE_ENCRYPTION_KEY"); encoded != "" {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("invalid STORE_ENCRYPTION_KEY, expected base64: %w", err)
+		}
+		encrypted, err := store.NewEncrypted(s, key)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("invalid STORE_ENCRYPTION_KEY: %w", err)
+		}
+		s = encrypted
+	}
+
+	runStore = s
+	return runStore, nil
+}
+
+// newStore opens the run store named by store.backend: "sqlite" (the
+// default, at store.path or go-trump.db), "file" (a JSONL file at store.path
+// or runs.jsonl), "postgres" (at store.database_url), "redis" (at
+// redis_url), "dynamodb" (the table store.table), "s3" or "gcs" (an object
+// at store.path or runs.jsonl in store.bucket), or "none" to keep no
+// history, which is nil. The settings each needs are checked when the config
+// is loaded.
+func newStore(ctx context.Context) (store.Store, error) {
+	c := conf.Store
+	switch c.Backend {
+	case config.StoreNone:
+		return nil, nil
+	case config.StoreSQLite:
+		return store.NewSQLite(cmp.Or(c.Path, "go-trump.db"))
+	case config.StoreFile:
+		if !store.FileLocking {
+			logger(logStore).Warn("File locks aren't available on this platform, so only one process should use the file store at a time")
+		}
+		return store.NewFile(cmp.Or(c.Path, "runs.jsonl"))
+	case config.StorePostgres:
+		connectCtx, cancel := httpRequest(ctx)
+		defer cancel()
+		return store.NewPostgres(connectCtx, c.DatabaseURL)
+	case config.StoreRedis:
+		return store.NewRedis(conf.RedisURL, c.KeyPrefix)
+	case config.StoreDynamoDB:
+		return store.NewDynamoDB(ctx, c.Table)
+	case config.StoreGCS:
+		return store.NewGCS(httpClient, c.Bucket, cmp.Or(c.Path, "runs.jsonl"), config.Getenv("GCS_ACCESS_TOKEN")), nil
+	case config.StoreS3:
+		return store.NewS3(ctx, c.Bucket, cmp.Or(c.Path, "runs.jsonl"))
+	}
+	return nil, fmt.Errorf("unknown store backend %q", c.Backend)
+}
+
+// runMigrate applies the store's pending schema migrations, for migrate mode
+func runMigrate(ctx context.Context) error {
+	s, err := newStore(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	if s == nil {
+		fmt.Println("STORE is none, there is nothing to migrate")
+		return nil
+	}
+	defer s.Close()
+
+	m, ok := s.(store.Migrator)
+	if !ok {
+		fmt.Println("The store has no schema to migrate")
+		return nil
+	}
+
+	applied, err := m.Migrate(ctx)
+	for _, migration := range applied {
+		fmt.Printf("Applied migration %s\n", migration)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("The store schema is up to date")
+	}
+	return nil
+}
+
+// postedInStore reports whether the store has a posted run of postType on
+// date. A store that can't be read is logged and treated as having none, so
+// the state file remains the guard.
+func postedInStore(ctx context.Context, postType, date string) bool {
+	s, err := openStore(ctx)
+	if err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to open store", "err", err)
+		return false
+	}
+	if s == nil {
+		return false
+	}
+
+	readCtx, cancel := httpRequest(ctx)
+	defer cancel()
+	posted, err := s.PostedOn(readCtx, postType, date)
+	if err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to check the store for today's post", "post_type", postType, "err", err)
+		return false
+	}
+	return posted
+}
+
+// runList prints up to limit of the latest runs, newest first, with where
+// each was published or why it failed. Without a store, it lists the state
+// file's posts.
+func runList(ctx context.Context, limit int) error {
+	s, err := openStore(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if s == nil {
+		st, err := state.Load(stateFile())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "DATE\tTYPE\tSTATUS\tTEXT")
+		for i := len(st.History) - 1; i >= 0 && len(st.History)-i <= limit; i-- {
+			post := st.History[i]
+			status, text := store.StatusPosted, post.Text
+			if post.Skipped != "" {
+				status, text = store.StatusSkipped, post.Skipped
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", post.Date, post.PostType, status, listExcerpt(text))
+		}
+		return w.Flush()
+	}
+
+	runs, err := s.RecentRuns(ctx, limit)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "ID\tDATE\tTYPE\tSTATUS\tTEXT")
+	for _, run := range runs {
+		text := run.Text
+		if run.Status != store.StatusPosted {
+			text = run.Error
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", run.ID, run.Date, run.PostType, run.Status, listExcerpt(text))
+		for _, result := range run.Results {
+			outcome := result.Link
+			if !result.Published() {
+				outcome = "failed: " + result.Error
+			}
+			fmt.Fprintf(w, "\t\t\t%s\t%s\n", result.Destination, outcome)
+		}
+	}
+	return w.Flush()
+}
+
+// listExcerpt shortens text to one line that fits a terminal
+func listExcerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > 60 {
+		return string(runes[:59]) + "…"
+	}
+	return text
+}
+
+// recentPosts returns up to n of the latest posts from the store. The state
+// file's are used when there's no store, it can't be read or, as just after
+// upgrading, it has fewer posts than the state file.
+func recentPosts(ctx context.Context, st *state.State, n int) []state.Post {
+	fallback := st.RecentPosts(n)
+	s, err := openStore(ctx)
+	if err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to open store", "err", err)
+		return fallback
+	}
+	if s == nil {
+		return fallback
+	}
+
+	readCtx, cancel := httpRequest(ctx)
+	defer cancel()
+	runs, err := s.RecentPosts(readCtx, n)
+	if err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to read recent posts from the store", "err", err)
+		return fallback
+	}
+	if len(runs) < len(fallback) {
+		return fallback
+	}
+
+	posts := make([]state.Post, len(runs))
+	for i, run := range runs {
+		posts[i] = postFromRun(run)
+	}
+	return posts
+}
+
+// lastPost returns the latest post of any type, from the store or, when
+// there's no store or it has none, the state file
+func lastPost(ctx context.Context, st *state.State) (state.Post, bool) {
+	if s, err := openStore(ctx); err == nil && s != nil {
+		readCtx, cancel := httpRequest(ctx)
+		defer cancel()
+		run, err := s.LastRun(readCtx, "")
+		if err != nil {
+			logger(logStore).ErrorContext(ctx, "Failed to read the last post from the store", "err", err)
+		}
+		if run != nil {
+			return postFromRun(*run), true
+		}
+	}
+	return st.LastPost()
+}
+
+// postFromRun describes a posted run the way the state file records posts
+func postFromRun(run store.Run) state.Post {
+	return state.Post{PostType: run.PostType, Date: run.Date, PostedAt: run.FinishedAt, Text: run.Text}
+}
+
+// saveRun records how a run went. A run that can't be recorded is logged
+// rather than failing it, as the post may already be out.
+func saveRun(ctx context.Context, rec *store.Run, err error) {
+	rec.FinishedAt = time.Now().UTC()
+	switch {
+	case err == nil:
+		rec.Status = store.StatusPosted
+	case errors.Is(err, errSkipPost), errors.Is(err, errCountdownOver):
+		rec.Status = store.StatusSkipped
+		rec.Error = err.Error()
+	default:
+		rec.Status = store.StatusFailed
+		rec.Error = err.Error()
+	}
+
+	saveCtx, cancel := httpRequest(context.WithoutCancel(ctx))
+	defer cancel()
+
+	s, openErr := openStore(saveCtx)
+	if openErr != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to open store", "err", openErr)
+		return
+	}
+	if s == nil {
+		return
+	}
+	if err := s.SaveRun(saveCtx, rec); err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to record the run", "err", err)
+		return
+	}
+
+	pruneRuns(saveCtx, s)
+}
+
+// RetentionPolicy represents how much run history is kept
+type RetentionPolicy struct {
+	// Days is how many days of runs to keep, or 0 for all of them
+	Days int
+
+	// Runs is how many of the latest runs to keep, or 0 for all of them
+	Runs int
+}
+
+// retentionPolicy returns the configured retention policy, which defaults
+// to keeping everything
+func retentionPolicy() RetentionPolicy {
+	return RetentionPolicy{Days: conf.Store.RetentionDays, Runs: conf.Store.RetentionRuns}
+}
+
+// pruneRuns deletes the runs the retention policy no longer keeps, after each
+// run is saved. Pruning that fails is logged and tried again next time.
+func pruneRuns(ctx context.Context, s store.Store) {
+	policy := retentionPolicy()
+	if policy.Days == 0 && policy.Runs == 0 {
+		return
+	}
+
+	var cutoff time.Time
+	if policy.Days > 0 {
+		cutoff = time.Now().AddDate(0, 0, -policy.Days)
+	}
+	deleted, err := 0, errors.ErrUnsupported
+	if p, ok := s.(store.Pruner); ok {
+		deleted, err = p.Prune(ctx, cutoff, policy.Runs)
+	}
+	if errors.Is(err, errors.ErrUnsupported) {
+		logger(logStore).WarnContext(ctx, "Not pruning the store, it doesn't support retention", "backend", conf.Store.Backend)
+		return
+	}
+	if err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to prune the store", "err", err)
+		return
+	}
+	if deleted > 0 {
+		logger(logStore).InfoContext(ctx, "Pruned the store", "runs", deleted)
+	}
+}
@@ -0,0 +1,10 @@
+// Package httpclient holds what the packages making HTTP requests share.
+package httpclient
+
+import "net/http"
+
+// Doer sends HTTP requests, as an *http.Client does, so callers can be given
+// a client that's wrapped, such as by the audit log, or stubbed in tests
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
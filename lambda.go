@@ -0,0 +1,38 @@
+//go:build lambda
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/getsentry/sentry-go"
+)
+
+// LambdaEvent represents the input of a scheduled invocation. EventBridge
+// schedules can set it as a constant input to choose the post type.
+type LambdaEvent struct {
+	PostType string `json:"post_type"`
+}
+
+func init() {
+	startLambda = func() {
+		lambda.Start(handleLambda)
+	}
+}
+
+// handleLambda runs the same pipeline as a one-shot run, for an EventBridge
+// scheduled invocation
+func handleLambda(ctx context.Context, event LambdaEvent) (RunResult, error) {
+	result, err := triggerRun(ctx, event.PostType, false)
+	pushMetrics(ctx)
+
+	// The environment may be frozen between invocations, so spans are
+	// exported before returning
+	if err := forceFlushTracing(ctx); err != nil {
+		logger(logServer).ErrorContext(ctx, "Failed to export traces", "err", err)
+	}
+	sentry.Flush(2 * time.Second)
+	return result, err
+}
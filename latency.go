@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// runLatency prints the p50 and p95 latency of OpenAI and each destination
+// over the latest limit runs in the store, so creeping slowness shows up
+// before requests start timing out. The trend compares the median of the
+// newer half of the runs with that of the older half.
+func runLatency(ctx context.Context, limit int) error {
+	s, err := openStore(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	if s == nil {
+		return errors.New("latency is only recorded in a store, and STORE is none")
+	}
+
+	runs, err := s.RecentRuns(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	// Latencies are collected newest first, as the runs are
+	var names []string
+	latencies := map[string][]time.Duration{}
+	note := func(name string, latency time.Duration) {
+		if latency <= 0 {
+			return
+		}
+		if _, ok := latencies[name]; !ok {
+			names = append(names, name)
+		}
+		latencies[name] = append(latencies[name], latency)
+	}
+	for _, run := range runs {
+		note("openai", run.GenerationLatency)
+		for _, result := range run.Results {
+			note(result.Destination, result.Latency)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DEPENDENCY\tSAMPLES\tP50\tP95\tTREND\tDEADLINE")
+	for _, name := range names {
+		samples := latencies[name]
+		deadline := conf.Timeouts.Publish
+		if name == "openai" {
+			deadline = conf.Timeouts.OpenAI
+		}
+
+		p95 := percentile(samples, 0.95)
+		warning := ""
+		if p95 > deadline/2 {
+			warning = " (p95 over half the deadline)"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s%s\n", name, len(samples),
+			roundLatency(percentile(samples, 0.5)), roundLatency(p95), latencyTrend(samples), deadline, warning)
+	}
+	return w.Flush()
+}
+
+// percentile returns the pth percentile of samples, by the nearest rank
+func percentile(samples []time.Duration, p float64) time.Duration {
+	sorted := slices.Clone(samples)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// latencyTrend describes how much the median of the newer half of samples,
+// which are newest first, differs from that of the older half
+func latencyTrend(samples []time.Duration) string {
+	if len(samples) < 4 {
+		return "-"
+	}
+	half := len(samples) / 2
+	newer, older := percentile(samples[:half], 0.5), percentile(samples[half:], 0.5)
+	return fmt.Sprintf("%+.0f%%", (float64(newer)/float64(older)-1)*100)
+}
+
+// roundLatency rounds a latency to a readable precision
+func roundLatency(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(10 * time.Millisecond)
+}
@@ -0,0 +1,89 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoDBClient is the part of a DynamoDB client the locks use
+type dynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoDB represents locks held as items in a DynamoDB table, written with a
+// conditional put. The table's partition key is a string named "lock_key",
+// and "expires_at" can be enabled as its TTL attribute to clean up old locks.
+type DynamoDB struct {
+	client dynamoDBClient
+	table  string
+	prefix string
+	owner  string
+}
+
+// NewDynamoDB uses the DynamoDB table named table, with the default AWS
+// config, prefixing lock keys with prefix
+func NewDynamoDB(ctx context.Context, table, prefix string) (*DynamoDB, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &DynamoDB{client: dynamodb.NewFromConfig(cfg), table: table, prefix: prefix, owner: NewOwner()}, nil
+}
+
+func (d *DynamoDB) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item: map[string]types.AttributeValue{
+			"lock_key":   &types.AttributeValueMemberS{Value: d.prefix + key},
+			"owner":      &types.AttributeValueMemberS{Value: d.owner},
+			"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl).Unix(), 10)},
+		},
+		// Take the lock if nobody holds it, or the holder's lock has expired
+		// but not yet been cleaned up
+		ConditionExpression: aws.String("attribute_not_exists(lock_key) OR expires_at < :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire DynamoDB lock: %w", err)
+	}
+	return true, nil
+}
+
+func (d *DynamoDB) Release(ctx context.Context, key string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(d.table),
+		Key: map[string]types.AttributeValue{
+			"lock_key": &types.AttributeValueMemberS{Value: d.prefix + key},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: d.owner},
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionFailed) {
+		return fmt.Errorf("failed to release DynamoDB lock: %w", err)
+	}
+	return nil
+}
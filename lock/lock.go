@@ -0,0 +1,28 @@
+// Package lock provides distributed locks, so that when the bot runs on
+// several hosts, or a job is retried, only one instance makes each post
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Locker represents a distributed lock backend
+type Locker interface {
+	// Acquire takes the lock on key for ttl, reporting false if another
+	// instance already holds it
+	Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Release gives up a lock this instance holds, so it can be retried
+	Release(ctx context.Context, key string) error
+}
+
+// NewOwner returns a random ID identifying an instance as a lock's holder.
+// The stores' own locks use it too.
+func NewOwner() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
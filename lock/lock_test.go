@@ -0,0 +1,172 @@
+package lock
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeRedis stands in for a Redis server, running the release script as the
+// compare-and-delete it is
+type fakeRedis struct {
+	redis.Scripter
+
+	mu   sync.Mutex
+	keys map[string]fakeKey
+}
+
+type fakeKey struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (f *fakeRedis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) *redis.BoolCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cmd := redis.NewBoolCmd(ctx)
+	if held, ok := f.keys[key]; ok && time.Now().Before(held.expiresAt) {
+		cmd.SetVal(false)
+		return cmd
+	}
+	f.keys[key] = fakeKey{value: value.(string), expiresAt: time.Now().Add(expiration)}
+	cmd.SetVal(true)
+	return cmd
+}
+
+func (f *fakeRedis) EvalSha(ctx context.Context, sha1 string, keys []string, args ...any) *redis.Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cmd := redis.NewCmd(ctx)
+	if held, ok := f.keys[keys[0]]; ok && held.value == args[0] {
+		delete(f.keys, keys[0])
+		cmd.SetVal(int64(1))
+		return cmd
+	}
+	cmd.SetVal(int64(0))
+	return cmd
+}
+
+// fakeDynamoDB stands in for a DynamoDB table, checking the conditions the
+// locks write with
+type fakeDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func attr(item map[string]types.AttributeValue, name string) string {
+	switch v := item[name].(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	}
+	return ""
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := attr(params.Item, "lock_key")
+	if held, ok := f.items[key]; ok {
+		expiresAt, _ := strconv.ParseInt(attr(held, "expires_at"), 10, 64)
+		now, _ := strconv.ParseInt(attr(params.ExpressionAttributeValues, ":now"), 10, 64)
+		if expiresAt >= now {
+			return nil, &types.ConditionalCheckFailedException{}
+		}
+	}
+	f.items[key] = params.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := attr(params.Key, "lock_key")
+	held, ok := f.items[key]
+	if !ok || attr(held, "owner") != attr(params.ExpressionAttributeValues, ":owner") {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+	delete(f.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// lockers returns two instances of each backend sharing a fake server
+func lockers() map[string][2]Locker {
+	redisServer := &fakeRedis{keys: map[string]fakeKey{}}
+	table := &fakeDynamoDB{items: map[string]map[string]types.AttributeValue{}}
+	return map[string][2]Locker{
+		"redis": {
+			&Redis{client: redisServer, prefix: "go-trump:", owner: NewOwner()},
+			&Redis{client: redisServer, prefix: "go-trump:", owner: NewOwner()},
+		},
+		"dynamodb": {
+			&DynamoDB{client: table, table: "locks", prefix: "go-trump:", owner: NewOwner()},
+			&DynamoDB{client: table, table: "locks", prefix: "go-trump:", owner: NewOwner()},
+		},
+	}
+}
+
+func TestLocker(t *testing.T) {
+	const key = "2026-10-14/daily"
+	ctx := context.Background()
+
+	for name, pair := range lockers() {
+		t.Run(name, func(t *testing.T) {
+			a, b := pair[0], pair[1]
+
+			steps := []struct {
+				name string
+				do   func() (bool, error)
+				want bool
+			}{
+				{"a takes the lock", func() (bool, error) { return a.Acquire(ctx, key, time.Minute) }, true},
+				{"b can't take a's lock", func() (bool, error) { return b.Acquire(ctx, key, time.Minute) }, false},
+				{"b's release is refused", func() (bool, error) { return true, b.Release(ctx, key) }, true},
+				{"a still holds the lock", func() (bool, error) { return b.Acquire(ctx, key, time.Minute) }, false},
+				{"a releases the lock", func() (bool, error) { return true, a.Release(ctx, key) }, true},
+				{"b takes the released lock", func() (bool, error) { return b.Acquire(ctx, key, time.Minute) }, true},
+				{"a can't take b's lock", func() (bool, error) { return a.Acquire(ctx, key, time.Minute) }, false},
+				{"a lock on another key", func() (bool, error) { return a.Acquire(ctx, "2026-10-14/evening", time.Minute) }, true},
+			}
+			for _, step := range steps {
+				got, err := step.do()
+				if err != nil {
+					t.Fatalf("%s: error = %v", step.name, err)
+				}
+				if got != step.want {
+					t.Fatalf("%s: got %v, want %v", step.name, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDynamoDBExpiredLock(t *testing.T) {
+	ctx := context.Background()
+	pair := lockers()["dynamodb"]
+	a, b := pair[0], pair[1]
+
+	if ok, err := a.Acquire(ctx, "2026-10-14/daily", -time.Minute); err != nil || !ok {
+		t.Fatalf("a.Acquire() = %v, %v, want the lock", ok, err)
+	}
+	if ok, err := b.Acquire(ctx, "2026-10-14/daily", time.Minute); err != nil || !ok {
+		t.Errorf("b.Acquire() of an expired lock = %v, %v, want the lock", ok, err)
+	}
+}
+
+func TestNewOwner(t *testing.T) {
+	a, b := NewOwner(), NewOwner()
+	if len(a) != 32 || a == b {
+		t.Errorf("NewOwner() = %q and %q, want two different 32-character IDs", a, b)
+	}
+}
@@ -0,0 +1,55 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// releaseScript deletes a key only if this instance still holds it
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// redisClient is the part of a Redis client the locks use
+type redisClient interface {
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) *redis.BoolCmd
+	redis.Scripter
+}
+
+// Redis represents locks held as Redis keys set with NX and an expiry
+type Redis struct {
+	client redisClient
+	prefix string
+	owner  string
+}
+
+// NewRedis connects to the Redis server at url, e.g. redis://localhost:6379/0,
+// prefixing lock keys with prefix
+func NewRedis(url, prefix string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return &Redis{client: redis.NewClient(opts), prefix: prefix, owner: NewOwner()}, nil
+}
+
+func (r *Redis) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, r.prefix+key, r.owner, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire Redis lock: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *Redis) Release(ctx context.Context, key string) error {
+	if err := releaseScript.Run(ctx, r.client, []string{r.prefix + key}, r.owner).Err(); err != nil {
+		return fmt.Errorf("failed to release Redis lock: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lukeocodes/go-trump/lock"
+	"github.com/lukeocodes/go-trump/store"
+)
+
+// postLockTTL is how long a post's lock is held once taken. Locks are keyed
+// by date, so this only needs to outlast the day.
+const postLockTTL = 48 * time.Hour
+
+var (
+	lockerMu sync.Mutex
+	locker   lock.Locker
+)
+
+// openLocker returns the lock backend named by lock.backend ("redis",
+// "dynamodb" or "store", which locks in the run store), or nil when locking
+// is disabled. It is opened once and reused.
+func openLocker(ctx context.Context) (lock.Locker, error) {
+	lockerMu.Lock()
+	defer lockerMu.Unlock()
+
+	if locker != nil {
+		return locker, nil
+	}
+
+	prefix := conf.Lock.KeyPrefix
+
+	var err error
+	switch backend := conf.Lock.Backend; backend {
+	case "":
+		return nil, nil
+	case "redis":
+		locker, err = lock.NewRedis(conf.RedisURL, prefix)
+	case "dynamodb":
+		locker, err = lock.NewDynamoDB(ctx, conf.Lock.Table, prefix)
+	case "store":
+		var s store.Store
+		if s, err = openStore(ctx); err == nil && s == nil {
+			err = fmt.Errorf("the store lock needs a store backend other than none")
+		}
+		if err == nil {
+			locker = storeLocker{Store: s, prefix: prefix}
+		}
+	default:
+		return nil, fmt.Errorf("unknown lock backend %q", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return locker, nil
+}
+
+// acquirePostLock takes the lock for a post type on date's calendar day. It
+// returns errSkipPost if another instance holds it, and otherwise a function
+// that releases the lock, for when the post couldn't be made.
+func acquirePostLock(ctx context.Context, postType string, date time.Time) (func(), error) {
+	l, err := openLocker(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock backend: %w", err)
+	}
+	if l == nil {
+		return func() {}, nil
+	}
+
+	key := postType + ":" + date.Format("2006-01-02")
+	acquireCtx, cancel := httpRequest(ctx)
+	defer cancel()
+	ok, err := l.Acquire(acquireCtx, key, postLockTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("another instance is making today's %s post: %w", postType, errSkipPost)
+	}
+
+	return func() {
+		// Release even if the run was cancelled, so a retry can take the lock
+		releaseCtx, cancel := httpRequest(context.WithoutCancel(ctx))
+		defer cancel()
+		if err := l.Release(releaseCtx, key); err != nil {
+			logger(logStore).ErrorContext(ctx, "Failed to release lock", "key", key, "err", err)
+		}
+	}, nil
+}
+
+// storeLocker takes locks in the run store, so a shared store is all that
+// replicated instances need
+type storeLocker struct {
+	store.Store
+	prefix string
+}
+
+func (l storeLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return l.Lock(ctx, l.prefix+key, ttl)
+}
+
+func (l storeLocker) Release(ctx context.Context, key string) error {
+	return l.Unlock(ctx, l.prefix+key)
+}
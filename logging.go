@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Components that log under their own name, as the component attribute
+const (
+	logBluesky    = "bluesky"
+	logGenerator  = "generator"
+	logScheduler  = "scheduler"
+	logPublisher  = "publisher"
+	logModeration = "moderation"
+	logStore      = "store"
+	logServer     = "server"
+	logSecrets    = "secrets"
+	logSite       = "site"
+	logAudit      = "audit"
+	logHTTP       = "http"
+)
+
+// logger returns the logger of a component, such as bluesky or scheduler
+func logger(component string) *slog.Logger {
+	return slog.Default().With("component", component)
+}
+
+// logFile is the rotated log file that logs are written to when log_file is
+// set, and stopRotation stops rotating it every interval
+var (
+	logFile      *lumberjack.Logger
+	stopRotation = func() {}
+)
+
+// setupLogging makes slog log at the configured level and in the configured
+// format on stderr, or log_file, adding the attributes carried by the context
+// of each line. It's called again when the daemon reloads its settings.
+func setupLogging() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(conf.LogLevel)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", conf.LogLevel, err)
+	}
+	if conf.Debug {
+		level = slog.LevelDebug
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	previous := logFile
+	var out io.Writer = os.Stderr
+	logFile = nil
+	if conf.LogFile != "" {
+		logFile = &lumberjack.Logger{
+			Filename:   conf.LogFile,
+			MaxSize:    conf.LogRotation.MaxSize,
+			MaxAge:     conf.LogRotation.MaxAge,
+			MaxBackups: conf.LogRotation.MaxBackups,
+			Compress:   conf.LogRotation.Compress,
+			LocalTime:  true,
+		}
+		out = logFile
+	}
+
+	var handler slog.Handler
+	switch conf.LogFormat {
+	case "json":
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		handler = slog.NewTextHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(contextHandler{handler}))
+
+	// The file logged to before a reload is closed once nothing logs to it
+	stopRotation()
+	stopRotation = func() {}
+	if previous != nil {
+		previous.Close()
+	}
+	if logFile != nil && conf.LogRotation.Interval > 0 {
+		stopRotation = rotateEvery(logFile, conf.LogRotation.Interval)
+	}
+	return nil
+}
+
+// rotateEvery rotates f every interval, whatever its size, until the
+// returned function is called
+func rotateEvery(f *lumberjack.Logger, interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				if err := f.Rotate(); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to rotate the log file: %v\n", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// newRunID returns a random ID that the log lines of a run carry as run_id,
+// to pick them out of those of other runs
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type runIDKey struct{}
+
+// withRunID returns a context carrying runID, which the log lines, metrics
+// and audit entries of what's done with it are labelled with
+func withRunID(ctx context.Context, runID string) context.Context {
+	ctx = withLogAttrs(ctx, "run_id", runID)
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// runIDFrom returns the run ID ctx carries, or "" outside a run
+func runIDFrom(ctx context.Context) string {
+	runID, _ := ctx.Value(runIDKey{}).(string)
+	return runID
+}
+
+type logAttrsKey struct{}
+
+// withLogAttrs returns a context whose log lines carry args, as key-value
+// pairs, on top of those ctx already carries, such as the date of a run
+func withLogAttrs(ctx context.Context, args ...any) context.Context {
+	attrs, _ := ctx.Value(logAttrsKey{}).([]any)
+	return context.WithValue(ctx, logAttrsKey{}, append(attrs[:len(attrs):len(attrs)], args...))
+}
+
+// contextHandler adds the attributes of withLogAttrs to each line logged
+// with a context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(logAttrsKey{}).([]any); ok {
+		r.Add(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
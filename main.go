@@ -2,21 +2,31 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/getsentry/sentry-go"
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+	"github.com/lukeocodes/go-trump/httpclient"
 )
 
 // AuthResponse represents the authentication response from Bluesky
 type AuthResponse struct {
 	AccessJwt string `json:"accessJwt"`
 	Did       string `json:"did"`
+	Handle    string `json:"handle"`
 }
 
 // ErrorResponse represents the error response structure from Bluesky
@@ -25,61 +35,133 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
+// APIError represents a non-success HTTP response from Bluesky or OpenAI
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// blueskyError reads a Bluesky error response into an APIError
+func blueskyError(op string, resp *http.Response) error {
+	countAPIError("bluesky", resp.StatusCode)
+	var errResponse ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
+		return &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s error (%d): failed to decode error response: %v", op, resp.StatusCode, err)}
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s error (%d): %s - %s", op, resp.StatusCode, errResponse.Error, errResponse.Message)}
+}
+
 const (
-	authURL = "https://bsky.social/xrpc/com.atproto.server.createSession"
-	postURL = "https://bsky.social/xrpc/com.atproto.repo.createRecord"
+	authURL       = "https://bsky.social/xrpc/com.atproto.server.createSession"
+	postURL       = "https://bsky.social/xrpc/com.atproto.repo.createRecord"
+	uploadBlobURL = "https://bsky.social/xrpc/com.atproto.repo.uploadBlob"
+	deleteURL     = "https://bsky.social/xrpc/com.atproto.repo.deleteRecord"
+
+	resolveHandleURL = "https://bsky.social/xrpc/com.atproto.identity.resolveHandle"
+	getConvoURL      = "https://bsky.social/xrpc/chat.bsky.convo.getConvoForMembers"
+	sendMessageURL   = "https://bsky.social/xrpc/chat.bsky.convo.sendMessage"
+
+	// chatProxy routes chat requests through the PDS to Bluesky's chat service
+	chatProxy = "did:web:api.bsky.chat#bsky_chat"
 )
 
 var (
 	now = time.Now()
+
+	// conf holds the bot's settings, loaded at startup from confFile, if
+	// there is one, with its confProfile, and confFlags, the settings given
+	// with --set
+	conf        = config.Default()
+	confFile    string
+	confProfile string
+	confFlags   map[string]string
+
+	// startLambda is set in lambda builds to run as an AWS Lambda handler
+	startLambda func()
+
+	// blueskyClient sends requests to Bluesky, openAIClient to OpenAI and
+	// httpClient every other request the bot makes, such as to the other
+	// destinations, alerts, check-ins, the GCS store and the on this day
+	// feed. They share the default transport and its connections, and the
+	// audit log and debug logging wrap them. They're only the defaults that
+	// newBlueskyAPI and newOpenAIAPI hand out; tests give the APIs their own.
+	blueskyClient httpclient.Doer = http.DefaultClient
+	openAIClient  httpclient.Doer = http.DefaultClient
+	httpClient    httpclient.Doer = http.DefaultClient
 )
 
-func main() {
-	// // Only load .env file in development environment
-	if os.Getenv("ENVIRONMENT") != "production" {
-		err := godotenv.Load()
-		if err != nil {
-			log.Fatal("Error loading .env file")
-		}
-	}
+// blueskyAPI makes requests of Bluesky through client
+type blueskyAPI struct {
+	client httpclient.Doer
+}
 
-	exitDate := time.Date(2029, time.January, 20, 0, 0, 0, 0, time.UTC)
+// newBlueskyAPI returns the Bluesky API through the bot's blueskyClient
+func newBlueskyAPI() *blueskyAPI {
+	return &blueskyAPI{client: blueskyClient}
+}
 
-	if now.After(exitDate) {
-		return
-	}
+// openAIAPI makes requests of OpenAI through client
+type openAIAPI struct {
+	client httpclient.Doer
+}
 
-	// Get the post we will send
-	post := getPost()
-	fmt.Printf("Generated post: %s", post)
+// newOpenAIAPI returns the OpenAI API through the bot's openAIClient
+func newOpenAIAPI() *openAIAPI {
+	return &openAIAPI{client: openAIClient}
+}
 
-	// Load environment variables
-	username := os.Getenv("BLUESKY_USERNAME")
-	if username == "" {
-		log.Fatal("BLUESKY_USERNAME environment variable not set")
+// wrapTransport returns a copy of client with wrap around its transport, or
+// client as it is when it's a stub rather than an *http.Client
+func wrapTransport(client httpclient.Doer, wrap func(http.RoundTripper) http.RoundTripper) httpclient.Doer {
+	c, ok := client.(*http.Client)
+	if !ok {
+		return client
 	}
-
-	password := os.Getenv("BLUESKY_PASSWORD")
-	if password == "" {
-		log.Fatal("BLUESKY_PASSWORD environment variable not set")
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
 	}
+	wrapped := *c
+	wrapped.Transport = wrap(transport)
+	return &wrapped
+}
 
-	// Authenticate and obtain access token
-	authResponse, err := authenticate(username, password)
-	if err != nil {
-		log.Fatalf("Authentication failed: %v", err)
+func main() {
+	// SIGINT and SIGTERM cancel the context, aborting any in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := newRootCommand().ExecuteContext(ctx)
+
+	// Spans still buffered are exported even when the run was interrupted
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to export traces", "err", err)
 	}
+	cancel()
+	sentry.Flush(2 * time.Second)
 
-	// Post message using access token
-	err = postMessage(authResponse.AccessJwt, authResponse.Did, post)
+	if errors.Is(err, errCountdownOver) || errors.Is(err, errSkipPost) {
+		slog.Info("Nothing to post", "reason", err)
+		return
+	}
 	if err != nil {
-		log.Fatalf("Failed to post message: %v", err)
+		slog.Error("Failed", "err", err)
+		os.Exit(exitCode(err))
 	}
-
-	fmt.Println("Message posted successfully!")
 }
 
-func authenticate(identifier string, password string) (*AuthResponse, error) {
+func (b *blueskyAPI) authenticate(ctx context.Context, identifier string, password string) (_ *AuthResponse, err error) {
+	ctx, span := startSpan(ctx, "auth")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := blueskyRequest(ctx)
+	defer cancel()
+
 	authBody := map[string]string{
 		"identifier": identifier,
 		"password":   password,
@@ -89,15 +171,15 @@ func authenticate(identifier string, password string) (*AuthResponse, error) {
 		return nil, fmt.Errorf("failed to marshal auth request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", authURL, bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", authURL, bytes.NewBuffer(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create auth request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := b.client.Do(req)
 	if err != nil {
+		countAPIError("bluesky", 0)
 		return nil, fmt.Errorf("auth request failed: %w", err)
 	}
 	defer resp.Body.Close()
@@ -108,71 +190,282 @@ func authenticate(identifier string, password string) (*AuthResponse, error) {
 			return nil, fmt.Errorf("failed to decode auth response: %w", err)
 		}
 
-		fmt.Println("Authentication successful!")
+		logger(logBluesky).InfoContext(ctx, "Authenticated", "handle", authResponse.Handle)
 		return &authResponse, nil
 	}
 
-	var errResponse ErrorResponse
-	if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode error response: %w", err)
+	return nil, blueskyError("auth", resp)
+}
+
+// PostImage represents an uploaded image blob to embed in a post
+type PostImage struct {
+	Blob json.RawMessage
+	Alt  string
+}
+
+// uploadImage uploads an image file as a blob for embedding in a post
+func (b *blueskyAPI) uploadImage(ctx context.Context, accessToken, path, alt string) (_ *PostImage, err error) {
+	ctx, span := startSpan(ctx, "upload")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := blueskyRequest(ctx)
+	defer cancel()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadBlobURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", http.DetectContentType(data))
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		countAPIError("bluesky", 0)
+		return nil, fmt.Errorf("upload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var uploadResponse struct {
+			Blob json.RawMessage `json:"blob"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&uploadResponse); err != nil {
+			return nil, fmt.Errorf("failed to decode upload response: %w", err)
+		}
+		return &PostImage{Blob: uploadResponse.Blob, Alt: alt}, nil
 	}
-	return nil, fmt.Errorf("auth error (%d): %s - %s", resp.StatusCode, errResponse.Error, errResponse.Message)
+
+	return nil, blueskyError("upload", resp)
+}
+
+// PostRef represents a record created on Bluesky
+type PostRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
 }
 
-func postMessage(accessToken, did, message string) error {
+func (b *blueskyAPI) postMessage(ctx context.Context, accessToken, did, message string, image *PostImage) (_ *PostRef, err error) {
+	ctx, span := startSpan(ctx, "post")
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := blueskyRequest(ctx)
+	defer cancel()
+
+	record := map[string]interface{}{
+		"$type":     "app.bsky.feed.post",
+		"text":      message,
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+	}
+	if image != nil {
+		record["embed"] = map[string]interface{}{
+			"$type": "app.bsky.embed.images",
+			"images": []map[string]interface{}{
+				{
+					"alt":   image.Alt,
+					"image": image.Blob,
+				},
+			},
+		}
+	}
+
 	postBody := map[string]interface{}{
 		"repo":       did,
 		"collection": "app.bsky.feed.post",
-		"record": map[string]interface{}{
-			"$type":     "app.bsky.feed.post",
-			"text":      message,
-			"createdAt": time.Now().UTC().Format(time.RFC3339),
-		},
+		"record":     record,
 	}
 	bodyBytes, err := json.Marshal(postBody)
 	if err != nil {
-		return fmt.Errorf("failed to marshal post request body: %w", err)
+		return nil, fmt.Errorf("failed to marshal post request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", postURL, bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", postURL, bytes.NewBuffer(bodyBytes))
 	if err != nil {
-		return fmt.Errorf("failed to create post request: %w", err)
+		return nil, fmt.Errorf("failed to create post request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := b.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("post request failed: %w", err)
+		countAPIError("bluesky", 0)
+		return nil, fmt.Errorf("post request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var ref PostRef
+		if err := json.NewDecoder(resp.Body).Decode(&ref); err != nil {
+			return nil, fmt.Errorf("failed to decode post response: %w", err)
+		}
+
+		logger(logBluesky).InfoContext(ctx, "Posted", "post_uri", ref.URI)
+		return &ref, nil
+	}
+
+	return nil, blueskyError("post", resp)
+}
+
+// deleteRecord deletes a record from a Bluesky repo
+func (b *blueskyAPI) deleteRecord(ctx context.Context, accessToken, did, collection, rkey string) error {
+	ctx, cancel := blueskyRequest(ctx)
+	defer cancel()
+
+	bodyBytes, err := json.Marshal(map[string]string{
+		"repo":       did,
+		"collection": collection,
+		"rkey":       rkey,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal delete request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", deleteURL, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		countAPIError("bluesky", 0)
+		return fmt.Errorf("delete request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusOK {
-		fmt.Println("Post successful!")
 		return nil
 	}
+	return blueskyError("delete", resp)
+}
 
-	var errResponse ErrorResponse
-	if err := json.NewDecoder(resp.Body).Decode(&errResponse); err != nil {
-		return fmt.Errorf("failed to decode error response: %w", err)
+// sendDirectMessage sends text to recipient, a handle or DID, as a Bluesky
+// direct message. The app password must be allowed to access direct messages.
+func (b *blueskyAPI) sendDirectMessage(ctx context.Context, accessToken, recipient, text string) error {
+	did := recipient
+	if !strings.HasPrefix(recipient, "did:") {
+		var resolved struct {
+			Did string `json:"did"`
+		}
+		query := url.Values{"handle": {strings.TrimPrefix(recipient, "@")}}
+		if err := b.xrpc(ctx, "resolve handle", "GET", resolveHandleURL+"?"+query.Encode(), accessToken, nil, &resolved); err != nil {
+			return err
+		}
+		did = resolved.Did
+	}
+
+	var convo struct {
+		Convo struct {
+			ID string `json:"id"`
+		} `json:"convo"`
+	}
+	query := url.Values{"members": {did}}
+	if err := b.xrpc(ctx, "get conversation", "GET", getConvoURL+"?"+query.Encode(), accessToken, nil, &convo); err != nil {
+		return err
+	}
+
+	message := map[string]any{
+		"convoId": convo.Convo.ID,
+		"message": map[string]string{"text": text},
+	}
+	return b.xrpc(ctx, "send message", "POST", sendMessageURL, accessToken, message, nil)
+}
+
+// xrpc makes an XRPC request of Bluesky, sending body and decoding the
+// response into out when they aren't nil. Chat requests are proxied by the
+// PDS to the chat service.
+func (b *blueskyAPI) xrpc(ctx context.Context, op, method, endpoint, accessToken string, body, out any) error {
+	ctx, cancel := blueskyRequest(ctx)
+	defer cancel()
+
+	var reqBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s request body: %w", op, err)
+		}
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", op, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if strings.Contains(endpoint, "/xrpc/chat.bsky.") {
+		req.Header.Set("Atproto-Proxy", chatProxy)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		countAPIError("bluesky", 0)
+		return fmt.Errorf("%s request failed: %w", op, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return blueskyError(op, resp)
+	}
+	if out == nil {
+		return nil
 	}
-	return fmt.Errorf("post error (%d): %s - %s", resp.StatusCode, errResponse.Error, errResponse.Message)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", op, err)
+	}
+	return nil
+}
+
+// Completion represents a reply from the OpenAI chat completions API.
+// Variant names the prompt that produced it, when it's a post. System and
+// Prompt are the messages exactly as sent, and Raw the response body as
+// received, for auditing.
+type Completion struct {
+	Text             string
+	Model            string
+	Variant          string
+	PromptTokens     int
+	CompletionTokens int
+
+	System string
+	Prompt string
+	Raw    string
+
+	// Latency is how long the request took
+	Latency time.Duration
+}
+
+// writePost asks OpenAI for a post from the rendered prompt
+func (o *openAIAPI) writePost(ctx context.Context, prompt, hashtag string) (*Completion, error) {
+	system := "You're a bot on Bluesky social (handle: daysoftrump.bsky.social). You'll post a message every day. Your messages should be no more than 300 characters. Only respond as an agent with the post to share online. The format of that post should be exactly: 'X days until Y event. Rest of the message goes here " + hashtag + "'"
+	return o.chatCompletion(ctx, "gpt-4o-mini", system, prompt)
 }
 
-func makeOpenAIRequest(prompt string) (string, error) {
+// chatCompletion sends a system and user message to the OpenAI chat
+// completions API, returning the reply
+func (o *openAIAPI) chatCompletion(ctx context.Context, model, system, prompt string) (*Completion, error) {
+	ctx, cancel := openAIRequest(ctx)
+	defer cancel()
+
 	url := "https://api.openai.com/v1/chat/completions"
-	apiKey := os.Getenv("OPENAI_API_KEY")
+	apiKey := config.Getenv("OPENAI_API_KEY")
 	if apiKey == "" {
-		return "", fmt.Errorf("OPENAI_API_KEY environment variable not set")
+		return nil, fmt.Errorf("OPENAI_API_KEY environment variable not set")
 	}
 
 	requestBody := map[string]interface{}{
-		"model": "gpt-4o-mini",
+		"model": model,
 		"messages": []map[string]string{
 			{
 				"role":    "system",
-				"content": "You're a bot on Bluesky social (handle: daysoftrump.bsky.social). You'll post a message every day. Your messages should be no more than 300 characters. Only respond as an agent with the post to share online. The format of that post should be exactly: 'X days until Y event. Rest of the message goes here #TheFinalTrumpDown'",
+				"content": system,
 			},
 			{
 				"role":    "user",
@@ -183,66 +476,176 @@ func makeOpenAIRequest(prompt string) (string, error) {
 
 	bodyBytes, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %w", err)
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(bodyBytes))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	start := time.Now()
+	resp, err := o.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		countAPIError("openai", 0)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		countAPIError("openai", resp.StatusCode)
 		bodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return "", fmt.Errorf("failed to read error response body: %w", err)
+			return nil, fmt.Errorf("failed to read error response body: %w", err)
 		}
-		return "", fmt.Errorf("received non-200 response status: %d - %s", resp.StatusCode, string(bodyBytes))
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("received non-200 response status: %d - %s", resp.StatusCode, string(bodyBytes))}
 	}
 
 	var response struct {
+		Model   string `json:"model"`
 		Choices []struct {
 			Message struct {
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if len(response.Choices) == 0 {
-		return "", fmt.Errorf("no response choices returned")
+		return nil, fmt.Errorf("no response choices returned")
+	}
+
+	completion := &Completion{
+		Text:             response.Choices[0].Message.Content,
+		Model:            response.Model,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		System:           system,
+		Prompt:           prompt,
+		Raw:              string(raw),
+		Latency:          time.Since(start),
 	}
+	countTokens(completion)
+	return completion, nil
+}
 
-	return response.Choices[0].Message.Content, nil
+// configFile returns the config file to load: path, CONFIG_FILE, or the
+// first of go-trump.yaml and go-trump.toml that exists, or "" for none
+func configFile(path string) string {
+	if path != "" {
+		return path
+	}
+	if path := config.Getenv("CONFIG_FILE"); path != "" {
+		return path
+	}
+	for _, path := range []string{"go-trump.yaml", "go-trump.toml"} {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
 }
 
-func getPost() string {
-	inaugurationDate := time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC)
-	dateStr := now.Format("January 2, 2006")
+// loadEvents reads the configured events file (default events.json), falling
+// back to the built-in events when no file exists
+func loadEvents() (*events.File, error) {
+	path := conf.EventsFile
+	if path == "" {
+		path = "events.json"
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return events.Default(), nil
+		}
+	}
 
-	var prompt string
-	if now.Before(inaugurationDate) {
-		prompt = fmt.Sprintf(`Today is %s. Write a short, encouraging post about how many days are left until Trump's inauguration. Include the exact number of days until January 20th, 2025. Trump is not a good guy. Say something randomly positive to get people through this.`, dateStr)
-	} else {
-		prompt = fmt.Sprintf(`Today is %s. Write a short, encouraging post about how many days are left of Trump's 2nd term in office. Include the exact number of days until January 20th, 2029. Trump is not a good guy. Say something randomly positive to get people through this.`, dateStr)
+	cfg, err := events.Load(path)
+	if err != nil {
+		return nil, failure(errConfig, err)
 	}
+	if err := validateDestinations(cfg); err != nil {
+		return nil, failure(errConfig, err)
+	}
+	return cfg, nil
+}
 
-	response, err := makeOpenAIRequest(prompt)
+// getPost generates a post of the schedule's type counting down to the nearest
+// upcoming event, while mentioning any other configured countdowns. On
+// milestone days the daily post uses the milestone's prompt instead, and the
+// milestone is returned. Special days take precedence over milestones.
+func getPost(ctx context.Context, cfg *events.File, upcoming []events.Event, schedule *events.Schedule) (*Completion, *events.Milestone, error) {
+	special, err := specialDay(cfg, schedule)
 	if err != nil {
-		log.Printf("Error getting AI response: %v", err)
+		return nil, nil, err
 	}
 
-	return response
+	primary := upcoming[0]
+	data := newPromptData(ctx, cfg, upcoming)
+
+	tmpl := schedulePrompt(cfg, schedule)
+	variant := "schedule:" + schedule.Name
+
+	var milestone *events.Milestone
+	if special == nil && events.HasMilestones(schedule.Name) && primary.CountsDown() {
+		milestone, _ = events.DetectMilestone(cfg.Milestones, data.DaysLeft, totalDays(&primary))
+	}
+	if special != nil {
+		data.Special = special.Name
+		logger(logGenerator).InfoContext(ctx, "Special day", "special_day", special.Name)
+		tmpl = special.Prompt
+		variant = "special:" + special.Name
+	} else if milestone != nil {
+		data.Milestone = milestone.Label(data.milestoneNumber)
+		logger(logGenerator).InfoContext(ctx, "Milestone reached", "milestone", data.Milestone)
+		tmpl = milestone.Prompt
+		if tmpl == "" {
+			tmpl = defaultMilestonePrompt
+		}
+		variant = "milestone:" + data.Milestone
+	}
+
+	prompt, err := renderPrompt(tmpl, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := requestPost(ctx, prompt, primary.Hashtag, data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get AI response: %w", err)
+	}
+
+	response.Variant = variant
+	return response, milestone, nil
+}
+
+// specialDay returns today's special day for the schedule's post type, if
+// any. It replaces the daily post in instead mode, and is the only thing
+// special posts make, so they are skipped when there is none.
+func specialDay(cfg *events.File, schedule *events.Schedule) (*events.SpecialDay, error) {
+	if schedule.Name == events.PostTypeSpecial {
+		special, ok := cfg.SpecialDayOn(now, events.SpecialExtra)
+		if !ok {
+			return nil, fmt.Errorf("no special day today: %w", errSkipPost)
+		}
+		return special, nil
+	}
+
+	if events.HasMilestones(schedule.Name) {
+		if special, ok := cfg.SpecialDayOn(now, events.SpecialInstead); ok {
+			return special, nil
+		}
+	}
+	return nil, nil
 }
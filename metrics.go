@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// metricsRegistry holds the bot's metrics, served on /metrics by the daemon
+// and the trigger server, and pushed to a Pushgateway after one-shot runs.
+// Dry runs and previews aren't counted in them.
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	postsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "go_trump",
+		Name:      "posts_total",
+		Help:      "Posts published or failed, by destination and status.",
+	}, []string{"destination", "status"})
+
+	generationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "go_trump",
+		Name:      "generation_duration_seconds",
+		Help:      "Time taken to generate a post, by post type.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"post_type"})
+
+	publishDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "go_trump",
+		Name:      "publish_duration_seconds",
+		Help:      "Time taken to publish a post, by destination.",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60},
+	}, []string{"destination"})
+
+	llmTokensTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "go_trump",
+		Name:      "llm_tokens_total",
+		Help:      "Tokens used by LLM requests, by model and kind, prompt or completion.",
+	}, []string{"model", "kind"})
+
+	apiErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "go_trump",
+		Name:      "api_errors_total",
+		Help:      "Failed API requests, by API and HTTP status, or error when there was no response.",
+	}, []string{"api", "status"})
+
+	lastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "go_trump",
+		Name:      "last_run_timestamp_seconds",
+		Help:      "When the last run of each post type started, labelled with its run ID.",
+	}, []string{"post_type", "run_id"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(postsTotal, generationDuration, publishDuration, llmTokensTotal, apiErrorsTotal, lastRun)
+}
+
+// markRun records the start of the run of postType with runID, replacing
+// the post type's previous run so only the latest is labelled
+func markRun(runID, postType string) {
+	lastRun.DeletePartialMatch(prometheus.Labels{"post_type": postType})
+	lastRun.WithLabelValues(postType, runID).SetToCurrentTime()
+}
+
+// exemplar returns the run ID ctx carries as the labels of an exemplar, or
+// nil outside a run. Exemplars label an observation without making a series
+// for every run.
+func exemplar(ctx context.Context) prometheus.Labels {
+	runID := runIDFrom(ctx)
+	if runID == "" {
+		return nil
+	}
+	return prometheus.Labels{"run_id": runID}
+}
+
+// observe observes v in h, with the run ID of ctx as its exemplar
+func observe(ctx context.Context, h prometheus.Observer, v float64) {
+	if conf.DryRun {
+		return
+	}
+	if labels := exemplar(ctx); labels != nil {
+		h.(prometheus.ExemplarObserver).ObserveWithExemplar(v, labels)
+		return
+	}
+	h.Observe(v)
+}
+
+// countPost counts a post published or failed at destination, with the run
+// ID of ctx as its exemplar
+func countPost(ctx context.Context, destination, status string) {
+	if conf.DryRun {
+		return
+	}
+	counter := postsTotal.WithLabelValues(destination, status)
+	if labels := exemplar(ctx); labels != nil {
+		counter.(prometheus.ExemplarAdder).AddWithExemplar(1, labels)
+		return
+	}
+	counter.Inc()
+}
+
+// countAPIError counts a failed request to api, by its HTTP status, or 0 when
+// there was no response
+func countAPIError(api string, status int) {
+	if conf.DryRun {
+		return
+	}
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	apiErrorsTotal.WithLabelValues(api, label).Inc()
+}
+
+// countTokens counts the tokens a completion used
+func countTokens(c *Completion) {
+	if conf.DryRun {
+		return
+	}
+	llmTokensTotal.WithLabelValues(c.Model, "prompt").Add(float64(c.PromptTokens))
+	llmTokensTotal.WithLabelValues(c.Model, "completion").Add(float64(c.CompletionTokens))
+}
+
+// registerMetrics adds /metrics to mux. Scrapers that negotiate the
+// OpenMetrics format get the exemplars carrying run IDs.
+func registerMetrics(mux *http.ServeMux) {
+	mux.Handle("GET /metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+}
+
+// pushMetrics pushes the metrics to metrics.pushgateway_url, when set, at the
+// end of a one-shot run, which exits before it could be scraped. A failed push
+// is logged rather than failing the run. Dry runs aren't pushed.
+func pushMetrics(ctx context.Context) {
+	url := conf.Metrics.PushgatewayURL
+	if url == "" || conf.DryRun {
+		return
+	}
+
+	ctx, cancel := httpRequest(ctx)
+	defer cancel()
+	pusher := push.New(url, conf.Metrics.Job).Gatherer(metricsRegistry)
+	if err := pusher.PushContext(ctx); err != nil {
+		logger(logServer).ErrorContext(ctx, "Failed to push metrics", "err", err)
+	}
+}
+
+// timePublish observes how long publishing to destination took
+func timePublish(ctx context.Context, destination string, latency time.Duration) {
+	observe(ctx, publishDuration.WithLabelValues(destination), latency.Seconds())
+}
+
+// timeGeneration observes how long generating a post of postType took since
+// start
+func timeGeneration(ctx context.Context, postType string, start time.Time) {
+	observe(ctx, generationDuration.WithLabelValues(postType), time.Since(start).Seconds())
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// destinationMicropub is the destination type of a Micropub-capable blog
+const destinationMicropub = "micropub"
+
+const defaultMicropubEndpoint = "https://micro.blog/micropub"
+
+// micropubConfig represents a micropub destination's own settings
+type micropubConfig struct {
+	// Endpoint is the Micropub endpoint (default Micro.blog's), and
+	// AccessTokenEnv names the environment variable holding its token
+	// (default MICROPUB_TOKEN)
+	Endpoint       string `json:"endpoint"`
+	AccessTokenEnv string `json:"access_token_env"`
+}
+
+// micropubSettings decodes a micropub destination's own settings
+func micropubSettings(destination *events.Destination) (micropubConfig, error) {
+	var c micropubConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = defaultMicropubEndpoint
+	}
+	if c.AccessTokenEnv == "" {
+		c.AccessTokenEnv = "MICROPUB_TOKEN"
+	}
+	return c, nil
+}
+
+// micropubPublisher syndicates posts to a Micropub-capable blog, such as
+// Micro.blog
+type micropubPublisher struct {
+	adapter
+
+	name           string
+	endpoint       string
+	accessTokenEnv string
+}
+
+func init() {
+	registerPublisher(destinationMicropub, validateSettings(micropubSettings), factoryOf(newMicropubPublisher))
+}
+
+func newMicropubPublisher(destination *events.Destination) (*micropubPublisher, error) {
+	settings, err := micropubSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &micropubPublisher{
+		adapter:        newAdapter(destination, "a blog", 0, events.HashtagsKeep),
+		name:           destination.Name,
+		endpoint:       settings.Endpoint,
+		accessTokenEnv: settings.AccessTokenEnv,
+	}, nil
+}
+
+func (p *micropubPublisher) Name() string {
+	return p.name
+}
+
+// Publish creates an h-entry note, returning its URL from the Location header
+func (p *micropubPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	token := config.Getenv(p.accessTokenEnv)
+	if token == "" {
+		return Published{}, fmt.Errorf("%s environment variable not set", p.accessTokenEnv)
+	}
+
+	form := url.Values{
+		"h":       {"entry"},
+		"content": {post.Text},
+	}
+	if post.ImageURL != "" {
+		form.Set("photo", post.ImageURL)
+		if post.ImageAlt != "" {
+			form.Set("mp-photo-alt", post.ImageAlt)
+		}
+	}
+
+	// Publishing isn't cancelled by a shutdown signal once it starts
+	ctx, cancel := sendRequest(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to create Micropub request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Published{}, fmt.Errorf("Micropub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return Published{}, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("micropub error (%d): %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))}
+	}
+
+	return Published{Link: resp.Header.Get("Location")}, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/lukeocodes/go-trump/config"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const moderationURL = "https://api.openai.com/v1/moderations"
+
+// ModerationResult represents the outcome of the content-policy check
+type ModerationResult struct {
+	Flagged bool
+	Reasons []string
+}
+
+// policyRule is a single rules-based content-policy check
+type policyRule struct {
+	reason  string
+	pattern *regexp.Regexp
+}
+
+// policyRules catch threats, calls to violence and doxxing-style content that
+// could get the account suspended, regardless of what the LLM moderation says
+var policyRules = []policyRule{
+	{"threat or call to violence", regexp.MustCompile(`(?i)\b(kill|shoot|stab|hang|lynch|execute|assassinat\w*|murder|bomb)\s+(him|her|them|trump|the president)\b`)},
+	{"threat or call to violence", regexp.MustCompile(`(?i)\b(death to|take up arms|storm the|burn (it|them) down)\b`)},
+	// Phone numbers need the separators of one, so a tally like "the 312-226
+	// 2024 result" isn't taken for one
+	{"doxxing: phone number", regexp.MustCompile(`\b\d{3}-\d{3}-\d{4}\b|\b\d{3}\.\d{3}\.\d{4}\b|\(\d{3}\)\s?\d{3}[\s.-]\d{4}\b|\+1[\s.-]?\d{3}[\s.-]\d{3}[\s.-]\d{4}\b`)},
+	{"doxxing: email address", regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`)},
+	// Street names are capitalized, so a count like "60 days to St. Patrick's
+	// Day" isn't taken for an address
+	{"doxxing: street address", regexp.MustCompile(`\b\d{1,5}\s+[A-Z0-9]\w*(\s[A-Z0-9]\w*)?\s+(?i:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr)\b`)},
+	{"doxxing: social security number", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+}
+
+// moderatePost runs the configured content-policy checks against a post.
+// moderation_mode may be "rules", "llm" or "both" (the default). The mock
+// generator needs no OpenAI key, so with it only the rules are checked.
+func moderatePost(ctx context.Context, post string) (_ *ModerationResult, err error) {
+	ctx, span := startSpan(ctx, "moderate", attribute.String("mode", conf.ModerationMode))
+	defer func() { endSpan(span, err) }()
+
+	mode := conf.ModerationMode
+
+	result := &ModerationResult{}
+
+	switch mode {
+	case "rules", "llm", "both":
+	default:
+		return nil, fmt.Errorf("unknown moderation mode %q", mode)
+	}
+
+	if mode == "rules" || mode == "both" {
+		checkPolicyRules(post, result)
+	}
+
+	if (mode == "llm" || mode == "both") && conf.Generator != generatorMock {
+		if err := newOpenAIAPI().moderate(ctx, post, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func checkPolicyRules(post string, result *ModerationResult) {
+	for _, rule := range policyRules {
+		if rule.pattern.MatchString(post) {
+			result.Flagged = true
+			result.Reasons = append(result.Reasons, rule.reason)
+		}
+	}
+}
+
+func (o *openAIAPI) moderate(ctx context.Context, post string, result *ModerationResult) error {
+	ctx, cancel := openAIRequest(ctx)
+	defer cancel()
+
+	apiKey := config.Getenv("OPENAI_API_KEY")
+	if apiKey == "" {
+		return fmt.Errorf("OPENAI_API_KEY environment variable not set")
+	}
+
+	requestBody := map[string]interface{}{
+		"model": "omni-moderation-latest",
+		"input": post,
+	}
+
+	bodyBytes, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("failed to marshal moderation request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", moderationURL, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create moderation request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read moderation error response body: %w", err)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("received non-200 moderation response status: %d - %s", resp.StatusCode, string(bodyBytes))}
+	}
+
+	var response struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+
+	for _, r := range response.Results {
+		if !r.Flagged {
+			continue
+		}
+		result.Flagged = true
+		for category, flagged := range r.Categories {
+			if flagged {
+				result.Reasons = append(result.Reasons, "llm: "+category)
+			}
+		}
+	}
+
+	return nil
+}
+
+// blockedReasons joins moderation reasons for logging and alerts
+func blockedReasons(result *ModerationResult) string {
+	return strings.Join(result.Reasons, ", ")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lukeocodes/go-trump/config"
+)
+
+func TestCheckPolicyRules(t *testing.T) {
+	tests := []struct {
+		name string
+		post string
+		want []string
+	}{
+		{"threat", "Someone should shoot him before 2029.", []string{"threat or call to violence"}},
+		{"call to violence", "826 days left. Time to take up arms!", []string{"threat or call to violence"}},
+		{"phone number with dashes", "Call 202-456-1111 and tell them.", []string{"doxxing: phone number"}},
+		{"phone number with an area code", "Call (202) 456-1111 and tell them.", []string{"doxxing: phone number"}},
+		{"phone number with dots", "Call 202.456.1111 and tell them.", []string{"doxxing: phone number"}},
+		{"phone number with a country code", "Call +1 202 456 1111 and tell them.", []string{"doxxing: phone number"}},
+		{"email address", "Write to someone@example.com about it.", []string{"doxxing: email address"}},
+		{"street address", "He lives at 1600 Pennsylvania Avenue.", []string{"doxxing: street address"}},
+		{"abbreviated street address", "Meet at 123 Main St tonight.", []string{"doxxing: street address"}},
+		{"social security number", "His number is 078-05-1120.", []string{"doxxing: social security number"}},
+
+		{"daily countdown", "826 days until the end of the term. #DaysOfTrump", nil},
+		{"numbers in a row", "Day 634 of 1461. 827 to go, 2029 can't come soon enough. #DaysOfTrump", nil},
+		{"election tally", "Remember the 312-226 2024 result? 826 days to go.", nil},
+		{"ranges", "2025-2029: 826 days, 19,824 hours, 1,189,440 minutes left.", nil},
+		{"date", "826 days until 01-20-2029. #DaysOfTrump", nil},
+		{"saint's day", "60 days to St. Patrick's Day, and 826 until the end of the term.", nil},
+		{"doctor", "5 years since Dr. Fauci's briefings, 826 days to go.", nil},
+		{"long road", "826 days down the road, 731 more on the road ahead.", nil},
+		{"milestone", "100 days left! 1,361 down, 100 to go. #DaysOfTrump", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result ModerationResult
+			checkPolicyRules(tt.post, &result)
+			if result.Flagged != (len(tt.want) > 0) || !slices.Equal(result.Reasons, tt.want) {
+				t.Errorf("checkPolicyRules(%q) = %v %v, want %v", tt.post, result.Flagged, result.Reasons, tt.want)
+			}
+		})
+	}
+}
+
+// stubDoer answers every request with body
+type stubDoer struct {
+	body     string
+	requests int
+}
+
+func (d *stubDoer) Do(req *http.Request) (*http.Response, error) {
+	d.requests++
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(d.body)), Header: http.Header{}}, nil
+}
+
+func TestModeratePost(t *testing.T) {
+	saved, savedClient := conf, openAIClient
+	t.Cleanup(func() { conf, openAIClient = saved, savedClient })
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+
+	const flagged = `{"results": [{"flagged": true, "categories": {"harassment": true, "violence": false}}]}`
+	tests := []struct {
+		name        string
+		mode        string
+		generator   string
+		post        string
+		response    string
+		want        []string
+		wantLLMCall bool
+		wantErr     bool
+	}{
+		{name: "rules", mode: "rules", post: "Call 202-456-1111.", want: []string{"doxxing: phone number"}},
+		{name: "rules pass", mode: "rules", post: "826 days to go."},
+		{name: "llm", mode: "llm", post: "826 days to go.", response: flagged, want: []string{"llm: harassment"}, wantLLMCall: true},
+		{name: "both", mode: "both", post: "Call 202-456-1111.", response: `{"results": [{"flagged": false}]}`, want: []string{"doxxing: phone number"}, wantLLMCall: true},
+		{name: "both with the mock generator", mode: "both", generator: generatorMock, post: "826 days to go."},
+		{name: "unknown mode", mode: "strict", post: "826 days to go.", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf = config.Default()
+			conf.ModerationMode = tt.mode
+			if tt.generator != "" {
+				conf.Generator = tt.generator
+			}
+			stub := &stubDoer{body: tt.response}
+			openAIClient = stub
+
+			result, err := moderatePost(context.Background(), tt.post)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("moderatePost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if result.Flagged != (len(tt.want) > 0) || !slices.Equal(result.Reasons, tt.want) {
+				t.Errorf("moderatePost() = %v %v, want %v", result.Flagged, result.Reasons, tt.want)
+			}
+			if (stub.requests > 0) != tt.wantLLMCall {
+				t.Errorf("moderatePost() made %d moderation requests, want a request %v", stub.requests, tt.wantLLMCall)
+			}
+		})
+	}
+}
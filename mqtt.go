@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+	"github.com/lukeocodes/go-trump/mqtt"
+)
+
+// destinationMQTT is the destination type of an MQTT topic
+const destinationMQTT = "mqtt"
+
+// mqttConfig represents an mqtt destination's own settings
+type mqttConfig struct {
+	// Broker is the MQTT broker to publish to, e.g. "tcp://localhost:1883",
+	// and Topic the topic (default go-trump/countdown), published at QoS 0 or
+	// 1 and retained unless Retain is false
+	Broker string `json:"broker"`
+	Topic  string `json:"topic"`
+	QoS    int    `json:"qos"`
+	Retain *bool  `json:"retain"`
+
+	// UsernameEnv and PasswordEnv name the environment variables holding the
+	// broker's credentials (default MQTT_USERNAME and MQTT_PASSWORD)
+	UsernameEnv string `json:"username_env"`
+	PasswordEnv string `json:"password_env"`
+}
+
+// mqttSettings decodes and checks an mqtt destination's own settings
+func mqttSettings(destination *events.Destination) (mqttConfig, error) {
+	var c mqttConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if c.Broker == "" {
+		return c, fmt.Errorf("mqtt destination %q needs a broker", destination.Name)
+	}
+	if c.QoS != 0 && c.QoS != 1 {
+		return c, fmt.Errorf("mqtt destination %q has unsupported qos %d, expected 0 or 1", destination.Name, c.QoS)
+	}
+	if c.Topic == "" {
+		c.Topic = "go-trump/countdown"
+	}
+	if c.UsernameEnv == "" {
+		c.UsernameEnv = "MQTT_USERNAME"
+	}
+	if c.PasswordEnv == "" {
+		c.PasswordEnv = "MQTT_PASSWORD"
+	}
+	return c, nil
+}
+
+// MQTTMessage represents the JSON message published to an MQTT topic
+type MQTTMessage struct {
+	DaysLeft int    `json:"days_left"`
+	Event    string `json:"event,omitempty"`
+	Text     string `json:"text"`
+	PostType string `json:"post_type"`
+	Date     string `json:"date"`
+}
+
+// mqttPublisher publishes the day count and post to an MQTT topic, for
+// e-ink and LED countdown displays
+type mqttPublisher struct {
+	adapter
+
+	name        string
+	broker      string
+	topic       string
+	qos         byte
+	retain      bool
+	usernameEnv string
+	passwordEnv string
+}
+
+func init() {
+	registerPublisher(destinationMQTT, validateSettings(mqttSettings), factoryOf(newMQTTPublisher))
+}
+
+func newMQTTPublisher(destination *events.Destination) (*mqttPublisher, error) {
+	settings, err := mqttSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &mqttPublisher{
+		adapter:     newAdapter(destination, "a countdown display", 0, events.HashtagsStrip),
+		name:        destination.Name,
+		broker:      settings.Broker,
+		topic:       settings.Topic,
+		qos:         byte(settings.QoS),
+		retain:      settings.Retain == nil || *settings.Retain,
+		usernameEnv: settings.UsernameEnv,
+		passwordEnv: settings.PasswordEnv,
+	}, nil
+}
+
+func (p *mqttPublisher) Name() string {
+	return p.name
+}
+
+// Publish sends the JSON message to the topic, with the day count and text
+// alone on its days_left and text subtopics for displays that can't parse
+// JSON. There's no link to return.
+func (p *mqttPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	message, err := json.Marshal(MQTTMessage{
+		DaysLeft: post.DaysLeft,
+		Event:    post.Event,
+		Text:     post.Text,
+		PostType: post.PostType,
+		Date:     post.Date,
+	})
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to marshal MQTT message: %w", err)
+	}
+
+	// Publishing isn't cancelled by a shutdown signal once it starts
+	ctx, cancel := sendRequest(ctx)
+	defer cancel()
+
+	client, err := mqtt.Dial(ctx, p.broker, mqtt.Options{
+		ClientID: fmt.Sprintf("go-trump-%s-%d", p.name, time.Now().UnixNano()),
+		Username: config.Getenv(p.usernameEnv),
+		Password: config.Getenv(p.passwordEnv),
+	})
+	if err != nil {
+		return Published{}, err
+	}
+	defer client.Close()
+
+	messages := []struct {
+		topic   string
+		payload []byte
+	}{
+		{p.topic, message},
+		{p.topic + "/days_left", []byte(strconv.Itoa(post.DaysLeft))},
+		{p.topic + "/text", []byte(post.Text)},
+	}
+	for _, m := range messages {
+		if err := client.Publish(m.topic, m.payload, p.qos, p.retain); err != nil {
+			return Published{}, err
+		}
+	}
+
+	return Published{}, nil
+}
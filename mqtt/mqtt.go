@@ -0,0 +1,255 @@
+// Package mqtt is a minimal MQTT 3.1.1 client, enough to publish messages to
+// a broker at QoS 0 or 1
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Control packet types
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPuback     = 4
+	packetDisconnect = 14
+)
+
+// keepAlive is how long the broker waits between packets before dropping the
+// connection. Connections only live for one publish, so it is generous.
+const keepAlive = 60 * time.Second
+
+// Options represents how a client identifies itself to the broker
+type Options struct {
+	ClientID string
+	Username string
+	Password string
+}
+
+// Client represents a connection to an MQTT broker
+type Client struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	packetID uint16
+}
+
+// Dial connects to the broker, e.g. "tcp://localhost:1883", or over TLS with
+// "mqtts://" or "ssl://". Credentials in the URL are used when opts has none.
+// The context's deadline bounds the connection as a whole.
+func Dial(ctx context.Context, broker string, opts Options) (*Client, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker %q: %w", broker, err)
+	}
+
+	var port string
+	var useTLS bool
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		port = "1883"
+	case "mqtts", "ssl", "tls":
+		port, useTLS = "8883", true
+	default:
+		return nil, fmt.Errorf("invalid broker %q, expected tcp:// or mqtts://", broker)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if opts.Username == "" && u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+
+	var conn net.Conn
+	if useTLS {
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to broker: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c := &Client{conn: conn, r: bufio.NewReader(conn)}
+	if err := c.connect(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) connect(opts Options) error {
+	var flags byte = 0x02 // clean session
+	var payload []byte
+	payload = appendString(payload, opts.ClientID)
+	if opts.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+		if opts.Password != "" {
+			flags |= 0x40
+			payload = appendString(payload, opts.Password)
+		}
+	}
+
+	var header []byte
+	header = appendString(header, "MQTT")
+	header = append(header, 4, flags) // protocol level 4 is MQTT 3.1.1
+	header = appendUint16(header, uint16(keepAlive/time.Second))
+
+	if err := c.write(packetConnect<<4, append(header, payload...)); err != nil {
+		return fmt.Errorf("failed to send connect: %w", err)
+	}
+
+	packetType, body, err := c.read()
+	if err != nil {
+		return fmt.Errorf("failed to read connack: %w", err)
+	}
+	if packetType != packetConnack || len(body) != 2 {
+		return fmt.Errorf("unexpected reply to connect, packet type %d", packetType)
+	}
+	if code := body[1]; code != 0 {
+		return fmt.Errorf("broker refused the connection: %s", connackReason(code))
+	}
+	return nil
+}
+
+// Publish sends a message to the topic. At QoS 1 it waits for the broker to
+// acknowledge it. Retained messages are kept by the broker for subscribers
+// that connect later.
+func (c *Client) Publish(topic string, payload []byte, qos byte, retain bool) error {
+	if qos > 1 {
+		return fmt.Errorf("unsupported QoS %d", qos)
+	}
+
+	first := byte(packetPublish<<4) | qos<<1
+	if retain {
+		first |= 0x01
+	}
+
+	var body []byte
+	body = appendString(body, topic)
+	var id uint16
+	if qos > 0 {
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID = 1
+		}
+		id = c.packetID
+		body = appendUint16(body, id)
+	}
+	body = append(body, payload...)
+
+	if err := c.write(first, body); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	if qos == 0 {
+		return nil
+	}
+
+	for {
+		packetType, body, err := c.read()
+		if err != nil {
+			return fmt.Errorf("failed to read puback: %w", err)
+		}
+		if packetType == packetPuback && len(body) == 2 && uint16(body[0])<<8|uint16(body[1]) == id {
+			return nil
+		}
+	}
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() error {
+	c.write(packetDisconnect<<4, nil)
+	return c.conn.Close()
+}
+
+func (c *Client) write(first byte, body []byte) error {
+	packet := []byte{first}
+	packet = appendLength(packet, len(body))
+	packet = append(packet, body...)
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+func (c *Client) read() (byte, []byte, error) {
+	first, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return 0, nil, err
+	}
+	return first >> 4, body, nil
+}
+
+// appendLength encodes the remaining length of a packet
+func appendLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func appendUint16(b []byte, n uint16) []byte {
+	return append(b, byte(n>>8), byte(n))
+}
+
+func appendString(b []byte, s string) []byte {
+	return append(appendUint16(b, uint16(len(s))), s...)
+}
+
+func connackReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "client identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("return code %d", code)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// destinationNtfy is the destination type of an ntfy topic
+const destinationNtfy = "ntfy"
+
+const defaultNtfyServer = "https://ntfy.sh"
+
+// ntfyMaxLength is the longest message ntfy delivers as text rather than as
+// an attachment
+const ntfyMaxLength = 4096
+
+// NtfyMessage represents a message sent through ntfy's JSON publishing API
+type NtfyMessage struct {
+	Topic   string   `json:"topic"`
+	Message string   `json:"message"`
+	Title   string   `json:"title,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Attach  string   `json:"attach,omitempty"`
+}
+
+// ntfyConfig represents an ntfy destination's own settings
+type ntfyConfig struct {
+	// Topic is the topic to send to, on the server at Endpoint (default
+	// https://ntfy.sh), with the token in the environment variable named by
+	// AccessTokenEnv (default NTFY_TOKEN) for protected topics
+	Topic          string `json:"topic"`
+	Endpoint       string `json:"endpoint"`
+	AccessTokenEnv string `json:"access_token_env"`
+}
+
+// ntfySettings decodes and checks an ntfy destination's own settings
+func ntfySettings(destination *events.Destination) (ntfyConfig, error) {
+	var c ntfyConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if c.Topic == "" {
+		return c, fmt.Errorf("ntfy destination %q needs a topic", destination.Name)
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	if c.Endpoint == "" {
+		c.Endpoint = defaultNtfyServer
+	}
+	if c.AccessTokenEnv == "" {
+		c.AccessTokenEnv = "NTFY_TOKEN"
+	}
+	return c, nil
+}
+
+// ntfyPublisher sends posts as phone push notifications through ntfy
+type ntfyPublisher struct {
+	adapter
+
+	name           string
+	server         string
+	topic          string
+	accessTokenEnv string
+}
+
+func init() {
+	registerPublisher(destinationNtfy, validateSettings(ntfySettings), factoryOf(newNtfyPublisher))
+}
+
+func newNtfyPublisher(destination *events.Destination) (*ntfyPublisher, error) {
+	settings, err := ntfySettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &ntfyPublisher{
+		adapter:        newAdapter(destination, "a push notification", ntfyMaxLength, events.HashtagsStrip),
+		name:           destination.Name,
+		server:         settings.Endpoint,
+		topic:          settings.Topic,
+		accessTokenEnv: settings.AccessTokenEnv,
+	}, nil
+}
+
+func (p *ntfyPublisher) Name() string {
+	return p.name
+}
+
+// Publish sends the post to the topic, titled with the day count. The token is
+// optional, as public topics need none. There's no link to return.
+func (p *ntfyPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	message := NtfyMessage{
+		Topic:   p.topic,
+		Message: post.Text,
+		Tags:    []string{"calendar"},
+		Attach:  post.ImageURL,
+	}
+	if post.Event != "" {
+		message.Title = fmt.Sprintf("%d days until %s", post.DaysLeft, post.Event)
+	}
+
+	bodyBytes, err := json.Marshal(message)
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to marshal ntfy message: %w", err)
+	}
+
+	// Publishing isn't cancelled by a shutdown signal once it starts
+	ctx, cancel := sendRequest(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.server, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to create ntfy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := config.Getenv(p.accessTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Published{}, fmt.Errorf("ntfy request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return Published{}, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("ntfy error (%d): %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))}
+	}
+
+	return Published{}, nil
+}
@@ -0,0 +1,102 @@
+// Package numbers formats counts for posts, with locale-aware digit grouping
+// and English spelled-out numbers for milestones.
+package numbers
+
+import (
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Formatter formats numbers for a locale
+type Formatter struct {
+	printer *message.Printer
+}
+
+// NewFormatter returns a formatter for a BCP 47 locale such as "en-US" or
+// "fr-FR", falling back to US English when the locale is empty or unknown
+func NewFormatter(locale string) *Formatter {
+	tag, err := language.Parse(locale)
+	if locale == "" || err != nil {
+		tag = language.AmericanEnglish
+	}
+	return &Formatter{printer: message.NewPrinter(tag)}
+}
+
+// Format groups the digits of n for the locale, e.g. 1,234 or 1 234
+func (f *Formatter) Format(n int) string {
+	return f.printer.Sprintf("%d", n)
+}
+
+var ones = []string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+	"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen",
+	"seventeen", "eighteen", "nineteen",
+}
+
+var tens = []string{
+	"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety",
+}
+
+var scales = []struct {
+	value int
+	name  string
+}{
+	{1_000_000_000, "billion"},
+	{1_000_000, "million"},
+	{1_000, "thousand"},
+}
+
+// maxSpelled is the first number beyond the largest scale, which Spell writes
+// in digits instead
+const maxSpelled = 1_000_000_000_000
+
+// Spell spells out n in English, e.g. 1461 is "one thousand four hundred
+// sixty-one". Numbers of a trillion or more are written in digits.
+func Spell(n int) string {
+	if m := int64(n); m >= maxSpelled || m <= -maxSpelled {
+		return strconv.Itoa(n)
+	}
+	if n < 0 {
+		return "minus " + Spell(-n)
+	}
+	if n < 1000 {
+		return spellHundreds(n)
+	}
+
+	var parts []string
+	for _, scale := range scales {
+		if n >= scale.value {
+			parts = append(parts, spellHundreds(n/scale.value)+" "+scale.name)
+			n %= scale.value
+		}
+	}
+	if n > 0 {
+		parts = append(parts, spellHundreds(n))
+	}
+	return strings.Join(parts, " ")
+}
+
+// spellHundreds spells out a number below 1000
+func spellHundreds(n int) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, ones[n/100]+" hundred")
+		n %= 100
+		if n == 0 {
+			return strings.Join(parts, " ")
+		}
+	}
+
+	switch {
+	case n < 20:
+		parts = append(parts, ones[n])
+	case n%10 == 0:
+		parts = append(parts, tens[n/10])
+	default:
+		parts = append(parts, tens[n/10]+"-"+ones[n%10])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,64 @@
+package numbers
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestSpell(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "zero"},
+		{7, "seven"},
+		{13, "thirteen"},
+		{40, "forty"},
+		{42, "forty-two"},
+		{100, "one hundred"},
+		{826, "eight hundred twenty-six"},
+		{1000, "one thousand"},
+		{1461, "one thousand four hundred sixty-one"},
+		{1_000_001, "one million one"},
+		{2_000_340_000, "two billion three hundred forty thousand"},
+		{999_999_999_999, "nine hundred ninety-nine billion nine hundred ninety-nine million nine hundred ninety-nine thousand nine hundred ninety-nine"},
+		{-5, "minus five"},
+		{1_000_000_000_000, "1000000000000"},
+		{-1_000_000_000_000, "-1000000000000"},
+		{math.MaxInt, strconv.Itoa(math.MaxInt)},
+		{math.MinInt, strconv.Itoa(math.MinInt)},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.n), func(t *testing.T) {
+			if got := Spell(tt.n); got != tt.want {
+				t.Errorf("Spell(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatterFormat(t *testing.T) {
+	tests := []struct {
+		locale string
+		n      int
+		want   string
+	}{
+		{"en-US", 1461, "1,461"},
+		{"en-US", 826, "826"},
+		{"en-US", -1234567, "-1,234,567"},
+		{"de-DE", 1461, "1.461"},
+		{"fr-FR", 1461, "1\u00a0461"},
+		{"", 1461, "1,461"},
+		{"not a locale", 1461, "1,461"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			if got := NewFormatter(tt.locale).Format(tt.n); got != tt.want {
+				t.Errorf("NewFormatter(%q).Format(%d) = %q, want %q", tt.locale, tt.n, got, tt.want)
+			}
+		})
+	}
+}
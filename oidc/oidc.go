@@ -0,0 +1,296 @@
+// Package oidc verifies the Google-signed OIDC ID tokens that Cloud Scheduler
+// and other Google services attach to the HTTP requests they make
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lukeocodes/go-trump/httpclient"
+	"golang.org/x/sync/singleflight"
+)
+
+// GoogleCertsURL is where Google publishes the keys its ID tokens are signed with
+const GoogleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+// googleIssuers are the issuers Google ID tokens may carry
+var googleIssuers = []string{"https://accounts.google.com", "accounts.google.com"}
+
+// leeway allows for clock skew when checking token times
+const leeway = time.Minute
+
+// refetchInterval is the least time between fetches of the keys prompted by
+// an unknown key ID, so tokens with made-up IDs can't have every request
+// download them
+const refetchInterval = time.Minute
+
+// maxUnknown bounds how many unknown key IDs are remembered between fetches
+const maxUnknown = 1000
+
+// Claims represents the verified claims of an ID token
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	IssuedAt      int64    `json:"iat"`
+	Expiry        int64    `json:"exp"`
+}
+
+// audience is the "aud" claim, which may be a single string or a list
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid aud claim: %w", err)
+	}
+	*a = list
+	return nil
+}
+
+func (a audience) contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier represents a check of Google ID tokens against an expected audience
+type Verifier struct {
+	// Audience is the audience the token must have been minted for
+	Audience string
+	// Email, when set, is the service account the token must belong to
+	Email string
+	// CertsURL serves the signing keys as a JWK set (default GoogleCertsURL)
+	CertsURL string
+	// Client fetches the signing keys (default http.DefaultClient)
+	Client httpclient.Doer
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	expires   time.Time
+	fetchedAt time.Time
+	unknown   map[string]bool
+	fetches   singleflight.Group
+}
+
+// NewVerifier returns a Verifier for tokens minted for audience
+func NewVerifier(audience string) *Verifier {
+	return &Verifier{Audience: audience}
+}
+
+// Verify checks the token's RS256 signature, issuer, audience and expiry, and
+// returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("failed to decode token header: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", header.Alg)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, errors.New("invalid token signature")
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("failed to decode token claims: %w", err)
+	}
+
+	if !isGoogleIssuer(claims.Issuer) {
+		return nil, fmt.Errorf("unexpected token issuer %q", claims.Issuer)
+	}
+	if !claims.Audience.contains(v.Audience) {
+		return nil, fmt.Errorf("token audience %v does not match %q", []string(claims.Audience), v.Audience)
+	}
+
+	now := time.Now()
+	if now.After(time.Unix(claims.Expiry, 0).Add(leeway)) {
+		return nil, errors.New("token has expired")
+	}
+	if now.Add(leeway).Before(time.Unix(claims.IssuedAt, 0)) {
+		return nil, errors.New("token used before it was issued")
+	}
+
+	if v.Email != "" && (claims.Email != v.Email || !claims.EmailVerified) {
+		return nil, fmt.Errorf("token email %q does not match %q", claims.Email, v.Email)
+	}
+
+	return &claims, nil
+}
+
+// key returns the signing key with the given ID, refreshing the cached keys
+// when they have expired or the ID is unknown, as keys are rotated regularly.
+// An ID still unknown after a refresh is rejected without fetching again until
+// the keys expire, and other unknown IDs refresh them at most once every
+// refetchInterval.
+func (v *Verifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	fresh := time.Now().Before(v.expires)
+	refetch := !fresh || (!ok && !v.unknown[kid] && time.Since(v.fetchedAt) >= refetchInterval)
+	v.mu.Unlock()
+
+	switch {
+	case ok && fresh:
+		return key, nil
+	case !refetch:
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	// Concurrent requests share a fetch, made without holding the lock
+	if _, err, _ := v.fetches.Do("keys", func() (any, error) { return nil, v.fetchKeys(ctx) }); err != nil {
+		return nil, err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key, ok = v.keys[kid]
+	if !ok {
+		if len(v.unknown) < maxUnknown {
+			v.unknown[kid] = true
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys downloads the JWK set, caching it for as long as its response allows
+func (v *Verifier) fetchKeys(ctx context.Context) error {
+	v.mu.Lock()
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+
+	url := v.CertsURL
+	if url == "" {
+		url = GoogleCertsURL
+	}
+	var client httpclient.Doer = http.DefaultClient
+	if v.Client != nil {
+		client = v.Client
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create certs request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("certs request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received non-200 certs response status: %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode certs response: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return fmt.Errorf("invalid modulus for key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return fmt.Errorf("invalid exponent for key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.keys = keys
+	v.expires = time.Now().Add(maxAge(resp.Header.Get("Cache-Control")))
+	v.unknown = map[string]bool{}
+	return nil
+}
+
+// maxAge reads max-age from a Cache-Control header, defaulting to an hour
+func maxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if seconds, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if d, err := time.ParseDuration(seconds + "s"); err == nil {
+				return d
+			}
+		}
+	}
+	return time.Hour
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func isGoogleIssuer(iss string) bool {
+	for _, v := range googleIssuers {
+		if iss == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testKeys serves a JWK set of one key, counting how often it's fetched
+type testKeys struct {
+	key     *rsa.PrivateKey
+	kid     string
+	fetches atomic.Int32
+}
+
+func newTestKeys(t *testing.T) (*testKeys, *Verifier) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &testKeys{key: key, kid: "key-1"}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k.fetches.Add(1)
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": k.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(srv.Close)
+
+	v := NewVerifier("https://go-trump.example.com/trigger")
+	v.CertsURL = srv.URL
+	return k, v
+}
+
+// sign returns a token of the claims signed with key, under the key ID kid
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerify(t *testing.T) {
+	keys, shared := newTestKeys(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	// valid returns the claims of a token that passes, changed by edit
+	valid := func(edit func(map[string]any)) map[string]any {
+		claims := map[string]any{
+			"iss":            "https://accounts.google.com",
+			"sub":            "1234567890",
+			"aud":            "https://go-trump.example.com/trigger",
+			"email":          "scheduler@project.iam.gserviceaccount.com",
+			"email_verified": true,
+			"iat":            now.Add(-time.Minute).Unix(),
+			"exp":            now.Add(time.Hour).Unix(),
+		}
+		if edit != nil {
+			edit(claims)
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		email   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			token: sign(t, keys.key, keys.kid, valid(nil)),
+		},
+		{
+			name:  "issuer without scheme",
+			token: sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["iss"] = "accounts.google.com" })),
+		},
+		{
+			name:  "audience in a list",
+			token: sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["aud"] = []string{"other", "https://go-trump.example.com/trigger"} })),
+		},
+		{
+			name:    "signed by another key",
+			token:   sign(t, other, keys.kid, valid(nil)),
+			wantErr: "invalid token signature",
+		},
+		{
+			name: "claims changed after signing",
+			token: func() string {
+				parts := strings.Split(sign(t, keys.key, keys.kid, valid(nil)), ".")
+				claims, _ := json.Marshal(valid(func(c map[string]any) { c["email"] = "attacker@example.com" }))
+				parts[1] = base64.RawURLEncoding.EncodeToString(claims)
+				return strings.Join(parts, ".")
+			}(),
+			wantErr: "invalid token signature",
+		},
+		{
+			name:    "wrong issuer",
+			token:   sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["iss"] = "https://evil.example.com" })),
+			wantErr: "unexpected token issuer",
+		},
+		{
+			name:    "wrong audience",
+			token:   sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["aud"] = "https://other.example.com" })),
+			wantErr: "does not match",
+		},
+		{
+			name:    "expired",
+			token:   sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["exp"] = now.Add(-2 * leeway).Unix() })),
+			wantErr: "token has expired",
+		},
+		{
+			name:  "expired within the leeway",
+			token: sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["exp"] = now.Add(-leeway / 2).Unix() })),
+		},
+		{
+			name:    "issued in the future",
+			token:   sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["iat"] = now.Add(time.Hour).Unix() })),
+			wantErr: "before it was issued",
+		},
+		{
+			name:  "allowed email",
+			token: sign(t, keys.key, keys.kid, valid(nil)),
+			email: "scheduler@project.iam.gserviceaccount.com",
+		},
+		{
+			name:    "other email",
+			token:   sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["email"] = "someone@example.com" })),
+			email:   "scheduler@project.iam.gserviceaccount.com",
+			wantErr: "token email",
+		},
+		{
+			name:    "unverified email",
+			token:   sign(t, keys.key, keys.kid, valid(func(c map[string]any) { c["email_verified"] = false })),
+			email:   "scheduler@project.iam.gserviceaccount.com",
+			wantErr: "token email",
+		},
+		{
+			name:    "unknown key",
+			token:   sign(t, keys.key, "key-2", valid(nil)),
+			wantErr: "unknown signing key",
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-token",
+			wantErr: "malformed token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(shared.Audience)
+			v.CertsURL = shared.CertsURL
+			v.Email = tt.email
+
+			claims, err := v.Verify(context.Background(), tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				if claims.Subject != "1234567890" {
+					t.Errorf("Verify() subject = %q, want 1234567890", claims.Subject)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUnknownKeysAreRateLimited(t *testing.T) {
+	keys, v := newTestKeys(t)
+	claims := map[string]any{
+		"iss": "https://accounts.google.com",
+		"aud": v.Audience,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	if _, err := v.Verify(context.Background(), sign(t, keys.key, keys.kid, claims)); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	for i := range 20 {
+		kid := "made-up-" + string(rune('a'+i))
+		if _, err := v.Verify(context.Background(), sign(t, keys.key, kid, claims)); err == nil {
+			t.Fatalf("Verify() with key %q succeeded", kid)
+		}
+	}
+	if n := keys.fetches.Load(); n != 1 {
+		t.Errorf("fetched the keys %d times, want 1", n)
+	}
+
+	// Once the interval has passed an unknown ID may fetch them again, to
+	// pick up a rotated key, but the same ID only once
+	v.mu.Lock()
+	v.fetchedAt = time.Now().Add(-refetchInterval)
+	v.mu.Unlock()
+	keys.kid = "rotated"
+	if _, err := v.Verify(context.Background(), sign(t, keys.key, "rotated", claims)); err != nil {
+		t.Fatalf("Verify() with the rotated key error = %v", err)
+	}
+	for range 2 {
+		v.mu.Lock()
+		v.fetchedAt = time.Now().Add(-refetchInterval)
+		v.mu.Unlock()
+		v.Verify(context.Background(), sign(t, keys.key, "still-unknown", claims))
+	}
+	if n := keys.fetches.Load(); n != 3 {
+		t.Errorf("fetched the keys %d times, want 3", n)
+	}
+}
@@ -0,0 +1,99 @@
+// Package onthisday fetches historical facts for a date from Wikipedia's
+// "On this day" feed, to give each day's post a unique hook.
+package onthisday
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lukeocodes/go-trump/httpclient"
+)
+
+const feedURL = "https://api.wikimedia.org/feed/v1/wikipedia/en/onthisday/events/%02d/%02d"
+
+// userAgent identifies the bot, as required by the Wikimedia API etiquette
+const userAgent = "go-trump (https://github.com/lukeocodes/go-trump)"
+
+// relevantKeywords make a fact more likely to be picked, since facts about
+// elections and government fit the bot's posts best
+var relevantKeywords = []string{
+	"president", "election", "elected", "inaugurat", "congress", "senate",
+	"constitution", "democra", "vote", "voting", "supreme court", "impeach",
+	"civil rights", "independence", "amendment",
+}
+
+// Fact represents a single historical event on a given day
+type Fact struct {
+	Year int    `json:"year"`
+	Text string `json:"text"`
+}
+
+// String formats the fact as e.g. "In 1961, John F. Kennedy is inaugurated..."
+func (f Fact) String() string {
+	return fmt.Sprintf("In %d, %s", f.Year, f.Text)
+}
+
+// Fetch returns the historical events that happened on date's month and day,
+// requesting them with client
+func Fetch(ctx context.Context, client httpclient.Doer, date time.Time) ([]Fact, error) {
+	url := fmt.Sprintf(feedURL, int(date.Month()), date.Day())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create on this day request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("on this day request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read on this day error response body: %w", err)
+		}
+		return nil, fmt.Errorf("received non-200 on this day response status: %d - %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var response struct {
+		Events []Fact `json:"events"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode on this day response: %w", err)
+	}
+
+	return response.Events, nil
+}
+
+// Pick chooses a fact at random, preferring ones about elections and government
+func Pick(facts []Fact) (Fact, bool) {
+	if len(facts) == 0 {
+		return Fact{}, false
+	}
+
+	var relevant []Fact
+	for _, f := range facts {
+		text := strings.ToLower(f.Text)
+		for _, keyword := range relevantKeywords {
+			if strings.Contains(text, keyword) {
+				relevant = append(relevant, f)
+				break
+			}
+		}
+	}
+
+	if len(relevant) > 0 {
+		return relevant[rand.Intn(len(relevant))], true
+	}
+	return facts[rand.Intn(len(facts))], true
+}
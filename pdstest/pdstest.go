@@ -0,0 +1,285 @@
+// Package pdstest provides a fake Bluesky PDS for tests. It serves
+// createSession, refreshSession, createRecord and uploadBlob from an
+// httptest.Server, keeping the records and blobs it's given, and each
+// endpoint's responses can be scripted to test how failures are handled.
+package pdstest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The XRPC methods the server implements
+const (
+	CreateSession  = "com.atproto.server.createSession"
+	RefreshSession = "com.atproto.server.refreshSession"
+	CreateRecord   = "com.atproto.repo.createRecord"
+	UploadBlob     = "com.atproto.repo.uploadBlob"
+)
+
+// Server represents a fake PDS hosting a single account, which signs in with
+// Handle, or DID, and Password
+type Server struct {
+	*httptest.Server
+
+	Handle   string
+	DID      string
+	Password string
+
+	mu       sync.Mutex
+	requests []Request
+	scripts  map[string][]Response
+	records  []Record
+	blobs    [][]byte
+	sessions int
+	access   map[string]bool
+	refresh  map[string]bool
+}
+
+// Request represents a request the server received
+type Request struct {
+	Method string
+	NSID   string
+	Header http.Header
+	Body   []byte
+}
+
+// Response represents a scripted response, with Body encoded as JSON unless
+// it's nil
+type Response struct {
+	Status int
+	Body   any
+}
+
+// Record represents a record created in the account's repo
+type Record struct {
+	Collection string
+	Rkey       string
+	URI        string
+	CID        string
+
+	// Value is the record as it was sent
+	Value map[string]any
+}
+
+// NewServer starts a fake PDS for the account countdown.test, with the
+// password "app-password", which is closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		Handle:   "countdown.test",
+		DID:      "did:plc:countdowntest",
+		Password: "app-password",
+		scripts:  map[string][]Response{},
+		access:   map[string]bool{},
+		refresh:  map[string]bool{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a client that sends requests for any host, such as
+// bsky.social, to the server
+func (s *Server) Client() *http.Client {
+	target, _ := url.Parse(s.URL)
+	return &http.Client{Transport: &redirectTransport{target: target, next: s.Server.Client().Transport}}
+}
+
+// Script queues responses for nsid, which are sent in turn, in place of what
+// the server would otherwise do, until they run out
+func (s *Server) Script(nsid string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[nsid] = append(s.scripts[nsid], responses...)
+}
+
+// Fail queues an XRPC error response for nsid, as Script does
+func (s *Server) Fail(nsid string, status int, name, message string) {
+	s.Script(nsid, Response{Status: status, Body: xrpcError{Error: name, Message: message}})
+}
+
+// Requests returns the requests the server received for nsid, or for every
+// method when nsid is "", oldest first
+func (s *Server) Requests(nsid string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []Request
+	for _, req := range s.requests {
+		if nsid == "" || req.NSID == nsid {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// Records returns the records created in the repo, oldest first
+func (s *Server) Records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Record(nil), s.records...)
+}
+
+// Blobs returns the blobs uploaded, oldest first
+func (s *Server) Blobs() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.blobs...)
+}
+
+// xrpcError is the body of an XRPC error response
+type xrpcError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	nsid, ok := strings.CutPrefix(r.URL.Path, "/xrpc/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{Method: r.Method, NSID: nsid, Header: r.Header.Clone(), Body: body})
+
+	if script := s.scripts[nsid]; len(script) > 0 {
+		s.scripts[nsid] = script[1:]
+		writeJSON(w, script[0].Status, script[0].Body)
+		return
+	}
+
+	switch nsid {
+	case CreateSession:
+		s.createSession(w, body)
+	case RefreshSession:
+		s.refreshSession(w, r)
+	case CreateRecord:
+		s.createRecord(w, r, body)
+	case UploadBlob:
+		s.uploadBlob(w, r, body)
+	default:
+		writeJSON(w, http.StatusNotImplemented, xrpcError{"MethodNotImplemented", "Method Not Implemented"})
+	}
+}
+
+func (s *Server) createSession(w http.ResponseWriter, body []byte) {
+	var input struct {
+		Identifier string `json:"identifier"`
+		Password   string `json:"password"`
+	}
+	if err := json.Unmarshal(body, &input); err != nil {
+		writeJSON(w, http.StatusBadRequest, xrpcError{"InvalidRequest", "Invalid request body"})
+		return
+	}
+	if (input.Identifier != s.Handle && input.Identifier != s.DID) || input.Password != s.Password {
+		writeJSON(w, http.StatusUnauthorized, xrpcError{"AuthenticationRequired", "Invalid identifier or password"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.newSession())
+}
+
+func (s *Server) refreshSession(w http.ResponseWriter, r *http.Request) {
+	token := bearer(r)
+	if !s.refresh[token] {
+		writeJSON(w, http.StatusBadRequest, xrpcError{"ExpiredToken", "Token has expired"})
+		return
+	}
+	delete(s.refresh, token)
+	writeJSON(w, http.StatusOK, s.newSession())
+}
+
+// newSession issues a new pair of tokens
+func (s *Server) newSession() map[string]string {
+	s.sessions++
+	access, refresh := fmt.Sprintf("access-%d", s.sessions), fmt.Sprintf("refresh-%d", s.sessions)
+	s.access[access], s.refresh[refresh] = true, true
+	return map[string]string{"accessJwt": access, "refreshJwt": refresh, "did": s.DID, "handle": s.Handle}
+}
+
+func (s *Server) createRecord(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !s.access[bearer(r)] {
+		writeJSON(w, http.StatusUnauthorized, xrpcError{"InvalidToken", "Bad token"})
+		return
+	}
+	var input struct {
+		Repo       string         `json:"repo"`
+		Collection string         `json:"collection"`
+		Record     map[string]any `json:"record"`
+	}
+	if err := json.Unmarshal(body, &input); err != nil || input.Collection == "" || input.Record == nil {
+		writeJSON(w, http.StatusBadRequest, xrpcError{"InvalidRequest", "Invalid request body"})
+		return
+	}
+	if input.Repo != s.DID && input.Repo != s.Handle {
+		writeJSON(w, http.StatusBadRequest, xrpcError{"InvalidRequest", "Repo does not match the session"})
+		return
+	}
+
+	record := Record{
+		Collection: input.Collection,
+		Rkey:       fmt.Sprintf("3kfake%07d", len(s.records)+1),
+		CID:        fakeCID(body),
+		Value:      input.Record,
+	}
+	record.URI = fmt.Sprintf("at://%s/%s/%s", s.DID, record.Collection, record.Rkey)
+	s.records = append(s.records, record)
+	writeJSON(w, http.StatusOK, map[string]string{"uri": record.URI, "cid": record.CID})
+}
+
+func (s *Server) uploadBlob(w http.ResponseWriter, r *http.Request, body []byte) {
+	if !s.access[bearer(r)] {
+		writeJSON(w, http.StatusUnauthorized, xrpcError{"InvalidToken", "Bad token"})
+		return
+	}
+	s.blobs = append(s.blobs, body)
+	writeJSON(w, http.StatusOK, map[string]any{"blob": map[string]any{
+		"$type":    "blob",
+		"ref":      map[string]string{"$link": fakeCID(body)},
+		"mimeType": r.Header.Get("Content-Type"),
+		"size":     len(body),
+	}})
+}
+
+// bearer returns the token of a request's Authorization header
+func bearer(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// fakeCID returns a stand-in for the CID of data, stable for the same data
+func fakeCID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "bafyrei" + hex.EncodeToString(sum[:16])
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	if body == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// redirectTransport sends every request to target, whatever its host
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host, req.Host = t.target.Scheme, t.target.Host, ""
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,51 @@
+package pdstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRefreshSession(t *testing.T) {
+	s := NewServer(t)
+	client := s.Client()
+
+	call := func(nsid, token, body string) (int, map[string]string) {
+		req, _ := http.NewRequest("POST", "https://bsky.social/xrpc/"+nsid, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var out map[string]string
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp.StatusCode, out
+	}
+
+	status, session := call(CreateSession, "", `{"identifier":"countdown.test","password":"app-password"}`)
+	if status != http.StatusOK {
+		t.Fatalf("createSession responded %d", status)
+	}
+
+	status, refreshed := call(RefreshSession, session["refreshJwt"], "")
+	if status != http.StatusOK || refreshed["accessJwt"] == session["accessJwt"] {
+		t.Fatalf("refreshSession responded %d with %v, want a new session", status, refreshed)
+	}
+
+	// A refresh token can only be used once
+	if status, _ := call(RefreshSession, session["refreshJwt"], ""); status != http.StatusBadRequest {
+		t.Errorf("refreshSession with a used token responded %d, want 400", status)
+	}
+
+	s.Fail(RefreshSession, http.StatusInternalServerError, "InternalServerError", "Internal Server Error")
+	if status, body := call(RefreshSession, refreshed["refreshJwt"], ""); status != http.StatusInternalServerError || body["error"] != "InternalServerError" {
+		t.Errorf("scripted refreshSession responded %d with %v", status, body)
+	}
+	if n := len(s.Requests(RefreshSession)); n != 3 {
+		t.Errorf("recorded %d refreshSession requests, want 3", n)
+	}
+}
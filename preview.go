@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lukeocodes/go-trump/events"
+	"github.com/lukeocodes/go-trump/state"
+)
+
+// runPreview generates samples posts of postType for date, or for today when
+// date is empty, and prints the prompt they were written from, then each
+// with its length, how it fares against the checks made before publishing
+// and how each destination would get it. Nothing is published or recorded,
+// and no one is alerted.
+func runPreview(ctx context.Context, postType, date string, samples int) error {
+	if samples < 1 {
+		return failure(errConfig, fmt.Errorf("the number of samples must be at least 1, not %d", samples))
+	}
+
+	// Alerts are only logged, as in a dry run
+	conf.DryRun = true
+
+	cfg, err := loadEvents()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	schedule, ok := cfg.ScheduleNamed(postType)
+	if !ok {
+		return failure(errConfig, fmt.Errorf("no schedule configured for post type %q", postType))
+	}
+	st, err := state.Load(stateFile())
+	if err != nil {
+		return err
+	}
+	loc, err := cfg.Location()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	now = now.In(loc)
+
+	// Another date is previewed at the time of day it is now
+	if date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return failure(errConfig, fmt.Errorf("invalid date %q, want YYYY-MM-DD", date))
+		}
+		now = time.Date(day.Year(), day.Month(), day.Day(), now.Hour(), now.Minute(), now.Second(), 0, loc)
+	}
+
+	pubs, _, err := publishers(cfg)
+	if err != nil {
+		return err
+	}
+
+	policy := duplicatePolicy()
+	recent := recentPosts(ctx, st, policy.Window)
+	upcoming := events.Upcoming(cfg.Events, now)
+	fmt.Printf("Previewing the %s post for %s\n", schedule.Name, now.Format("Monday, January 2, 2006"))
+
+	var prompt string
+	passed := 0
+	for i := 1; i <= samples; i++ {
+		completion, milestone, err := writePost(ctx, cfg, upcoming, schedule)
+		if err != nil {
+			return failure(errGeneration, err)
+		}
+
+		// The prompt only changes between samples with the prompt variant
+		if completion.System+completion.Prompt != prompt {
+			prompt = completion.System + completion.Prompt
+			fmt.Printf("\nSystem prompt:\n%s\n\nPrompt, %s:\n%s\n", completion.System, completion.Variant, completion.Prompt)
+		}
+
+		fmt.Printf("\n--- Sample %d of %d, model %s, %d tokens\n%s\n\n", i, samples, completion.Model, completion.PromptTokens+completion.CompletionTokens, completion.Text)
+		if printChecks(ctx, completion.Text, recent, policy) {
+			passed++
+		}
+
+		// Destinations it can't be adapted for are shown with the reason
+		printPosts(ctx, pubs, outgoingPost(schedule, upcoming, milestone, completion.Text))
+	}
+
+	fmt.Printf("\n%d of %d samples passed every check\n", passed, samples)
+	return nil
+}
+
+// printChecks prints how text fares against Bluesky's length limit, the
+// content policy and the duplicate policy, reporting whether it passed them
+// all
+func printChecks(ctx context.Context, text string, recent []state.Post, policy DuplicatePolicy) bool {
+	ok := true
+
+	if n := graphemeCount(text); n > blueskyMaxLength {
+		fmt.Printf("Length: %d graphemes, over Bluesky's %d\n", n, blueskyMaxLength)
+		ok = false
+	} else {
+		fmt.Printf("Length: %d graphemes, within Bluesky's %d\n", n, blueskyMaxLength)
+	}
+
+	moderation, err := moderatePost(ctx, text)
+	switch {
+	case err != nil:
+		fmt.Printf("Content policy: check failed: %v\n", err)
+		ok = false
+	case moderation.Flagged:
+		fmt.Printf("Content policy: blocked, %s\n", blockedReasons(moderation))
+		ok = false
+	default:
+		fmt.Println("Content policy: passed")
+	}
+
+	similar, score := mostSimilar(text, recent)
+	switch {
+	case len(recent) == 0:
+		fmt.Println("Duplicates: no recent posts to compare with")
+	case score >= policy.Threshold:
+		fmt.Printf("Duplicates: %.2f similar to the %s post of %s, at or over %.2f, so it would be regenerated\n", score, similar.PostType, similar.Date, policy.Threshold)
+		ok = false
+	default:
+		fmt.Printf("Duplicates: at most %.2f similar to a recent post, under %.2f\n", score, policy.Threshold)
+	}
+	return ok
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+
+	"github.com/lukeocodes/go-trump/countdown"
+	"github.com/lukeocodes/go-trump/events"
+	"github.com/lukeocodes/go-trump/numbers"
+	"github.com/lukeocodes/go-trump/onthisday"
+)
+
+// promptPartials are shared sub-templates available to every prompt template
+const promptPartials = `
+{{- define "count" -}}
+{{- if .CountsDown}}{{if .FinalStretch}}This is the final stretch: there are only {{.ExactTimeLeft}} left until {{.TargetTime}}.{{else}}There are exactly {{number .DaysLeft}} days ({{.TimeLeft}}) until {{.TargetDate}}.{{end}}{{end}}
+{{- if and .CountsDown .CountsUp}} {{end}}
+{{- if .CountsUp}}Today is day {{number .DayNumber}} {{.Since}}.{{end}}
+{{- end -}}
+
+{{- define "extras" -}}
+{{- if .ShowPercentComplete}} {{printf "%.0f" .PercentComplete}}% of the term is over, so mention that too.{{end}}
+{{- if .OnThisDay}} For a unique hook, you can reference this fact about what happened on this day in history: {{.OnThisDay}}{{end}}
+{{- if .Others}} Also briefly mention these other countdowns:
+{{- range .Others}}
+- {{if .CountsDown}}{{if .FinalStretch}}{{.ExactTimeLeft}}{{else}}{{number .DaysLeft}} days{{end}} until {{.Name}} ({{.TargetDate}}){{end}}
+{{- if and .CountsDown .CountsUp}}, {{end}}
+{{- if .CountsUp}}day {{number .DayNumber}} {{.Since}}{{end}}
+{{- end}}
+{{- end}}
+{{- end -}}`
+
+// defaultPrompt is the ordinary daily prompt template
+const defaultPrompt = `Today is {{.Today}}. Write a short, encouraging post about {{if .CountsDown}}how many days are left until {{.Event.Name}}{{else}}the day count {{.Since}}{{end}}. {{template "count" .}} {{.Event.PromptHints}}{{template "extras" .}}`
+
+// defaultMilestonePrompt is used on milestone days that don't set their own prompt
+const defaultMilestonePrompt = `Today is {{.Today}} and the countdown has reached a milestone: {{.Milestone}}. Write a short, celebratory post marking this milestone on the way to {{.Event.Name}}. {{template "count" .}} {{.Event.PromptHints}}{{template "extras" .}}`
+
+// defaultWeeklyRecapPrompt is the built-in prompt for the weekly_recap post type
+const defaultWeeklyRecapPrompt = `Today is {{.Today}}. Write a short weekly recap post for the countdown to {{.Event.Name}}, celebrating another week down. {{template "count" .}} {{.Event.PromptHints}}{{template "extras" .}}`
+
+// defaultEveningPrompt is the built-in prompt for the evening post type
+const defaultEveningPrompt = `Today is {{.Today}}. Write a short, calming evening post to help people wind down, noting that another day of the countdown to {{.Event.Name}} is nearly done. {{template "count" .}} {{.Event.PromptHints}}{{template "extras" .}}`
+
+// schedulePrompt returns the prompt template for a schedule's post type
+func schedulePrompt(cfg *events.File, schedule *events.Schedule) string {
+	switch {
+	case schedule.Prompt != "":
+		return schedule.Prompt
+	case schedule.Name == events.PostTypeWeeklyRecap:
+		return defaultWeeklyRecapPrompt
+	case schedule.Name == events.PostTypeEvening:
+		return defaultEveningPrompt
+	case cfg.Prompt != "":
+		return cfg.Prompt
+	default:
+		return defaultPrompt
+	}
+}
+
+// Countdown represents a single countdown as exposed to prompt templates. Count
+// up events report the day number since their start instead of, or as well as,
+// the days left.
+type Countdown struct {
+	Name       string
+	DaysLeft   int
+	TimeLeft   string
+	TargetDate string
+	TargetTime string
+
+	// BusinessDaysLeft counts only the weekdays left, skipping holidays
+	BusinessDaysLeft int
+
+	// FinalStretch is set in the last few days of a countdown, when
+	// ExactTimeLeft gives the hours and minutes remaining
+	FinalStretch  bool
+	ExactTimeLeft string
+
+	CountsDown bool
+	CountsUp   bool
+	DaysSince  int
+	DayNumber  int
+	Since      string
+}
+
+// PromptData represents the variables available to prompt templates
+type PromptData struct {
+	Countdown
+
+	Today     string
+	Event     PromptEvent
+	Milestone string
+	Others    []Countdown
+
+	// Special names today's special day, on special day posts
+	Special string
+
+	// PercentComplete is how much of the primary event's term has elapsed, and
+	// is only meaningful when HasPercentComplete is set
+	PercentComplete     float64
+	HasPercentComplete  bool
+	ShowPercentComplete bool
+
+	// ProgressBar renders PercentComplete as text, e.g. "▓▓▓▓░░░░░░ 42%"
+	ProgressBar string
+
+	// OnThisDay is a historical fact about today's date, when enabled
+	OnThisDay string
+
+	// WrapUpDay and WrapUpDays number posts made after the countdown is over
+	WrapUpDay  int
+	WrapUpDays int
+
+	// Locale controls the number and spell template functions, and
+	// SpellNumbers spells out numbers in milestone labels
+	Locale       string
+	SpellNumbers bool
+}
+
+// milestoneNumber formats a number for a milestone label
+func (d PromptData) milestoneNumber(n int) string {
+	if d.SpellNumbers {
+		return numbers.Spell(n)
+	}
+	return numbers.NewFormatter(d.Locale).Format(n)
+}
+
+// defaultProgressBarWidth is the number of cells in a rendered progress bar
+const defaultProgressBarWidth = 10
+
+// progressBar renders a percentage as a unicode progress bar of width cells
+func progressBar(pct float64, width int) string {
+	if width <= 0 {
+		width = defaultProgressBarWidth
+	}
+	pct = math.Max(0, math.Min(100, pct))
+
+	filled := int(math.Round(pct / 100 * float64(width)))
+
+	return fmt.Sprintf("%s%s %.0f%%", strings.Repeat("▓", filled), strings.Repeat("░", width-filled), pct)
+}
+
+// templateFuncs returns the helper functions available to prompt templates,
+// formatting numbers for locale
+func templateFuncs(locale string) template.FuncMap {
+	formatter := numbers.NewFormatter(locale)
+
+	return template.FuncMap{
+		"progressBar": progressBar,
+		"number":      formatter.Format,
+		"spell":       numbers.Spell,
+	}
+}
+
+// PromptEvent represents the primary event as exposed to prompt templates
+type PromptEvent struct {
+	Name        string
+	Hashtag     string
+	PromptHints string
+}
+
+// newCountdown calculates the countdown variables for an event at now. Within
+// the final stretch the exact time remaining is included.
+func newCountdown(e *events.Event, cfg *events.File) Countdown {
+	c := Countdown{
+		Name:             e.Name,
+		DaysLeft:         daysUntil(e.Time()),
+		BusinessDaysLeft: businessDaysUntil(e.Time(), cfg),
+		TimeLeft:         countdown.BreakdownBetween(now, e.Time()).String(),
+		TargetDate:       e.Time().Format("January 2, 2006"),
+		TargetTime:       e.Time().Format("3:04 PM MST on January 2, 2006"),
+		CountsDown:       e.CountsDown(),
+		CountsUp:         e.CountsUp(),
+	}
+
+	if c.DaysLeft <= cfg.FinalStretch() && now.Before(e.Time()) {
+		c.FinalStretch = true
+		c.ExactTimeLeft = countdown.Precise(e.Time().Sub(now))
+	}
+
+	if start, ok := e.StartTime(); ok {
+		c.DaysSince = countdown.DaysBetween(start.In(now.Location()), now)
+		c.DayNumber = c.DaysSince + 1
+		if e.StartName != "" {
+			c.Since = "of " + e.StartName
+		} else {
+			c.Since = "since " + start.Format("January 2, 2006")
+		}
+	}
+
+	return c
+}
+
+// newPromptData builds the template variables for the nearest upcoming event,
+// with every other upcoming event in Others
+func newPromptData(ctx context.Context, cfg *events.File, upcoming []events.Event) PromptData {
+	primary := upcoming[0]
+
+	data := PromptData{
+		Countdown:    newCountdown(&primary, cfg),
+		Today:        now.Format("January 2, 2006"),
+		Locale:       cfg.Locale,
+		SpellNumbers: cfg.SpellMilestoneNumbers,
+		Event: PromptEvent{
+			Name:        primary.Name,
+			Hashtag:     primary.Hashtag,
+			PromptHints: primary.PromptHints,
+		},
+	}
+
+	data.PercentComplete, data.HasPercentComplete = primary.PercentComplete(now)
+	data.ShowPercentComplete = cfg.IncludePercentComplete && data.HasPercentComplete
+	if data.HasPercentComplete {
+		data.ProgressBar = progressBar(data.PercentComplete, cfg.ProgressBarWidth)
+	}
+
+	if cfg.OnThisDay {
+		data.OnThisDay = fetchOnThisDay(ctx)
+	}
+
+	for i := range upcoming[1:] {
+		data.Others = append(data.Others, newCountdown(&upcoming[i+1], cfg))
+	}
+
+	return data
+}
+
+// fetchOnThisDay returns a historical fact for today, or an empty string when
+// none is available so the post goes ahead without one
+func fetchOnThisDay(ctx context.Context) string {
+	ctx, cancel := httpRequest(ctx)
+	defer cancel()
+
+	facts, err := onthisday.Fetch(ctx, httpClient, now)
+	if err != nil {
+		logger(logGenerator).WarnContext(ctx, "Failed to get on this day facts", "err", err)
+		return ""
+	}
+
+	fact, ok := onthisday.Pick(facts)
+	if !ok {
+		return ""
+	}
+	return fact.String()
+}
+
+// renderPrompt executes a prompt template against the given data
+func renderPrompt(tmpl string, data PromptData) (string, error) {
+	t, err := template.New("prompt").Funcs(templateFuncs(data.Locale)).Parse(promptPartials)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt partials: %w", err)
+	}
+
+	t, err = t.Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+
+	return b.String(), nil
+}
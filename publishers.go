@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lukeocodes/go-trump/events"
+	"golang.org/x/time/rate"
+)
+
+// OutgoingPost represents a generated post, ready to publish to every destination
+type OutgoingPost struct {
+	Text     string
+	Image    string
+	ImageAlt string
+	ImageURL string
+
+	PostType    string
+	Date        string
+	Event       string
+	DaysLeft    int
+	GeneratedAt time.Time
+
+	// Links maps the destinations already published to, to their links, for
+	// destinations that report on the others
+	Links map[string]string
+}
+
+// linkReporter is implemented by destinations that report where the post was
+// published, so they are published to after the others
+type linkReporter interface {
+	ReportsLinks() bool
+}
+
+// reportsLinks reports whether a destination reports on the others
+func reportsLinks(pub Publisher) bool {
+	reporter, ok := unwrapPublisher(pub).(linkReporter)
+	return ok && reporter.ReportsLinks()
+}
+
+// credentialChecker is implemented by destinations that can check their
+// credentials without publishing
+type credentialChecker interface {
+	CheckCredentials(ctx context.Context) error
+}
+
+// unwrapPublisher returns the destination's own publisher, without throttling
+func unwrapPublisher(pub Publisher) Publisher {
+	if throttled, ok := pub.(*throttledPublisher); ok {
+		return throttled.Publisher
+	}
+	return pub
+}
+
+// Published represents where a destination published a post
+type Published struct {
+	// Link is the published post's URL or, on Bluesky, its AT URI, when the
+	// destination has one
+	Link string
+
+	// CID is the content hash of a Bluesky record, needed with its URI to
+	// reply to or quote it
+	CID string
+
+	// Latency is how long publishing took, which the caller notes
+	Latency time.Duration
+}
+
+// Publisher represents a destination that posts are published to. Adapt
+// fits a post to the destination, such as its length limit, and Publish
+// returns where the post was published.
+type Publisher interface {
+	Name() string
+	Adapt(ctx context.Context, post *OutgoingPost) (*OutgoingPost, error)
+	Publish(ctx context.Context, post *OutgoingPost) (Published, error)
+}
+
+// publisherFactory creates the publisher of a configured destination
+type publisherFactory func(destination *events.Destination) (Publisher, error)
+
+// destinationValidator checks the settings of a destination's own platform
+type destinationValidator func(destination *events.Destination) error
+
+// registeredPublisher represents a destination type's publisher, and the check
+// of its settings made when the events file is loaded
+type registeredPublisher struct {
+	validate destinationValidator
+	factory  publisherFactory
+}
+
+// destinationTypes maps destination types to their publishers
+var destinationTypes = map[string]registeredPublisher{}
+
+// registerPublisher makes a destination type available to the events file.
+// Each publisher registers itself from its own file, along with validate,
+// which checks its own settings so a mistake fails loading the events file
+// rather than the next post.
+func registerPublisher(destinationType string, validate destinationValidator, factory publisherFactory) {
+	if _, ok := destinationTypes[destinationType]; ok {
+		panic(fmt.Sprintf("publisher for destination type %q registered twice", destinationType))
+	}
+	destinationTypes[destinationType] = registeredPublisher{validate: validate, factory: factory}
+}
+
+// validateSettings returns the destinationValidator of a platform whose
+// settings are decoded and checked by settings
+func validateSettings[T any](settings func(destination *events.Destination) (T, error)) destinationValidator {
+	return func(destination *events.Destination) error {
+		_, err := settings(destination)
+		return err
+	}
+}
+
+// factoryOf returns the publisherFactory of a platform's constructor
+func factoryOf[P Publisher](newPublisher func(destination *events.Destination) (P, error)) publisherFactory {
+	return func(destination *events.Destination) (Publisher, error) {
+		pub, err := newPublisher(destination)
+		if err != nil {
+			return nil, err
+		}
+		return pub, nil
+	}
+}
+
+// validateDestinations checks each configured destination's own settings with
+// the publisher registered for its type
+func validateDestinations(cfg *events.File) error {
+	for i := range cfg.Destinations {
+		destination := &cfg.Destinations[i]
+		registered, ok := destinationTypes[destination.Type]
+		if !ok {
+			return fmt.Errorf("destination %q has unknown type %q", destination.Name, destination.Type)
+		}
+		if err := registered.validate(destination); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultDestination publishes to the Bluesky account in BLUESKY_USERNAME and
+// BLUESKY_PASSWORD, when the events file doesn't configure destinations
+var defaultDestination = events.Destination{Name: "bluesky", Type: destinationBluesky}
+
+// primaryDestinations returns the names of the destinations that must be
+// published to for a run to succeed
+func primaryDestinations(destinations []events.Destination) map[string]bool {
+	primary := map[string]bool{}
+	for _, d := range destinations {
+		if d.Primary {
+			primary[d.Name] = true
+		}
+	}
+	if len(primary) == 0 {
+		for _, d := range destinations {
+			if d.Type == destinationBluesky {
+				primary[d.Name] = true
+			}
+		}
+	}
+	if len(primary) == 0 && len(destinations) > 0 {
+		primary[destinations[0].Name] = true
+	}
+	return primary
+}
+
+// publishers returns a throttled publisher for every configured destination,
+// and the names of the primary ones. A secondary destination that can't be
+// set up is reported and left out, rather than stopping the others.
+func publishers(cfg *events.File) ([]Publisher, map[string]bool, error) {
+	destinations := cfg.Destinations
+	if len(destinations) == 0 {
+		destinations = []events.Destination{defaultDestination}
+	}
+	primary := primaryDestinations(destinations)
+
+	var pubs []Publisher
+	for i := range destinations {
+		destination := &destinations[i]
+
+		pub, err := newPublisher(destination)
+		if err != nil {
+			if primary[destination.Name] {
+				return nil, nil, failure(errConfig, err)
+			}
+			alertOperator("Destination left out", err.Error())
+			continue
+		}
+		pubs = append(pubs, pub)
+	}
+
+	return pubs, primary, nil
+}
+
+// newPublisher returns the throttled publisher of a destination
+func newPublisher(destination *events.Destination) (Publisher, error) {
+	registered, ok := destinationTypes[destination.Type]
+	if !ok {
+		return nil, fmt.Errorf("destination %q has unknown type %q", destination.Name, destination.Type)
+	}
+	pub, err := registered.factory(destination)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up destination %q: %w", destination.Name, err)
+	}
+
+	interval, err := destination.MinIntervalDuration()
+	if err != nil {
+		return nil, err
+	}
+	return &throttledPublisher{Publisher: pub, limiter: limiterFor(destination.Name, interval, destination.Burst)}, nil
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*rate.Limiter{}
+)
+
+// limiterFor returns the rate limiter of a destination, which lives as long as
+// the process so the daemon throttles across runs
+func limiterFor(name string, interval time.Duration, burst int) *rate.Limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	if burst < 1 {
+		burst = 1
+	}
+	limit := rate.Inf
+	if interval > 0 {
+		limit = rate.Every(interval)
+	}
+
+	limiter, ok := limiters[name]
+	if !ok {
+		limiter = rate.NewLimiter(limit, burst)
+		limiters[name] = limiter
+		return limiter
+	}
+	limiter.SetLimit(limit)
+	limiter.SetBurst(burst)
+	return limiter
+}
+
+// throttledPublisher waits for its destination's rate limiter before each post
+type throttledPublisher struct {
+	Publisher
+	limiter *rate.Limiter
+}
+
+func (p *throttledPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	if err := p.limiter.Wait(ctx); err != nil {
+		return Published{}, fmt.Errorf("throttled publishing to %s: %w", p.Name(), err)
+	}
+	return p.Publisher.Publish(ctx, post)
+}
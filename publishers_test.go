@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lukeocodes/go-trump/events"
+)
+
+func TestValidateDestinations(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination string
+		wantErr     bool
+	}{
+		{"bluesky", `{"name": "main", "type": "bluesky", "username_env": "MAIN_USERNAME"}`, false},
+		{"telegram", `{"name": "tg", "type": "telegram", "chat_id": "@mychannel"}`, false},
+		{"telegram without a chat", `{"name": "tg", "type": "telegram"}`, true},
+		{"email", `{"name": "news", "type": "email", "smtp_addr": "smtp.example.com:587", "from": "bot@example.com", "to": ["a@example.com"]}`, false},
+		{"email without subscribers", `{"name": "news", "type": "email", "smtp_addr": "smtp.example.com:587", "from": "bot@example.com"}`, true},
+		{"webhook without urls", `{"name": "hook", "type": "webhook"}`, true},
+		{"ntfy without a topic", `{"name": "push", "type": "ntfy"}`, true},
+		{"mqtt", `{"name": "display", "type": "mqtt", "broker": "tcp://localhost:1883", "qos": 1}`, false},
+		{"mqtt with an unsupported qos", `{"name": "display", "type": "mqtt", "broker": "tcp://localhost:1883", "qos": 2}`, true},
+		{"setting of the wrong kind", `{"name": "tg", "type": "telegram", "chat_id": 42}`, true},
+		{"unknown type", `{"name": "masto", "type": "mastodon"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg events.File
+			if err := json.Unmarshal([]byte(`{"destinations": [`+tt.destination+`]}`), &cfg); err != nil {
+				t.Fatalf("failed to decode destination: %v", err)
+			}
+			err := validateDestinations(&cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateDestinations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewPublisherSettings(t *testing.T) {
+	var destination events.Destination
+	data := `{"name": "display", "type": "mqtt", "broker": "tcp://localhost:1883", "retain": false, "min_interval": "1m"}`
+	if err := json.Unmarshal([]byte(data), &destination); err != nil {
+		t.Fatalf("failed to decode destination: %v", err)
+	}
+
+	pub, err := newMQTTPublisher(&destination)
+	if err != nil {
+		t.Fatalf("newMQTTPublisher() error = %v", err)
+	}
+	if pub.broker != "tcp://localhost:1883" || pub.topic != "go-trump/countdown" || pub.retain || pub.usernameEnv != "MQTT_USERNAME" {
+		t.Errorf("newMQTTPublisher() = %+v, want the broker, the default topic and credentials, and no retain", pub)
+	}
+	if destination.MinInterval != "1m" {
+		t.Errorf("MinInterval = %q, want the shared setting decoded alongside", destination.MinInterval)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy represents how often and how patiently a failed post is retried
+type RetryPolicy struct {
+	Attempts int
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// retryPolicy returns the configured retry policy
+func retryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts: conf.Retry.Attempts,
+		Delay:    conf.Retry.Delay,
+		MaxDelay: conf.Retry.MaxDelay,
+	}
+}
+
+// withRetry calls fn until it succeeds, fails permanently or runs out of
+// attempts, doubling the delay between attempts up to the policy's maximum
+func withRetry(ctx context.Context, fn func() error) error {
+	policy := retryPolicy()
+	delay := policy.Delay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) || attempt >= policy.Attempts {
+			return err
+		}
+
+		logger(logPublisher).WarnContext(ctx, "Attempt failed, retrying", "attempt", attempt, "attempts", policy.Attempts, "delay", delay, "err", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, policy.MaxDelay)
+	}
+}
+
+// isRetryable reports whether a failure is transient, like a network error or
+// a 5xx response, rather than permanent, like bad credentials or a post
+// blocked by the content policy
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// Failures from several destinations are worth retrying if any one is
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			if isRetryable(e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 ||
+			apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode == http.StatusRequestTimeout
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+)
+
+func TestIsRetryable(t *testing.T) {
+	netErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &APIError{StatusCode: http.StatusInternalServerError}, true},
+		{"bad gateway", &APIError{StatusCode: http.StatusBadGateway}, true},
+		{"rate limited", &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"request timeout", &APIError{StatusCode: http.StatusRequestTimeout}, true},
+		{"bad request", &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"unauthorized", &APIError{StatusCode: http.StatusUnauthorized}, false},
+		{"not found", &APIError{StatusCode: http.StatusNotFound}, false},
+		{"wrapped server error", fmt.Errorf("failed to post: %w", &APIError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"network error", netErr, true},
+		{"wrapped network error", fmt.Errorf("failed to post: %w", netErr), true},
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"canceled", context.Canceled, false},
+		{"canceled wrapping a network error", fmt.Errorf("%w: %w", context.Canceled, netErr), false},
+		{"plain error", errors.New("post blocked by the content policy"), false},
+		{"joined with one transient", errors.Join(&APIError{StatusCode: http.StatusUnauthorized}, &APIError{StatusCode: http.StatusBadGateway}), true},
+		{"joined all permanent", errors.Join(&APIError{StatusCode: http.StatusUnauthorized}, errors.New("too long")), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	saved := conf
+	t.Cleanup(func() { conf = saved })
+	conf = config.Default()
+	conf.Retry = config.Retry{Attempts: 3, Delay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	transient := &APIError{StatusCode: http.StatusBadGateway}
+	permanent := &APIError{StatusCode: http.StatusBadRequest}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{"succeeds first time", []error{nil}, nil, 1},
+		{"succeeds after a transient failure", []error{transient, nil}, nil, 2},
+		{"gives up after the attempts", []error{transient, transient, transient, nil}, transient, 3},
+		{"stops at a permanent failure", []error{transient, permanent, nil}, permanent, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := withRetry(context.Background(), func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if err != tt.wantErr {
+				t.Errorf("withRetry() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("withRetry() called fn %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lukeocodes/go-trump/events"
+	"github.com/lukeocodes/go-trump/state"
+	"github.com/lukeocodes/go-trump/store"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+)
+
+// run generates, checks and publishes a single post of postType for now. It
+// returns errCountdownOver when there is nothing left to post. Cancelling ctx
+// aborts any in-flight request up until the post is published, which is then
+// allowed to finish so it is recorded in the state. force posts even if the
+// post type was already posted today. Every run is recorded in the store,
+// whatever its outcome.
+func run(ctx context.Context, postType string, force bool) error {
+	rec := &store.Run{
+		PostType:  postType,
+		Date:      now.Format("2006-01-02"),
+		StartedAt: time.Now().UTC(),
+	}
+	runID := newRunID()
+	rec.CorrelationID = runID
+	ctx = withRunID(ctx, runID)
+	ctx = withLogAttrs(ctx, "post_type", postType)
+	ctx, span := startSpan(ctx, "run", attribute.String("run_id", runID), attribute.String("post_type", postType))
+	ctx = withSentryHub(ctx)
+	setSentryTags(ctx, map[string]string{"run_id": runID, "post_type": postType})
+	defer reportPanic(ctx)
+	// A dry run doesn't count as a run in the metrics or check in
+	if !conf.DryRun {
+		markRun(runID, postType)
+		checkIn(ctx, checkInStart, "")
+	}
+
+	stats := &runStats{}
+	err := runPost(ctx, postType, force, rec, stats)
+
+	// A dry run leaves nothing behind but its output and logs
+	if conf.DryRun {
+		endSpan(span, err)
+		return err
+	}
+	saveRun(ctx, rec, err)
+	writeSummary(ctx, runID, rec, stats)
+
+	// Skipping a post isn't a failure, nor is a run aborted on shutdown
+	switch {
+	case errors.Is(err, errSkipPost) || errors.Is(err, errCountdownOver):
+		span.SetAttributes(attribute.String("skipped", err.Error()))
+		endSpan(span, nil)
+		checkIn(ctx, "", err.Error())
+	case err != nil && ctx.Err() == nil:
+		reportError(ctx, err)
+		endSpan(span, err)
+		checkIn(ctx, checkInFail, err.Error())
+	case err != nil:
+		endSpan(span, err)
+	default:
+		endSpan(span, nil)
+		checkIn(ctx, "", "")
+	}
+	return err
+}
+
+// runOnce makes a one-shot run, as run does, alerting the operator when it
+// fails, then pushes its metrics
+func runOnce(ctx context.Context, postType string, force bool) error {
+	err := run(ctx, postType, force)
+	if err != nil && ctx.Err() == nil && !errors.Is(err, errSkipPost) && !errors.Is(err, errCountdownOver) {
+		alertOperator(fmt.Sprintf("The %s post failed", postType), err.Error())
+	}
+	pushMetrics(ctx)
+	return err
+}
+
+func runPost(ctx context.Context, postType string, force bool, rec *store.Run, stats *runStats) error {
+	// Load the events we count down to
+	cfg, err := loadEvents()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+
+	schedule, ok := cfg.ScheduleNamed(postType)
+	if !ok {
+		return failure(errConfig, fmt.Errorf("no schedule configured for post type %q", postType))
+	}
+
+	st, err := state.Load(stateFile())
+	if err != nil {
+		return err
+	}
+
+	// Day counts are calculated in the reference timezone
+	loc, err := cfg.Location()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	now = now.In(loc)
+	rec.Date = now.Format("2006-01-02")
+	ctx = withLogAttrs(ctx, "date", rec.Date)
+	setSentryTags(ctx, map[string]string{"date": rec.Date})
+
+	// Each post type posts at most once a day
+	if !force && st.PostedOn(schedule.Name, now) {
+		return fmt.Errorf("%s post already made today: %w", schedule.Name, errSkipPost)
+	}
+
+	// Stop early once there is nothing left to post
+	exitDate, hasExit, err := cfg.ExitDate()
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	if hasExit && now.After(exitDate) {
+		return fmt.Errorf("nothing left to post after %s: %w", exitDate.Format("January 2, 2006"), errCountdownOver)
+	}
+
+	// Skip days and quiet hours are recorded, so catch-up doesn't post later
+	if reason, skip := cfg.SkipReason(now); skip && !force {
+		if !conf.DryRun {
+			if err := st.MarkSkipped(schedule.Name, now, reason); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("not posting the %s post: %s: %w", schedule.Name, reason, errSkipPost)
+	}
+
+	pubs, primary, err := publishers(cfg)
+	if err != nil {
+		return err
+	}
+
+	// A dry run stops short of signing in anywhere, taking the lock or
+	// waiting for the final moment
+	if conf.DryRun {
+		post, err := generate(ctx, cfg, st, schedule, rec)
+		if err != nil {
+			return err
+		}
+		stats.noteGenerated(post)
+		fmt.Printf("Dry run: the %s post for %s, prompt %s, model %s, %d tokens\n",
+			schedule.Name, rec.Date, rec.PromptVariant, rec.Model, rec.PromptTokens+rec.CompletionTokens)
+		adapted, err := printPosts(ctx, pubs, post)
+		for name := range primary {
+			if !adapted[name] {
+				return failure(errPublish, err)
+			}
+		}
+		return nil
+	}
+
+	// Only one instance makes each post when the bot runs redundantly. The
+	// lock is kept once the post is made, and released if it isn't.
+	release := func() {}
+	if !force {
+		release, err = acquirePostLock(ctx, schedule.Name, now)
+		if err != nil {
+			return err
+		}
+	}
+	posted := false
+	defer func() {
+		if !posted {
+			release()
+		}
+	}()
+
+	// The store is checked too, once the lock is held, so reruns on another
+	// host or with a fresh state file don't post twice either
+	if !force && postedInStore(ctx, schedule.Name, rec.Date) {
+		return fmt.Errorf("%s post already made today, according to the store: %w", schedule.Name, errSkipPost)
+	}
+
+	// Hold the final post until the exact moment the countdown ends
+	waitForFinalMoment(ctx, cfg)
+
+	// Generate the post once and publish it everywhere, retrying transient
+	// failures without posting twice to a destination it already reached
+	var post *OutgoingPost
+	published := map[string]Published{}
+	err = withRetry(ctx, func() error {
+		if post == nil {
+			start := time.Now()
+			post, err = generate(ctx, cfg, st, schedule, rec)
+			stats.generation += time.Since(start)
+			if err != nil {
+				return err
+			}
+			stats.noteGenerated(post)
+		}
+		start := time.Now()
+		defer func() { stats.publishing += time.Since(start) }()
+		return publishAll(ctx, pubs, post, published)
+	})
+	if post != nil {
+		rec.Results = publishResults(pubs, published, err)
+		logPublishResults(ctx, schedule.Name, rec.Results)
+	}
+	if err != nil {
+		// Failures are only fatal when a primary destination missed the post
+		if post == nil {
+			return err
+		}
+		if !publishedToAll(primary, published) {
+			return failure(errPublish, err)
+		}
+		alertOperator(fmt.Sprintf("The %s post missed some destinations", schedule.Name), err.Error())
+		reportError(ctx, err)
+	}
+	posted = true
+
+	// Remember today's post so it isn't repeated by a catch-up run
+	if err := st.MarkPosted(schedule.Name, now, post.Text); err != nil {
+		return err
+	}
+	writeFeed(st)
+	refreshArchive(cfg, st)
+	return nil
+}
+
+// printPosts prints post as each destination would publish it, returning the
+// names of those it was adapted for, and the others as PublishErrors
+func printPosts(ctx context.Context, pubs []Publisher, post *OutgoingPost) (map[string]bool, error) {
+	adapted := map[string]bool{}
+	var errs PublishErrors
+	for _, pub := range pubs {
+		out, err := pub.Adapt(ctx, post)
+		if err != nil {
+			fmt.Printf("\n%s: failed to adapt: %v\n", pub.Name(), err)
+			errs = append(errs, &PublishError{Destination: pub.Name(), Err: err})
+			continue
+		}
+		adapted[pub.Name()] = true
+		fmt.Printf("\n%s, %d graphemes:\n%s\n", pub.Name(), graphemeCount(out.Text), out.Text)
+		if out.Image != "" {
+			fmt.Printf("[image %s: %s]\n", out.Image, out.ImageAlt)
+		}
+	}
+	if len(errs) > 0 {
+		return adapted, errs
+	}
+	return adapted, nil
+}
+
+// generate writes a post of the schedule's type and checks it against the
+// content policy, noting what was generated in the run record
+func generate(ctx context.Context, cfg *events.File, st *state.State, schedule *events.Schedule, rec *store.Run) (_ *OutgoingPost, err error) {
+	ctx, span := startSpan(ctx, "generate")
+	defer func() { endSpan(span, err) }()
+
+	policy := duplicatePolicy()
+	recent := recentPosts(ctx, st, policy.Window)
+
+	// Get the post we will send, or decide what to do once the countdown is
+	// over, regenerating it while it's too close to a recent post
+	var completion *Completion
+	var milestone *events.Milestone
+	upcoming := events.Upcoming(cfg.Events, now)
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		completion, milestone, err = writePost(ctx, cfg, upcoming, schedule)
+		if err != nil {
+			return nil, failure(errGeneration, err)
+		}
+		timeGeneration(ctx, schedule.Name, start)
+		setSentryTags(ctx, map[string]string{"provider": conf.Generator, "model": completion.Model})
+		logger(logGenerator).InfoContext(ctx, "Generated post", "text", completion.Text, "model", completion.Model, "variant", completion.Variant)
+		rec.PromptTokens += completion.PromptTokens
+		rec.CompletionTokens += completion.CompletionTokens
+
+		similar, score := mostSimilar(completion.Text, recent)
+		if score < policy.Threshold {
+			break
+		}
+		log := logger(logGenerator).With("similarity", score, "similar_post_type", similar.PostType, "similar_date", similar.Date)
+		if attempt > policy.Regenerations {
+			log.WarnContext(ctx, "Posting anyway, too similar to a recent post", "attempts", attempt)
+			break
+		}
+		log.InfoContext(ctx, "Regenerating, too similar to a recent post")
+	}
+	text := completion.Text
+
+	rec.Text = text
+	rec.PromptVariant = completion.Variant
+	rec.Model = completion.Model
+	rec.SystemPrompt = completion.System
+	rec.Prompt = completion.Prompt
+	rec.RawResponse = completion.Raw
+	rec.GenerationLatency = completion.Latency
+
+	// Second-pass content-policy check before anything is published. A dry
+	// run prints why a post would be blocked rather than alerting anyone.
+	moderation, err := moderatePost(ctx, text)
+	if err != nil {
+		if conf.DryRun {
+			fmt.Printf("Dry run: the content-policy check of the %s post failed: %v\n\n%s\n", schedule.Name, err, text)
+		} else {
+			alertOperator("Content-policy check failed", err.Error())
+		}
+		return nil, fmt.Errorf("content-policy check failed: %w", err)
+	}
+	if moderation.Flagged {
+		if conf.DryRun {
+			fmt.Printf("Dry run: the %s post would be blocked by the content policy, %s:\n\n%s\n", schedule.Name, blockedReasons(moderation), text)
+		} else {
+			alertOperator("Post blocked by content policy", fmt.Sprintf("%s\n\n%s", blockedReasons(moderation), text))
+		}
+		return nil, failure(errBlocked, fmt.Errorf("post blocked by content policy: %s", blockedReasons(moderation)))
+	}
+
+	post := outgoingPost(schedule, upcoming, milestone, text)
+	if len(upcoming) > 0 {
+		span.SetAttributes(attribute.String("event", post.Event), attribute.Int("day_count", post.DaysLeft))
+		setSentryTags(ctx, map[string]string{"event": post.Event, "day_count": strconv.Itoa(post.DaysLeft)})
+		logger(logGenerator).InfoContext(ctx, "Post passed the content policy", "event", post.Event, "day_count", post.DaysLeft)
+	}
+	return post, nil
+}
+
+// writePost has the model write a post of the schedule's type about the
+// upcoming events, or about the countdown being over when there are none
+func writePost(ctx context.Context, cfg *events.File, upcoming []events.Event, schedule *events.Schedule) (*Completion, *events.Milestone, error) {
+	if len(upcoming) > 0 {
+		return getPost(ctx, cfg, upcoming, schedule)
+	}
+	return getCompletionPost(ctx, cfg, schedule)
+}
+
+// outgoingPost returns the post to publish with the generated text, carrying
+// the milestone's image if there is one
+func outgoingPost(schedule *events.Schedule, upcoming []events.Event, milestone *events.Milestone, text string) *OutgoingPost {
+	post := &OutgoingPost{
+		Text:        text,
+		PostType:    schedule.Name,
+		Date:        now.Format("2006-01-02"),
+		GeneratedAt: time.Now().UTC(),
+	}
+	if len(upcoming) > 0 {
+		post.Event = upcoming[0].Name
+		post.DaysLeft = daysUntil(upcoming[0].Time())
+	}
+	if milestone != nil {
+		post.Image = milestone.Image
+		post.ImageAlt = milestone.ImageAlt
+		post.ImageURL = milestone.ImageURL
+	}
+	return post
+}
+
+// publishAll publishes the post to every destination not already in
+// published, a few at a time, adding those it publishes to with their links.
+// Every destination is attempted under a shared deadline, and the failures
+// are returned together as PublishErrors. Destinations that report on the
+// others, like webhooks, go last.
+func publishAll(ctx context.Context, pubs []Publisher, post *OutgoingPost, published map[string]Published) (err error) {
+	workers := conf.PublishConcurrency
+
+	ctx, span := startSpan(ctx, "publish-fanout", attribute.Int("destinations", len(pubs)))
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := publishDeadline(ctx)
+	defer cancel()
+
+	var first, last []Publisher
+	for _, pub := range pubs {
+		if reportsLinks(pub) {
+			last = append(last, pub)
+		} else {
+			first = append(first, pub)
+		}
+	}
+
+	errs := publishConcurrently(ctx, workers, first, post, published)
+
+	// Give the later destinations a copy of the post with the links so far
+	withLinks := *post
+	withLinks.Links = map[string]string{}
+	for name, p := range published {
+		if p.Link != "" {
+			withLinks.Links[name] = p.Link
+		}
+	}
+	errs = append(errs, publishConcurrently(ctx, workers, last, &withLinks, published)...)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// publishConcurrently publishes the post to every destination not already in
+// published, workers at a time
+func publishConcurrently(ctx context.Context, workers int, pubs []Publisher, post *OutgoingPost, published map[string]Published) PublishErrors {
+	var pending []Publisher
+	for _, pub := range pubs {
+		if _, ok := published[pub.Name()]; !ok {
+			pending = append(pending, pub)
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		errs PublishErrors
+	)
+	var g errgroup.Group
+	g.SetLimit(workers)
+
+	for _, pub := range pending {
+		g.Go(func() error {
+			ctx, span := startSpan(ctx, "publish", attribute.String("destination", pub.Name()))
+			adapted, err := pub.Adapt(ctx, post)
+			var p Published
+			if err == nil {
+				start := time.Now()
+				p, err = pub.Publish(ctx, adapted)
+				p.Latency = time.Since(start)
+			}
+			endSpan(span, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, &PublishError{Destination: pub.Name(), Err: err})
+			} else {
+				published[pub.Name()] = p
+				timePublish(ctx, pub.Name(), p.Latency)
+			}
+			// Failures are collected rather than returned, so one doesn't
+			// stop the others
+			return nil
+		})
+	}
+	g.Wait()
+
+	return errs
+}
+
+// PublishError represents a failure to publish to one destination
+type PublishError struct {
+	Destination string
+	Err         error
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("failed to publish to %s: %v", e.Destination, e.Err)
+}
+
+func (e *PublishError) Unwrap() error {
+	return e.Err
+}
+
+// PublishErrors represents the failures of a post at each destination it
+// missed
+type PublishErrors []*PublishError
+
+func (e PublishErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e PublishErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// publishResults lists how publishing went at every destination, for the store
+func publishResults(pubs []Publisher, published map[string]Published, err error) []store.Result {
+	failures := map[string]error{}
+	var publishErrs PublishErrors
+	if errors.As(err, &publishErrs) {
+		for _, failure := range publishErrs {
+			failures[failure.Destination] = failure.Err
+		}
+	}
+
+	var results []store.Result
+	for _, pub := range pubs {
+		result := store.Result{Destination: pub.Name()}
+		if p, ok := published[pub.Name()]; ok {
+			result.Link, result.CID, result.Latency = p.Link, p.CID, p.Latency
+		} else if failure, ok := failures[pub.Name()]; ok {
+			result.Error = failure.Error()
+		} else {
+			result.Error = "not attempted"
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// logPublishResults summarises how publishing went at every destination, and
+// counts it
+func logPublishResults(ctx context.Context, postType string, results []store.Result) {
+	log := logger(logPublisher)
+	var succeeded, failed []string
+	for _, result := range results {
+		if result.Published() {
+			countPost(ctx, result.Destination, "published")
+			succeeded = append(succeeded, result.Destination)
+			log.InfoContext(ctx, "Published", "destination", result.Destination, "post_uri", result.Link)
+		} else {
+			countPost(ctx, result.Destination, "failed")
+			failed = append(failed, result.Destination)
+			log.ErrorContext(ctx, "Failed to publish", "destination", result.Destination, "err", result.Error)
+		}
+	}
+
+	log.InfoContext(ctx, fmt.Sprintf("The %s post reached %d of %d destinations", postType, len(succeeded), len(results)),
+		"published", strings.Join(succeeded, ","), "missed", strings.Join(failed, ","))
+}
+
+// publishedToAll reports whether every one of the named destinations has the post
+func publishedToAll(names map[string]bool, published map[string]Published) bool {
+	for name := range names {
+		if _, ok := published[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// oneShotPostType returns the post type to make in one-shot mode, post_type
+// (default daily)
+func oneShotPostType() string {
+	return conf.PostType
+}
+
+// stateFile returns the path of the state file, state_file (default state.json)
+func stateFile() string {
+	return conf.StateFile
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/state"
+)
+
+func TestRunWithMockGenerator(t *testing.T) {
+	pds := withPDS(t)
+	t.Setenv("BLUESKY_USERNAME", pds.Handle)
+	t.Setenv("BLUESKY_PASSWORD", pds.Password)
+	t.Setenv("OPENAI_API_KEY", "")
+
+	dir := t.TempDir()
+	eventsFile := filepath.Join(dir, "events.json")
+	err := os.WriteFile(eventsFile, []byte(`{"events": [{"name": "the end of the term", "target": "2029-01-20T12:00:00", "hashtag": "#Countdown"}]}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, savedNow := conf, now
+	t.Cleanup(func() { conf, now = saved, savedNow })
+	conf = config.Default()
+	conf.Generator = generatorMock
+	conf.MockText = "{{.DaysLeft}} days until {{.Event.Name}}. {{.Event.Hashtag}}"
+	conf.EventsFile = eventsFile
+	conf.StateFile = filepath.Join(dir, "state.json")
+	conf.Store.Backend = "none"
+	now = time.Date(2027, time.January, 20, 9, 0, 0, 0, time.UTC)
+
+	if err := run(context.Background(), "daily", false); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	records := pds.Records()
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	text, _ := records[0].Value["text"].(string)
+	if text != "731 days until the end of the term. #Countdown" {
+		t.Errorf("posted %q, want the mock_text rendered for the day", text)
+	}
+
+	st, err := state.Load(conf.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.PostedOn("daily", now) {
+		t.Errorf("the post wasn't recorded in the state file")
+	}
+}
@@ -0,0 +1,164 @@
+// Package scheduler works out when the daemon's posts are due from cron
+// expressions evaluated in a timezone.
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// lookback is how far back Prev searches for a schedule's last fire time
+const lookback = 366 * 24 * time.Hour
+
+// parser accepts standard five field cron expressions and descriptors like @daily
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Entry represents a named schedule, one per post type
+type Entry struct {
+	Name     string
+	Expr     string
+	Location *time.Location
+
+	// Jitter randomly moves each post up to this much earlier or later, so
+	// the bot doesn't post at exactly the same second every day
+	Jitter time.Duration
+
+	// MaxLateness is how long after a missed fire time it may still be caught
+	// up; zero means no limit
+	MaxLateness time.Duration
+
+	schedule cron.Schedule
+}
+
+// Parse parses a cron expression evaluated in loc
+func Parse(name, expr string, loc *time.Location) (*Entry, error) {
+	schedule, err := parser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %q has invalid cron expression %q: %w", name, expr, err)
+	}
+
+	if spec, ok := schedule.(*cron.SpecSchedule); ok {
+		spec.Location = loc
+	}
+
+	// An expression like 0 0 30 2 * is valid but never fires, which would
+	// leave the daemon nothing to wait for
+	e := &Entry{
+		Name:     name,
+		Expr:     expr,
+		Location: loc,
+		schedule: schedule,
+	}
+	if e.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q has cron expression %q, which never fires", name, expr)
+	}
+	return e, nil
+}
+
+// Next returns the first fire time after t
+func (e *Entry) Next(t time.Time) time.Time {
+	return e.schedule.Next(t.In(e.Location))
+}
+
+// Prev returns the most recent fire time at or before t, and false if the
+// schedule hasn't fired in the last year
+func (e *Entry) Prev(t time.Time) (time.Time, bool) {
+	var prev time.Time
+	for next := e.Next(t.Add(-lookback)); !next.After(t); next = e.Next(next) {
+		prev = next
+	}
+	return prev, !prev.IsZero()
+}
+
+// Due represents an entry's next post. Slot is when the schedule fires, and At
+// is when the post should actually be made, after jitter.
+type Due struct {
+	Entry *Entry
+	Slot  time.Time
+	At    time.Time
+}
+
+// Planner tracks the next due post of each entry. Jitter is drawn once per
+// slot, so a post made early for a slot is never made again for the same slot.
+type Planner struct {
+	due map[*Entry]Due
+}
+
+// NewPlanner plans the first post after t for each entry
+func NewPlanner(entries []*Entry, t time.Time) *Planner {
+	p := &Planner{due: make(map[*Entry]Due, len(entries))}
+	for _, e := range entries {
+		p.plan(e, t)
+	}
+	return p
+}
+
+// plan schedules an entry's first slot after t
+func (p *Planner) plan(e *Entry, t time.Time) {
+	slot := e.Next(t)
+	p.due[e] = Due{Entry: e, Slot: slot, At: jittered(slot, e.Jitter)}
+}
+
+// Next returns the post due soonest
+func (p *Planner) Next() Due {
+	var next Due
+	for _, d := range p.due {
+		if next.Entry == nil || d.At.Before(next.At) {
+			next = d
+		}
+	}
+	return next
+}
+
+// Done marks a due post as made and plans the entry's following slot
+func (p *Planner) Done(d Due) {
+	p.plan(d.Entry, d.Slot)
+}
+
+// Update replaces the planned entries with entries, such as after a reload.
+// An entry that's unchanged, with the same name, expression, timezone and
+// jitter, keeps its planned post, so its jitter isn't drawn again; the others
+// are planned afresh after t.
+func (p *Planner) Update(entries []*Entry, t time.Time) {
+	due := make(map[*Entry]Due, len(entries))
+	for _, e := range entries {
+		for old, d := range p.due {
+			if old.Name == e.Name && old.Expr == e.Expr && old.Location.String() == e.Location.String() && old.Jitter == e.Jitter {
+				d.Entry = e
+				due[e] = d
+				break
+			}
+		}
+	}
+
+	p.due = due
+	for _, e := range entries {
+		if _, ok := p.due[e]; !ok {
+			p.plan(e, t)
+		}
+	}
+}
+
+// jittered offsets t by a random duration within ±jitter, kept within t's
+// calendar day in its location, so a slot just after midnight isn't moved
+// into the day before, where it would be taken for that day's post
+func jittered(t time.Time, jitter time.Duration) time.Time {
+	if jitter <= 0 {
+		return t
+	}
+	offset := time.Duration(rand.Int63n(int64(2*jitter)+1)) - jitter
+
+	at := t.Add(offset)
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	end := start.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	switch {
+	case at.Before(start):
+		return start
+	case at.After(end):
+		return end
+	}
+	return at
+}
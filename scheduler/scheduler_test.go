@@ -0,0 +1,303 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func mustParse(t *testing.T, name, expr string, loc *time.Location) *Entry {
+	t.Helper()
+	e, err := Parse(name, expr, loc)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", expr, err)
+	}
+	return e
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"0 9 * * *", false},
+		{"30 8 * * 1-5", false},
+		{"0 9,18 * * *", false},
+		{"*/15 * * * *", false},
+		{"@daily", false},
+		{"@weekly", false},
+		{"0 0 9 * * *", true},
+		{"0 9 * *", true},
+		{"60 9 * * *", true},
+		{"0 24 * * *", true},
+		{"@fortnightly", true},
+		{"", true},
+		{"0 0 30 2 *", true},
+		{"0 0 31 4 *", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse("daily", tt.expr, time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		expr string
+		loc  *time.Location
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "later today",
+			expr: "0 9 * * *",
+			loc:  time.UTC,
+			from: time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC),
+			want: time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "at the fire time is the next day",
+			expr: "0 9 * * *",
+			loc:  time.UTC,
+			from: time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC),
+			want: time.Date(2026, time.October, 15, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "evaluated in the entry's timezone",
+			expr: "0 9 * * *",
+			loc:  ny,
+			from: time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2026, time.October, 14, 9, 0, 0, 0, ny),
+		},
+		{
+			name: "weekdays skip the weekend",
+			expr: "30 8 * * 1-5",
+			loc:  time.UTC,
+			from: time.Date(2026, time.October, 16, 9, 0, 0, 0, time.UTC),
+			want: time.Date(2026, time.October, 19, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "across spring forward",
+			expr: "0 9 * * *",
+			loc:  ny,
+			from: time.Date(2026, time.March, 7, 10, 0, 0, 0, ny),
+			want: time.Date(2026, time.March, 8, 9, 0, 0, 0, ny),
+		},
+		{
+			name: "descriptor",
+			expr: "@daily",
+			loc:  ny,
+			from: time.Date(2026, time.October, 14, 12, 0, 0, 0, ny),
+			want: time.Date(2026, time.October, 15, 0, 0, 0, 0, ny),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := mustParse(t, "daily", tt.expr, tt.loc)
+			if got := e.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrev(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		at     time.Time
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "earlier today",
+			expr:   "0 9 * * *",
+			at:     time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "at the fire time",
+			expr:   "0 9 * * *",
+			at:     time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "yesterday",
+			expr:   "0 9 * * *",
+			at:     time.Date(2026, time.October, 14, 8, 59, 0, 0, time.UTC),
+			want:   time.Date(2026, time.October, 13, 9, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "yearly within the lookback",
+			expr:   "0 12 20 1 *",
+			at:     time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, time.January, 20, 12, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "yearly a year ago to the day",
+			expr:   "0 0 14 10 *",
+			at:     time.Date(2026, time.October, 13, 0, 0, 0, 0, time.UTC),
+			want:   time.Date(2025, time.October, 14, 0, 0, 0, 0, time.UTC),
+			wantOK: true,
+		},
+		{
+			name:   "leap day beyond the lookback",
+			expr:   "0 9 29 2 *",
+			at:     time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC),
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := mustParse(t, "daily", tt.expr, time.UTC)
+			got, ok := e.Prev(tt.at)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("Prev(%v) = %v, %v, want %v, %v", tt.at, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPlanner(t *testing.T) {
+	start := time.Date(2026, time.October, 14, 7, 0, 0, 0, time.UTC)
+	morning := mustParse(t, "morning", "0 8 * * *", time.UTC)
+	evening := mustParse(t, "evening", "0 20 * * *", time.UTC)
+	p := NewPlanner([]*Entry{evening, morning}, start)
+
+	steps := []struct {
+		entry *Entry
+		slot  time.Time
+	}{
+		{morning, time.Date(2026, time.October, 14, 8, 0, 0, 0, time.UTC)},
+		{evening, time.Date(2026, time.October, 14, 20, 0, 0, 0, time.UTC)},
+		{morning, time.Date(2026, time.October, 15, 8, 0, 0, 0, time.UTC)},
+		{evening, time.Date(2026, time.October, 15, 20, 0, 0, 0, time.UTC)},
+	}
+	for i, step := range steps {
+		due := p.Next()
+		if due.Entry != step.entry || !due.Slot.Equal(step.slot) || !due.At.Equal(step.slot) {
+			t.Fatalf("step %d: Next() = %s at %v, want %s at %v", i, due.Entry.Name, due.Slot, step.entry.Name, step.slot)
+		}
+		p.Done(due)
+	}
+}
+
+func TestPlannerUpdate(t *testing.T) {
+	london := mustLoad(t, "Europe/London")
+	start := time.Date(2026, time.October, 14, 7, 0, 0, 0, time.UTC)
+	reload := time.Date(2026, time.October, 14, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		reloaded Entry
+		keep     bool
+		want     time.Time
+	}{
+		{
+			name:     "unchanged keeps its planned post",
+			reloaded: Entry{Name: "daily", Expr: "0 8 * * *", Location: time.UTC, Jitter: time.Hour},
+			keep:     true,
+		},
+		{
+			name:     "new expression",
+			reloaded: Entry{Name: "daily", Expr: "0 9 * * *", Location: time.UTC},
+			want:     time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "new timezone",
+			reloaded: Entry{Name: "daily", Expr: "0 8 * * *", Location: london},
+			want:     time.Date(2026, time.October, 15, 8, 0, 0, 0, london),
+		},
+		{
+			name:     "new jitter",
+			reloaded: Entry{Name: "daily", Expr: "0 8 * * *", Location: time.UTC},
+			want:     time.Date(2026, time.October, 15, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "renamed",
+			reloaded: Entry{Name: "morning", Expr: "0 8 * * *", Location: time.UTC},
+			want:     time.Date(2026, time.October, 15, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := mustParse(t, "daily", "0 8 * * *", time.UTC)
+			old.Jitter = time.Hour
+			p := NewPlanner([]*Entry{old}, start)
+			planned := p.Next()
+
+			e := mustParse(t, tt.reloaded.Name, tt.reloaded.Expr, tt.reloaded.Location)
+			e.Jitter = tt.reloaded.Jitter
+			p.Update([]*Entry{e}, reload)
+
+			got := p.Next()
+			if got.Entry != e {
+				t.Fatalf("Next() after Update() is for %s, want the reloaded entry", got.Entry.Name)
+			}
+			if tt.keep {
+				if !got.Slot.Equal(planned.Slot) || !got.At.Equal(planned.At) {
+					t.Errorf("Next() after Update() = %v at %v, want the planned %v at %v", got.Slot, got.At, planned.Slot, planned.At)
+				}
+				return
+			}
+			if !got.Slot.Equal(tt.want) || !got.At.Equal(tt.want) {
+				t.Errorf("Next() after Update() = %v at %v, want %v", got.Slot, got.At, tt.want)
+			}
+		})
+	}
+}
+
+func TestJittered(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name   string
+		slot   time.Time
+		jitter time.Duration
+	}{
+		{"none", time.Date(2026, time.October, 14, 9, 0, 0, 0, time.UTC), 0},
+		{"midday", time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC), 30 * time.Minute},
+		{"just after midnight", time.Date(2026, time.October, 14, 0, 5, 0, 0, time.UTC), time.Hour},
+		{"just before midnight", time.Date(2026, time.October, 14, 23, 55, 0, 0, time.UTC), time.Hour},
+		{"just after midnight in New York", time.Date(2026, time.October, 14, 0, 5, 0, 0, ny), time.Hour},
+		{"more than a day", time.Date(2026, time.October, 14, 12, 0, 0, 0, time.UTC), 36 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := tt.slot.Format(time.DateOnly)
+			for range 1000 {
+				got := jittered(tt.slot, tt.jitter)
+				if d := got.Sub(tt.slot).Abs(); d > tt.jitter {
+					t.Fatalf("jittered(%v, %v) = %v, %v away", tt.slot, tt.jitter, got, d)
+				}
+				if got.In(tt.slot.Location()).Format(time.DateOnly) != day {
+					t.Fatalf("jittered(%v, %v) = %v, off the slot's day", tt.slot, tt.jitter, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/secrets"
+)
+
+// ssmSecrets are the environment variables that can also name an SSM
+// parameter in <NAME>_SSM_PARAMETER, as they could before secret references
+var ssmSecrets = []string{"BLUESKY_USERNAME", "BLUESKY_PASSWORD", "OPENAI_API_KEY"}
+
+// secretSources maps the schemes of secret references to their sources
+var secretSources = map[string]secrets.Opener{
+	"secretsmanager": secrets.OpenSecretsManager,
+	"ssm":            secrets.OpenSSM,
+	"vault":          openVault,
+	"keychain":       secrets.OpenKeychain,
+	"op":             secrets.OpenOnePassword,
+}
+
+// openVault connects to Vault with the VAULT_ environment variables its own
+// CLI reads, signing in with AppRole when there's no VAULT_TOKEN
+func openVault(ctx context.Context) (secrets.Source, error) {
+	return secrets.NewVault(ctx, secrets.VaultConfig{
+		Addr:         config.Getenv("VAULT_ADDR"),
+		Namespace:    config.Getenv("VAULT_NAMESPACE"),
+		Token:        config.Getenv("VAULT_TOKEN"),
+		RoleID:       config.Getenv("VAULT_ROLE_ID"),
+		SecretID:     config.Getenv("VAULT_SECRET_ID"),
+		AppRoleMount: config.Getenv("VAULT_APPROLE_MOUNT"),
+	})
+}
+
+// resolveSecrets replaces environment variables that reference a secret,
+// such as BLUESKY_PASSWORD=arn:aws:secretsmanager:..., with the secret, once
+// at startup. Secret files are read first, so they can hold references, or
+// the credentials of a secret store.
+func resolveSecrets(ctx context.Context) error {
+	if err := readSecretFiles(); err != nil {
+		return err
+	}
+
+	for _, name := range ssmSecrets {
+		if parameter := config.Getenv(name + "_SSM_PARAMETER"); parameter != "" {
+			os.Setenv(name, "ssm:"+parameter)
+		}
+	}
+
+	names, err := secrets.NewResolver(secretSources).ResolveEnv(ctx)
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		logger(logSecrets).InfoContext(ctx, "Resolved secrets", "names", strings.Join(names, ","))
+	}
+	return nil
+}
+
+// readSecretFiles sets each variable whose <NAME>_FILE names a file, such as
+// a Docker or Kubernetes secret mounted as BLUESKY_PASSWORD_FILE, to the
+// file's contents. Settings that are themselves files, such as STATE_FILE,
+// are left alone.
+func readSecretFiles() error {
+	settings := map[string]bool{"CONFIG_FILE": true, "PROFILE": true}
+	for _, v := range config.Vars() {
+		settings[v.Name] = true
+	}
+
+	for _, entry := range os.Environ() {
+		name, path, _ := strings.Cut(entry, "=")
+		secret, ok := strings.CutSuffix(name, "_FILE")
+		if !ok || secret == "" || path == "" || settings[config.TrimPrefix(name)] {
+			continue
+		}
+		if value := os.Getenv(secret); value != "" {
+			return fmt.Errorf("both %s and %s are set, set only one", secret, name)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		// Files usually end in a newline that isn't part of the secret
+		os.Setenv(secret, strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
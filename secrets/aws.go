@@ -0,0 +1,64 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// SecretsManager represents AWS Secrets Manager. A reference is a secret's
+// name or ARN, and the secret's current version is fetched.
+type SecretsManager struct {
+	client *secretsmanager.Client
+}
+
+// OpenSecretsManager connects to Secrets Manager with the default AWS config
+func OpenSecretsManager(ctx context.Context) (Source, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &SecretsManager{client: secretsmanager.NewFromConfig(cfg)}, nil
+}
+
+func (s *SecretsManager) Fetch(ctx context.Context, ref string) (string, error) {
+	out, err := s.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(ref)})
+	if err != nil {
+		return "", err
+	}
+	if out.SecretString != nil {
+		return *out.SecretString, nil
+	}
+	return string(out.SecretBinary), nil
+}
+
+// SSM represents AWS Systems Manager Parameter Store. A reference is a
+// parameter's name, such as /go-trump/bluesky-password, or its ARN, and
+// SecureStrings are decrypted.
+type SSM struct {
+	client *ssm.Client
+}
+
+// OpenSSM connects to Parameter Store with the default AWS config
+func OpenSSM(ctx context.Context) (Source, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &SSM{client: ssm.NewFromConfig(cfg)}, nil
+}
+
+func (s *SSM) Fetch(ctx context.Context, ref string) (string, error) {
+	out, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(ref),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(out.Parameter.Value), nil
+}
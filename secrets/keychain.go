@@ -0,0 +1,28 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Keychain represents the OS keychain: the macOS Keychain, the Secret
+// Service on Linux, such as GNOME Keyring, or the Windows Credential Manager.
+// A reference is <service>/<account>, such as go-trump/bluesky-password, and
+// the secret is the password stored for them.
+type Keychain struct{}
+
+// OpenKeychain returns the keychain of the user running the bot
+func OpenKeychain(ctx context.Context) (Source, error) {
+	return Keychain{}, nil
+}
+
+func (Keychain) Fetch(ctx context.Context, ref string) (string, error) {
+	i := strings.LastIndex(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return "", fmt.Errorf("%q is not <service>/<account>", ref)
+	}
+	return keyring.Get(ref[:i], ref[i+1:])
+}
@@ -0,0 +1,39 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// OnePassword represents 1Password, read through its op CLI, which must be
+// signed in or have OP_SERVICE_ACCOUNT_TOKEN set. A reference is a 1Password
+// secret reference, op://<vault>/<item>/<field>.
+type OnePassword struct {
+	path string
+}
+
+// OpenOnePassword finds the op CLI on the PATH
+func OpenOnePassword(ctx context.Context) (Source, error) {
+	path, err := exec.LookPath("op")
+	if err != nil {
+		return nil, errors.New("the 1Password CLI, op, is not installed")
+	}
+	return &OnePassword{path: path}, nil
+}
+
+func (o *OnePassword) Fetch(ctx context.Context, ref string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, o.path, "read", "--no-newline", "op:"+ref)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,121 @@
+// Package secrets resolves references to secrets kept in a secret store, or
+// a local keychain or password manager, so credentials such as
+// BLUESKY_PASSWORD needn't live in plaintext env files
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Source represents a secret store
+type Source interface {
+	// Fetch returns the secret that ref names within the store
+	Fetch(ctx context.Context, ref string) (string, error)
+}
+
+// Opener connects to a source, the first time one of its secrets is needed
+type Opener func(ctx context.Context) (Source, error)
+
+// Resolver resolves secret references, of the form <scheme>:<ref>, against
+// the source registered for their scheme. AWS ARNs are references too, to
+// Secrets Manager or SSM Parameter Store. A reference may end in #<key> to
+// pick one key of a secret holding a JSON object.
+type Resolver struct {
+	openers map[string]Opener
+	sources map[string]Source
+}
+
+// NewResolver returns a resolver for the sources that openers maps schemes to
+func NewResolver(openers map[string]Opener) *Resolver {
+	return &Resolver{openers: openers, sources: map[string]Source{}}
+}
+
+// arnSchemes maps the prefixes of AWS ARNs to the scheme of their source
+var arnSchemes = map[string]string{
+	"arn:aws:secretsmanager:": "secretsmanager",
+	"arn:aws:ssm:":            "ssm",
+}
+
+// Parse splits a secret reference into its scheme and the reference within
+// the source, reporting false if value isn't one
+func (r *Resolver) Parse(value string) (scheme, ref string, ok bool) {
+	for prefix, scheme := range arnSchemes {
+		if strings.HasPrefix(value, prefix) {
+			_, registered := r.openers[scheme]
+			return scheme, value, registered
+		}
+	}
+
+	scheme, ref, ok = strings.Cut(value, ":")
+	if !ok || ref == "" {
+		return "", "", false
+	}
+	_, registered := r.openers[scheme]
+	return scheme, ref, registered
+}
+
+// Resolve returns the secret a reference names
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := r.Parse(value)
+	if !ok {
+		return "", fmt.Errorf("%q is not a secret reference", value)
+	}
+	ref, key, _ := strings.Cut(ref, "#")
+
+	source, ok := r.sources[scheme]
+	if !ok {
+		var err error
+		if source, err = r.openers[scheme](ctx); err != nil {
+			return "", fmt.Errorf("failed to open %s: %w", scheme, err)
+		}
+		r.sources[scheme] = source
+	}
+
+	secret, err := source.Fetch(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s secret %s: %w", scheme, ref, err)
+	}
+	if key == "" {
+		return secret, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("%s secret %s is not a JSON object, so it has no key %q", scheme, ref, key)
+	}
+	field, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%s secret %s has no key %q", scheme, ref, key)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(field), nil
+}
+
+// ResolveEnv replaces every environment variable holding a secret reference
+// with the secret, returning the names of the variables it replaced
+func (r *Resolver) ResolveEnv(ctx context.Context) ([]string, error) {
+	var names []string
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if _, _, ok := r.Parse(value); !ok {
+			continue
+		}
+
+		secret, err := r.Resolve(ctx, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		os.Setenv(name, secret)
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,146 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// vaultTimeout bounds each request to Vault
+const vaultTimeout = 10 * time.Second
+
+// VaultConfig represents how to reach and sign in to Vault. With a token,
+// it's used as is; otherwise RoleID and SecretID sign in with AppRole.
+type VaultConfig struct {
+	Addr      string
+	Namespace string
+	Token     string
+
+	RoleID   string
+	SecretID string
+
+	// AppRoleMount is where the AppRole auth method is mounted, by default
+	// "approle"
+	AppRoleMount string
+}
+
+// Vault represents HashiCorp Vault. A reference is the API path of a secret,
+// such as secret/data/go-trump for a KV version 2 engine mounted at secret,
+// and the secret is its data as a JSON object, to pick a key from with #.
+type Vault struct {
+	addr      string
+	namespace string
+	token     string
+	client    *http.Client
+}
+
+// NewVault connects to Vault, signing in with AppRole if cfg has no token
+func NewVault(ctx context.Context, cfg VaultConfig) (*Vault, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("no Vault address")
+	}
+	v := &Vault{
+		addr:      strings.TrimSuffix(cfg.Addr, "/"),
+		namespace: cfg.Namespace,
+		token:     cfg.Token,
+		client:    &http.Client{Timeout: vaultTimeout},
+	}
+	if v.token != "" {
+		return v, nil
+	}
+
+	if cfg.RoleID == "" || cfg.SecretID == "" {
+		return nil, errors.New("no Vault token, or AppRole role and secret ID")
+	}
+	mount := cfg.AppRoleMount
+	if mount == "" {
+		mount = "approle"
+	}
+	body, err := json.Marshal(map[string]string{"role_id": cfg.RoleID, "secret_id": cfg.SecretID})
+	if err != nil {
+		return nil, err
+	}
+
+	var login struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := v.do(ctx, "POST", "auth/"+strings.Trim(mount, "/")+"/login", body, &login); err != nil {
+		return nil, fmt.Errorf("AppRole login failed: %w", err)
+	}
+	v.token = login.Auth.ClientToken
+	return v, nil
+}
+
+func (v *Vault) Fetch(ctx context.Context, ref string) (string, error) {
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := v.do(ctx, "GET", ref, nil, &secret); err != nil {
+		return "", err
+	}
+
+	// KV version 2 nests the secret's keys, alongside its metadata
+	data := secret.Data
+	if nested, ok := data["data"]; ok && data["metadata"] != nil {
+		data = nil
+		if err := json.Unmarshal(nested, &data); err != nil {
+			return "", fmt.Errorf("failed to decode secret: %w", err)
+		}
+	}
+	if data == nil {
+		return "", errors.New("secret not found")
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// do makes a Vault API request, decoding the response into out
+func (v *Vault) do(ctx context.Context, method, path string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+"/v1/"+strings.TrimPrefix(path, "/"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(data, &failure)
+		if len(failure.Errors) > 0 {
+			return fmt.Errorf("vault error (%d): %s", resp.StatusCode, strings.Join(failure.Errors, "; "))
+		}
+		return fmt.Errorf("vault error (%d)", resp.StatusCode)
+	}
+	return json.Unmarshal(data, out)
+}
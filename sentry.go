@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// setupSentry reports failed runs and panics to Sentry when sentry_dsn is
+// set. SENTRY_ENVIRONMENT and SENTRY_RELEASE name the environment and release
+// they're reported under.
+func setupSentry() error {
+	if conf.SentryDSN == "" {
+		return nil
+	}
+	if err := sentry.Init(sentry.ClientOptions{Dsn: conf.SentryDSN}); err != nil {
+		return fmt.Errorf("failed to set up Sentry: %w", err)
+	}
+	return nil
+}
+
+// withSentryHub returns a context with a Sentry hub of its own, so the tags
+// describing one run don't end up on the errors of another
+func withSentryHub(ctx context.Context) context.Context {
+	return sentry.SetHubOnContext(ctx, sentry.CurrentHub().Clone())
+}
+
+// sentryHub returns the Sentry hub of ctx, or the global one
+func sentryHub(ctx context.Context) *sentry.Hub {
+	if hub := sentry.GetHubFromContext(ctx); hub != nil {
+		return hub
+	}
+	return sentry.CurrentHub()
+}
+
+// setSentryTags tags the errors later reported during the run of ctx, such as
+// with its day count
+func setSentryTags(ctx context.Context, tags map[string]string) {
+	sentryHub(ctx).Scope().SetTags(tags)
+}
+
+// reportError reports an error that retrying didn't get past to Sentry. Each
+// destination a post missed is reported on its own, tagged with the
+// destination.
+func reportError(ctx context.Context, err error) {
+	hub := sentryHub(ctx)
+
+	var pubErrs PublishErrors
+	if !errors.As(err, &pubErrs) {
+		hub.CaptureException(err)
+		return
+	}
+	for _, pubErr := range pubErrs {
+		hub.WithScope(func(scope *sentry.Scope) {
+			scope.SetTag("destination", pubErr.Destination)
+			hub.CaptureException(pubErr)
+		})
+	}
+}
+
+// reportPanic reports a panic to Sentry, waiting for it to be sent, then lets
+// it carry on. It must be deferred.
+func reportPanic(ctx context.Context) {
+	if r := recover(); r != nil {
+		hub := sentryHub(ctx)
+		hub.Recover(r)
+		hub.Flush(2 * time.Second)
+		panic(r)
+	}
+}
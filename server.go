@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/oidc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// TriggerRequest represents the optional body of a trigger request, such as
+// the body of a Cloud Scheduler job
+type TriggerRequest struct {
+	PostType string `json:"post_type"`
+	Force    bool   `json:"force"`
+}
+
+// runServer serves HTTP triggers on server.port (default 8080) until ctx is
+// cancelled. POST / is the Cloud Scheduler → Cloud Run entrypoint, and
+// requires a Google OIDC token minted for server.oidc_audience. POST /trigger requires TRIGGER_TOKEN as
+// a bearer token. At least one of them must be configured.
+func runServer(ctx context.Context) error {
+	audience := conf.Server.OIDCAudience
+	triggerToken := config.Getenv("TRIGGER_TOKEN")
+	if audience == "" && triggerToken == "" {
+		return errors.New("neither an OIDC audience nor the TRIGGER_TOKEN environment variable set")
+	}
+
+	mux := http.NewServeMux()
+	if audience != "" {
+		verifier := oidc.NewVerifier(audience)
+		verifier.Email = conf.Server.OIDCServiceAccount
+		verifier.Client = httpClient
+		mux.Handle("POST /{$}", requireOIDC(verifier, http.HandlerFunc(handleTrigger)))
+	}
+	if triggerToken != "" {
+		mux.Handle("POST /trigger", requireToken(triggerToken, http.HandlerFunc(handleTrigger)))
+	}
+	registerHealth(mux)
+	registerMetrics(mux)
+	mux.HandleFunc("GET /feed", handleFeed)
+
+	return serveHTTP(ctx, ":"+conf.Server.Port, mux)
+}
+
+// serveHTTP serves handler on addr until ctx is cancelled, then shuts down
+// gracefully, giving an in-flight post time to be published and recorded
+func serveHTTP(ctx context.Context, addr string, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		grace := conf.Timeouts.Bluesky + 10*time.Second
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger(logServer).Error("Failed to shut down cleanly", "err", err)
+		}
+	}()
+
+	logger(logServer).Info("Listening", "addr", server.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+
+	// Wait for in-flight requests to finish before returning
+	<-shutdown
+	return nil
+}
+
+// requireOIDC rejects requests without a valid Google ID token in their
+// Authorization header
+func requireOIDC(verifier *oidc.Verifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		ctx, cancel := httpRequest(r.Context())
+		claims, err := verifier.Verify(ctx, token)
+		cancel()
+		if err != nil {
+			logger(logServer).WarnContext(r.Context(), "Rejected trigger request", "err", err)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		logger(logServer).InfoContext(r.Context(), "Trigger request", "email", claims.Email)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireToken rejects requests whose bearer token isn't the shared secret
+func requireToken(secret string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			logger(logServer).WarnContext(r.Context(), "Rejected trigger request, invalid token", "remote_addr", r.RemoteAddr)
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleTrigger runs the pipeline for the post type named in the request body
+// or post_type query parameter, and reposts when the body sets force. Failures
+// respond with a 500 so the scheduler can retry.
+func handleTrigger(w http.ResponseWriter, r *http.Request) {
+	var body TriggerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if postType := r.URL.Query().Get("post_type"); postType != "" {
+		body.PostType = postType
+	}
+
+	// A caller that traces its requests gets the run's spans in its trace
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	result, err := triggerRun(ctx, body.PostType, body.Force)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// bearerToken returns the token from a "Bearer" Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lukeocodes/go-trump/state"
+)
+
+// DuplicatePolicy represents how new posts are kept from repeating recent
+// ones: a post at least Threshold similar to one of the last Window posts is
+// regenerated up to Regenerations times
+type DuplicatePolicy struct {
+	Window        int
+	Threshold     float64
+	Regenerations int
+}
+
+// duplicatePolicy returns the configured duplicate detection policy. A
+// window of 0 turns detection off.
+func duplicatePolicy() DuplicatePolicy {
+	return DuplicatePolicy{
+		Window:        conf.Duplicates.Window,
+		Threshold:     conf.Duplicates.Threshold,
+		Regenerations: conf.Duplicates.Regenerations,
+	}
+}
+
+var (
+	// Numbers and hashtags change or repeat every day whatever the wording,
+	// so they don't count towards similarity
+	similarityIgnored = regexp.MustCompile(`#\w+|\d[\d,.]*|https?://\S+`)
+	similarityWord    = regexp.MustCompile(`[\p{L}']+`)
+)
+
+// mostSimilar returns the recent post most similar to text, and how similar
+// it is from 0 to 1
+func mostSimilar(text string, recent []state.Post) (state.Post, float64) {
+	var best state.Post
+	var bestScore float64
+	shingles := wordShingles(text)
+	for _, post := range recent {
+		if score := jaccard(shingles, wordShingles(post.Text)); score > bestScore {
+			best, bestScore = post, score
+		}
+	}
+	return best, bestScore
+}
+
+// wordShingles normalizes text and returns its pairs of consecutive words, so
+// similarity reflects phrasing rather than shared vocabulary
+func wordShingles(text string) map[string]bool {
+	text = similarityIgnored.ReplaceAllString(strings.ToLower(text), " ")
+	words := similarityWord.FindAllString(text, -1)
+
+	shingles := map[string]bool{}
+	if len(words) == 1 {
+		shingles[words[0]] = true
+	}
+	for i := 1; i < len(words); i++ {
+		shingles[words[i-1]+" "+words[i]] = true
+	}
+	return shingles
+}
+
+// jaccard returns the share of shingles two texts have in common
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for s := range a {
+		if b[s] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
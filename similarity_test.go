@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lukeocodes/go-trump/state"
+)
+
+func TestMostSimilar(t *testing.T) {
+	recent := []state.Post{
+		{PostType: "daily", Date: "2026-10-13", Text: "Only 829 days to go! Keep your chin up and hold on to hope. #TheFinalTrumpDown"},
+		{PostType: "daily", Date: "2026-10-12", Text: "Tea, a good book and a long walk. 830 days left. #TheFinalTrumpDown"},
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		wantDate string
+		want     float64
+	}{
+		{
+			name:     "same wording with a new count",
+			text:     "Only 828 days to go! Keep your chin up and hold on to hope. #TheFinalTrumpDown",
+			wantDate: "2026-10-13",
+			want:     1,
+		},
+		{
+			name:     "case, punctuation and hashtags ignored",
+			text:     "ONLY 828 DAYS TO GO. keep your chin up, and hold on to hope #Countdown",
+			wantDate: "2026-10-13",
+			want:     1,
+		},
+		{
+			name:     "partly reworded",
+			text:     "Only 828 days to go! Keep your spirits up and hold on to hope.",
+			wantDate: "2026-10-13",
+			want:     10.0 / 14,
+		},
+		{
+			name: "nothing in common",
+			text: "Sunshine in the forecast, with a new recipe to try.",
+			want: 0,
+		},
+		{
+			name: "only numbers and hashtags",
+			text: "828 #TheFinalTrumpDown",
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, score := mostSimilar(tt.text, recent)
+			if math.Abs(score-tt.want) > 1e-9 || post.Date != tt.wantDate {
+				t.Errorf("mostSimilar(%q) = %s, %.3f, want %s, %.3f", tt.text, post.Date, score, tt.wantDate, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordShingles(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Hope", []string{"hope"}},
+		{"Don't give up", []string{"don't give", "give up"}},
+		{"Day 100: https://example.com/post hang in there #Countdown", []string{"day hang", "hang in", "in there"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := wordShingles(tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("wordShingles(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			for _, s := range tt.want {
+				if !got[s] {
+					t.Errorf("wordShingles(%q) = %v, missing %q", tt.text, got, s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// destinationSlack is the destination type of a Slack channel
+const destinationSlack = "slack"
+
+// slackConfig represents a slack destination's own settings
+type slackConfig struct {
+	// WebhookURLEnv names the environment variable holding the incoming
+	// webhook URL (default SLACK_WEBHOOK_URL)
+	WebhookURLEnv string `json:"webhook_url_env"`
+}
+
+// slackSettings decodes a slack destination's own settings
+func slackSettings(destination *events.Destination) (slackConfig, error) {
+	var c slackConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if c.WebhookURLEnv == "" {
+		c.WebhookURLEnv = "SLACK_WEBHOOK_URL"
+	}
+	return c, nil
+}
+
+// slackMaxLength is the most characters the text of a section block may have
+const slackMaxLength = 3000
+
+// slackPublisher publishes to a Slack channel through an incoming webhook
+type slackPublisher struct {
+	adapter
+
+	name          string
+	webhookURLEnv string
+}
+
+func init() {
+	registerPublisher(destinationSlack, validateSettings(slackSettings), factoryOf(newSlackPublisher))
+}
+
+func newSlackPublisher(destination *events.Destination) (*slackPublisher, error) {
+	settings, err := slackSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &slackPublisher{
+		adapter:       newAdapter(destination, "Slack", slackMaxLength, events.HashtagsStrip),
+		name:          destination.Name,
+		webhookURLEnv: settings.WebhookURLEnv,
+	}, nil
+}
+
+func (p *slackPublisher) Name() string {
+	return p.name
+}
+
+// Publish posts the text as Block Kit blocks, with the image when it is
+// publicly served. The plain text is kept as the notification fallback.
+func (p *slackPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	webhookURL := config.Getenv(p.webhookURLEnv)
+	if webhookURL == "" {
+		return Published{}, fmt.Errorf("%s environment variable not set", p.webhookURLEnv)
+	}
+
+	ctx, cancel := sendRequest(ctx)
+	defer cancel()
+
+	bodyBytes, err := json.Marshal(slackMessage(post))
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to create Slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Published{}, fmt.Errorf("Slack request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return Published{}, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("slack error (%d): %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))}
+	}
+
+	return Published{}, nil
+}
+
+// slackMessage builds the Block Kit payload for a post
+func slackMessage(post *OutgoingPost) map[string]interface{} {
+	blocks := []map[string]interface{}{
+		{
+			"type": "section",
+			"text": map[string]interface{}{
+				"type": "mrkdwn",
+				"text": slackEscape(post.Text),
+			},
+		},
+	}
+
+	if post.ImageURL != "" {
+		alt := post.ImageAlt
+		if alt == "" {
+			alt = "Milestone image"
+		}
+		blocks = append(blocks, map[string]interface{}{
+			"type":      "image",
+			"image_url": post.ImageURL,
+			"alt_text":  alt,
+		})
+	}
+
+	blocks = append(blocks, map[string]interface{}{
+		"type": "context",
+		"elements": []map[string]interface{}{
+			{
+				"type": "mrkdwn",
+				"text": "Countdown for " + now.Format("Monday, January 2, 2006"),
+			},
+		},
+	})
+
+	return map[string]interface{}{
+		"text":   post.Text,
+		"blocks": blocks,
+	}
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as control
+// characters
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
@@ -0,0 +1,172 @@
+// Package state remembers what the bot has done between runs, so it can tell
+// whether a post has already gone out today.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// dateLayout is how posted dates are recorded
+const dateLayout = "2006-01-02"
+
+// Post represents a single post in the history
+type Post struct {
+	PostType string    `json:"post_type"`
+	Date     string    `json:"date"`
+	PostedAt time.Time `json:"posted_at"`
+	Text     string    `json:"text"`
+
+	// Skipped gives the reason when the post was deliberately not made
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Followers represents the bot's follower count at a point in time
+type Followers struct {
+	Count int       `json:"count"`
+	At    time.Time `json:"at"`
+}
+
+// State represents what the bot remembers between runs
+type State struct {
+	// LastPosted maps a post type, e.g. "daily", to the date it was last posted
+	LastPosted map[string]string `json:"last_posted"`
+
+	// History lists every post made, oldest first
+	History []Post `json:"history,omitempty"`
+
+	// Followers is the bot's follower count when the last status report was
+	// made, to report how it has changed since
+	Followers *Followers `json:"followers,omitempty"`
+
+	path string
+}
+
+// Load reads the state file at path. A missing file is an empty state.
+func Load(path string) (*State, error) {
+	s := &State{
+		LastPosted: map[string]string{},
+		path:       path,
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to decode state file: %w", err)
+	}
+	if s.LastPosted == nil {
+		s.LastPosted = map[string]string{}
+	}
+
+	return s, nil
+}
+
+// PostedOn reports whether a post type was posted, or deliberately skipped, on
+// date's calendar day
+func (s *State) PostedOn(postType string, date time.Time) bool {
+	return s.LastPosted[postType] == date.Format(dateLayout)
+}
+
+// MarkPosted records that a post type was posted on date's calendar day, adds
+// it to the history and saves the state
+func (s *State) MarkPosted(postType string, date time.Time, text string) error {
+	s.LastPosted[postType] = date.Format(dateLayout)
+	s.History = append(s.History, Post{
+		PostType: postType,
+		Date:     date.Format(dateLayout),
+		PostedAt: time.Now().UTC(),
+		Text:     text,
+	})
+	return s.save()
+}
+
+// MarkSkipped records that a post type was deliberately not posted on date's
+// calendar day, so it isn't caught up later, and saves the state
+func (s *State) MarkSkipped(postType string, date time.Time, reason string) error {
+	s.LastPosted[postType] = date.Format(dateLayout)
+	s.History = append(s.History, Post{
+		PostType: postType,
+		Date:     date.Format(dateLayout),
+		PostedAt: time.Now().UTC(),
+		Skipped:  reason,
+	})
+	return s.save()
+}
+
+// RecordFollowers remembers the bot's follower count at a point in time and
+// saves the state
+func (s *State) RecordFollowers(count int, at time.Time) error {
+	s.Followers = &Followers{Count: count, At: at.UTC()}
+	return s.save()
+}
+
+// PostsOfType returns the posts made of a single post type, oldest first
+func (s *State) PostsOfType(postType string) []Post {
+	var posts []Post
+	for _, p := range s.History {
+		if p.PostType == postType && p.Skipped == "" {
+			posts = append(posts, p)
+		}
+	}
+	return posts
+}
+
+// LastPost returns the most recent post made of any type
+func (s *State) LastPost() (Post, bool) {
+	for i := len(s.History) - 1; i >= 0; i-- {
+		if s.History[i].Skipped == "" {
+			return s.History[i], true
+		}
+	}
+	return Post{}, false
+}
+
+// RecentPosts returns up to n of the most recent posts made of any type,
+// newest first
+func (s *State) RecentPosts(n int) []Post {
+	var posts []Post
+	for i := len(s.History) - 1; i >= 0 && len(posts) < n; i-- {
+		if s.History[i].Skipped == "" {
+			posts = append(posts, s.History[i])
+		}
+	}
+	return posts
+}
+
+// save writes the state atomically, so a crash mid-write can't corrupt it
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/state"
+	"github.com/lukeocodes/go-trump/store"
+)
+
+// statusReportType is what the status report is scheduled as in the daemon,
+// alongside the post types
+const statusReportType = "status_report"
+
+// statusReportPeriod is how far back the status report looks
+const statusReportPeriod = 7 * 24 * time.Hour
+
+// statusReportRuns is the most of the latest runs the status report reads,
+// far more than a week of posts
+const statusReportRuns = 1000
+
+// getProfileURL looks up profiles on the public Bluesky AppView, which needs
+// no authentication
+const getProfileURL = "https://public.api.bsky.app/xrpc/app.bsky.actor.getProfile"
+
+// StatusReport represents how the bot has done over a week
+type StatusReport struct {
+	From time.Time
+	To   time.Time
+
+	Posted  int
+	Failed  int
+	Skipped int
+
+	// Missed counts the posts each destination didn't get
+	Missed map[string]int
+
+	// Followers is the bot's Bluesky follower count, and FollowerDelta how
+	// it has changed since the last report, when they're known
+	Followers     *int
+	FollowerDelta *int
+
+	// Engagement totals the engagement of the week's Bluesky posts
+	Engagement Engagement
+
+	// Tokens is how many tokens the week's completions used, and
+	// GenerationP50 their median latency
+	Tokens        int
+	GenerationP50 time.Duration
+}
+
+// runStatusReport prints the status report for the week to now, or sends it
+// as status_report is configured to when send is set
+func runStatusReport(ctx context.Context, send bool) error {
+	if send {
+		if !conf.StatusReport.Enabled() {
+			return failure(errConfig, errors.New("neither status_report.post nor status_report.bluesky_dm is set"))
+		}
+		return sendStatusReport(ctx)
+	}
+
+	report, err := buildStatusReport(ctx, newBlueskyAPI(), time.Now())
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(os.Stdout, report.text()+"\n")
+	return err
+}
+
+// sendStatusReport builds the status report for the week to now, then posts
+// it from the bot's Bluesky account and sends it to status_report.bluesky_dm,
+// as configured. The follower count is remembered for the next report. A
+// dry run prints it instead.
+func sendStatusReport(ctx context.Context) error {
+	bsky := newBlueskyAPI()
+	report, err := buildStatusReport(ctx, bsky, time.Now())
+	if err != nil {
+		return err
+	}
+	text := report.text()
+	if conf.DryRun {
+		_, err := io.WriteString(os.Stdout, text+"\n")
+		return err
+	}
+
+	username, password := config.Getenv("BLUESKY_USERNAME"), config.Getenv("BLUESKY_PASSWORD")
+	if username == "" || password == "" {
+		return failure(errConfig, errors.New("BLUESKY_USERNAME and BLUESKY_PASSWORD environment variables must be set"))
+	}
+	auth, err := bsky.authenticate(ctx, username, password)
+	if err != nil {
+		return failure(errAuth, fmt.Errorf("authentication failed: %w", err))
+	}
+
+	// Failing to send it one way doesn't stop the other
+	var errs []error
+	if conf.StatusReport.Post {
+		post := text
+		if runes := []rune(post); len(runes) > blueskyMaxLength {
+			post = string(runes[:blueskyMaxLength-1]) + "…"
+		}
+		if _, err := bsky.postMessage(ctx, auth.AccessJwt, auth.Did, post, nil); err != nil {
+			errs = append(errs, fmt.Errorf("failed to post the status report: %w", err))
+		}
+	}
+	if recipient := conf.StatusReport.BlueskyDM; recipient != "" {
+		if err := bsky.sendDirectMessage(ctx, auth.AccessJwt, recipient, text); err != nil {
+			errs = append(errs, fmt.Errorf("failed to message the status report to %s: %w", recipient, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	logger(logScheduler).InfoContext(ctx, "Sent the status report", "posted", report.Posted, "failed", report.Failed)
+
+	if report.Followers != nil {
+		st, err := state.Load(stateFile())
+		if err != nil {
+			return err
+		}
+		if err := st.RecordFollowers(*report.Followers, report.To); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildStatusReport reports on the week to now from the runs in the store,
+// with the follower count and engagement looked up on Bluesky through bsky.
+// Bluesky failing to answer leaves them out rather than failing the report.
+func buildStatusReport(ctx context.Context, bsky *blueskyAPI, now time.Time) (*StatusReport, error) {
+	s, err := openStore(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+	if s == nil {
+		return nil, errors.New("the status report is built from the store, and STORE is none")
+	}
+
+	runs, err := s.RecentRuns(ctx, statusReportRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &StatusReport{From: now.Add(-statusReportPeriod), To: now, Missed: map[string]int{}}
+	var uris []string
+	var latencies []time.Duration
+	for _, run := range runs {
+		if run.StartedAt.Before(report.From) {
+			break
+		}
+		switch run.Status {
+		case store.StatusPosted:
+			report.Posted++
+		case store.StatusFailed:
+			report.Failed++
+		case store.StatusSkipped:
+			report.Skipped++
+		}
+		report.Tokens += run.PromptTokens + run.CompletionTokens
+		if run.GenerationLatency > 0 {
+			latencies = append(latencies, run.GenerationLatency)
+		}
+		for _, result := range run.Results {
+			if !result.Published() {
+				report.Missed[result.Destination]++
+			} else if strings.HasPrefix(result.Link, "at://") {
+				uris = append(uris, result.Link)
+			}
+		}
+	}
+	if len(latencies) > 0 {
+		report.GenerationP50 = percentile(latencies, 0.5)
+	}
+
+	log := logger(logBluesky)
+	for start := 0; start < len(uris); start += getPostsBatch {
+		engagement, err := bsky.fetchEngagement(ctx, uris[start:min(start+getPostsBatch, len(uris))])
+		if err != nil {
+			log.WarnContext(ctx, "Failed to fetch engagement for the status report", "err", err)
+			break
+		}
+		for _, e := range engagement {
+			report.Engagement.Likes += e.Likes
+			report.Engagement.Reposts += e.Reposts
+			report.Engagement.Replies += e.Replies
+			report.Engagement.Quotes += e.Quotes
+		}
+	}
+
+	if actor := config.Getenv("BLUESKY_USERNAME"); actor != "" {
+		followers, err := bsky.fetchFollowers(ctx, actor)
+		if err != nil {
+			log.WarnContext(ctx, "Failed to fetch the follower count for the status report", "err", err)
+		} else {
+			report.Followers = &followers
+			if st, err := state.Load(stateFile()); err == nil && st.Followers != nil {
+				delta := followers - st.Followers.Count
+				report.FollowerDelta = &delta
+			}
+		}
+	}
+	return report, nil
+}
+
+// fetchFollowers looks up how many followers actor, a handle or DID, has
+func (b *blueskyAPI) fetchFollowers(ctx context.Context, actor string) (int, error) {
+	ctx, cancel := blueskyRequest(ctx)
+	defer cancel()
+
+	query := url.Values{"actor": {actor}}
+	req, err := http.NewRequestWithContext(ctx, "GET", getProfileURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create getProfile request: %w", err)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("getProfile request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, blueskyError("getProfile", resp)
+	}
+
+	var profile struct {
+		FollowersCount int `json:"followersCount"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return 0, fmt.Errorf("failed to decode getProfile response: %w", err)
+	}
+	return profile.FollowersCount, nil
+}
+
+// text writes the report out as a post, short enough for Bluesky
+func (r *StatusReport) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Bot status, %s to %s\n", r.From.Format("Jan 2"), r.To.Format("Jan 2"))
+	fmt.Fprintf(&b, "Posts: %d made, %d failed, %d skipped\n", r.Posted, r.Failed, r.Skipped)
+
+	if len(r.Missed) > 0 {
+		var missed []string
+		for _, destination := range slices.Sorted(maps.Keys(r.Missed)) {
+			missed = append(missed, fmt.Sprintf("%s %d", destination, r.Missed[destination]))
+		}
+		fmt.Fprintf(&b, "Missed: %s\n", strings.Join(missed, ", "))
+	}
+
+	if r.Followers != nil {
+		fmt.Fprintf(&b, "Followers: %d", *r.Followers)
+		if r.FollowerDelta != nil {
+			fmt.Fprintf(&b, " (%+d)", *r.FollowerDelta)
+		}
+		b.WriteString("\n")
+	}
+
+	e := r.Engagement
+	fmt.Fprintf(&b, "Engagement: %d likes, %d reposts, %d replies, %d quotes\n", e.Likes, e.Reposts, e.Replies, e.Quotes)
+	fmt.Fprintf(&b, "OpenAI: %d tokens", r.Tokens)
+	if r.GenerationP50 > 0 {
+		fmt.Fprintf(&b, ", %s median", roundLatency(r.GenerationP50))
+	}
+	return b.String()
+}
@@ -0,0 +1,336 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/lukeocodes/go-trump/lock"
+)
+
+// dynamoMetaDate is the partition holding the run ID counter and the range of
+// dates with runs, and dynamoLockDate the partition holding locks, which no
+// real date sorts alongside
+const (
+	dynamoMetaDate = "#meta"
+	dynamoLockDate = "#lock"
+)
+
+// DynamoDB keeps runs in a single DynamoDB table, for Lambda deployments.
+// Runs are partitioned by date, so checking a day's posts is one query. The
+// table's partition key is a string named "date" and its sort key a string
+// named "sk".
+type DynamoDB struct {
+	client *dynamodb.Client
+	table  string
+	owner  string
+}
+
+// NewDynamoDB uses the DynamoDB table named table, with the default AWS config
+func NewDynamoDB(ctx context.Context, table string) (*DynamoDB, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &DynamoDB{client: dynamodb.NewFromConfig(cfg), table: table, owner: lock.NewOwner()}, nil
+}
+
+func (d *DynamoDB) SaveRun(ctx context.Context, run *Run) error {
+	// Take the next ID, noting the first date with runs
+	out, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(d.table),
+		Key:              dynamoKey(dynamoMetaDate, "counter"),
+		UpdateExpression: aws.String("ADD run_id :one SET first_date = if_not_exists(first_date, :date)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":  &types.AttributeValueMemberN{Value: "1"},
+			":date": &types.AttributeValueMemberS{Value: run.Date},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+	id, err := strconv.ParseInt(dynamoNumber(out.Attributes["run_id"]), 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to save run: invalid run ID: %w", err)
+	}
+
+	if _, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item:      dynamoRunItem(id, run),
+	}); err != nil {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+
+	// Note the latest date with runs, which reading starts from
+	_, err = d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.table),
+		Key:                 dynamoKey(dynamoMetaDate, "counter"),
+		UpdateExpression:    aws.String("SET last_date = :date"),
+		ConditionExpression: aws.String("attribute_not_exists(last_date) OR last_date < :date"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":date": &types.AttributeValueMemberS{Value: run.Date},
+		},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionFailed) {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+
+	run.ID = id
+	return nil
+}
+
+func (d *DynamoDB) RecentRuns(ctx context.Context, limit int) ([]Run, error) {
+	return d.recentRuns(ctx, limit, func(Run) bool { return true })
+}
+
+func (d *DynamoDB) LastRun(ctx context.Context, postType string) (*Run, error) {
+	runs, err := d.recentRuns(ctx, 1, func(run Run) bool {
+		return run.Status == StatusPosted && (postType == "" || run.PostType == postType)
+	})
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+func (d *DynamoDB) RecentPosts(ctx context.Context, n int) ([]Run, error) {
+	return d.recentRuns(ctx, n, func(run Run) bool { return run.Status == StatusPosted })
+}
+
+// recentRuns reads the date partitions one day at a time, from the latest
+// date with runs back to the first or until it has limit runs that match
+func (d *DynamoDB) recentRuns(ctx context.Context, limit int, match func(Run) bool) ([]Run, error) {
+	out, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            dynamoKey(dynamoMetaDate, "counter"),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+	first, err := time.Parse(time.DateOnly, dynamoString(out.Item["first_date"]))
+	if err != nil {
+		// Nothing has been saved yet
+		return nil, nil
+	}
+	last, err := time.Parse(time.DateOnly, dynamoString(out.Item["last_date"]))
+	if err != nil {
+		last = first
+	}
+
+	var runs []Run
+	for day := last; !day.Before(first) && len(runs) < limit; day = day.AddDate(0, 0, -1) {
+		dayRuns, err := d.runsOn(ctx, day.Format(time.DateOnly), math.MaxInt)
+		if err != nil {
+			return nil, err
+		}
+		for _, run := range dayRuns {
+			if len(runs) < limit && match(run) {
+				runs = append(runs, run)
+			}
+		}
+	}
+	return runs, nil
+}
+
+// runsOn returns up to limit of a date's runs, newest first
+func (d *DynamoDB) runsOn(ctx context.Context, date string, limit int) ([]Run, error) {
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:              aws.String(d.table),
+		KeyConditionExpression: aws.String("#date = :date AND begins_with(sk, :run)"),
+		ExpressionAttributeNames: map[string]string{
+			"#date": "date",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":date": &types.AttributeValueMemberS{Value: date},
+			":run":  &types.AttributeValueMemberS{Value: "run#"},
+		},
+		ScanIndexForward: aws.Bool(false),
+	})
+
+	var runs []Run
+	for paginator.HasMorePages() && len(runs) < limit {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read runs: %w", err)
+		}
+		for _, item := range page.Items {
+			if len(runs) == limit {
+				break
+			}
+			runs = append(runs, dynamoRun(item))
+		}
+	}
+	return runs, nil
+}
+
+func (d *DynamoDB) PostedOn(ctx context.Context, postType, date string) (bool, error) {
+	runs, err := d.runsOn(ctx, date, math.MaxInt)
+	if err != nil {
+		return false, err
+	}
+	for _, run := range runs {
+		if run.PostType == postType && run.Status == StatusPosted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (d *DynamoDB) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	item := dynamoKey(dynamoLockDate, key)
+	item["owner"] = &types.AttributeValueMemberS{Value: d.owner}
+	item["expires_at"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl).Unix(), 10)}
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item:      item,
+		// Take the lock if nobody holds it, or the holder's lock has expired
+		ConditionExpression: aws.String("attribute_not_exists(sk) OR expires_at < :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return true, nil
+}
+
+func (d *DynamoDB) Unlock(ctx context.Context, key string) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(d.table),
+		Key:                 dynamoKey(dynamoLockDate, key),
+		ConditionExpression: aws.String("#owner = :owner"),
+		ExpressionAttributeNames: map[string]string{
+			"#owner": "owner",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":owner": &types.AttributeValueMemberS{Value: d.owner},
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionFailed) {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+func (d *DynamoDB) Close() error {
+	return nil
+}
+
+func dynamoKey(date, sk string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"date": &types.AttributeValueMemberS{Value: date},
+		"sk":   &types.AttributeValueMemberS{Value: sk},
+	}
+}
+
+// dynamoRunItem encodes a run. Sort keys pad the ID so they sort in order.
+func dynamoRunItem(id int64, run *Run) map[string]types.AttributeValue {
+	item := dynamoKey(run.Date, fmt.Sprintf("run#%019d", id))
+	item["id"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(id, 10)}
+	item["post_type"] = &types.AttributeValueMemberS{Value: run.PostType}
+	item["started_at"] = &types.AttributeValueMemberS{Value: formatTime(run.StartedAt)}
+	item["finished_at"] = &types.AttributeValueMemberS{Value: formatTime(run.FinishedAt)}
+	item["status"] = &types.AttributeValueMemberS{Value: run.Status}
+	item["correlation_id"] = &types.AttributeValueMemberS{Value: run.CorrelationID}
+	item["error"] = &types.AttributeValueMemberS{Value: run.Error}
+	item["text"] = &types.AttributeValueMemberS{Value: run.Text}
+	item["prompt_variant"] = &types.AttributeValueMemberS{Value: run.PromptVariant}
+	item["model"] = &types.AttributeValueMemberS{Value: run.Model}
+	item["prompt_tokens"] = &types.AttributeValueMemberN{Value: strconv.Itoa(run.PromptTokens)}
+	item["completion_tokens"] = &types.AttributeValueMemberN{Value: strconv.Itoa(run.CompletionTokens)}
+	item["system_prompt"] = &types.AttributeValueMemberS{Value: run.SystemPrompt}
+	item["prompt"] = &types.AttributeValueMemberS{Value: run.Prompt}
+	item["raw_response"] = &types.AttributeValueMemberS{Value: run.RawResponse}
+	item["generation_latency_ms"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(run.GenerationLatency.Milliseconds(), 10)}
+
+	results := make([]types.AttributeValue, len(run.Results))
+	for i, result := range run.Results {
+		results[i] = &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"destination": &types.AttributeValueMemberS{Value: result.Destination},
+			"link":        &types.AttributeValueMemberS{Value: result.Link},
+			"cid":         &types.AttributeValueMemberS{Value: result.CID},
+			"error":       &types.AttributeValueMemberS{Value: result.Error},
+			"latency_ms":  &types.AttributeValueMemberN{Value: strconv.FormatInt(result.Latency.Milliseconds(), 10)},
+		}}
+	}
+	item["results"] = &types.AttributeValueMemberL{Value: results}
+	return item
+}
+
+func dynamoRun(item map[string]types.AttributeValue) Run {
+	id, _ := strconv.ParseInt(dynamoNumber(item["id"]), 10, 64)
+	promptTokens, _ := strconv.Atoi(dynamoNumber(item["prompt_tokens"]))
+	completionTokens, _ := strconv.Atoi(dynamoNumber(item["completion_tokens"]))
+	generationMS, _ := strconv.ParseInt(dynamoNumber(item["generation_latency_ms"]), 10, 64)
+	run := Run{
+		ID:               id,
+		PostType:         dynamoString(item["post_type"]),
+		Date:             dynamoString(item["date"]),
+		StartedAt:        parseTime(dynamoString(item["started_at"])),
+		FinishedAt:       parseTime(dynamoString(item["finished_at"])),
+		Status:           dynamoString(item["status"]),
+		Error:            dynamoString(item["error"]),
+		Text:             dynamoString(item["text"]),
+		PromptVariant:    dynamoString(item["prompt_variant"]),
+		Model:            dynamoString(item["model"]),
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		SystemPrompt:     dynamoString(item["system_prompt"]),
+		Prompt:           dynamoString(item["prompt"]),
+		RawResponse:      dynamoString(item["raw_response"]),
+
+		GenerationLatency: time.Duration(generationMS) * time.Millisecond,
+		CorrelationID:     dynamoString(item["correlation_id"]),
+	}
+
+	if list, ok := item["results"].(*types.AttributeValueMemberL); ok {
+		for _, v := range list.Value {
+			m, ok := v.(*types.AttributeValueMemberM)
+			if !ok {
+				continue
+			}
+			latencyMS, _ := strconv.ParseInt(dynamoNumber(m.Value["latency_ms"]), 10, 64)
+			run.Results = append(run.Results, Result{
+				Destination: dynamoString(m.Value["destination"]),
+				Link:        dynamoString(m.Value["link"]),
+				CID:         dynamoString(m.Value["cid"]),
+				Error:       dynamoString(m.Value["error"]),
+				Latency:     time.Duration(latencyMS) * time.Millisecond,
+			})
+		}
+	}
+	return run
+}
+
+func dynamoString(v types.AttributeValue) string {
+	if s, ok := v.(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func dynamoNumber(v types.AttributeValue) string {
+	if n, ok := v.(*types.AttributeValueMemberN); ok {
+		return n.Value
+	}
+	return ""
+}
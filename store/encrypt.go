@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// encryptedPrefix marks a field encrypted by an Encrypted store
+const encryptedPrefix = "enc:v1:"
+
+// Encrypted wraps a store so the prompts and raw responses of runs are
+// encrypted with AES-GCM before they're saved, and decrypted when read.
+// Fields saved without encryption are read as they are.
+type Encrypted struct {
+	Store
+	aead cipher.AEAD
+}
+
+// NewEncrypted wraps s, encrypting with key, which must be 32 bytes
+func NewEncrypted(s Store, key []byte) (*Encrypted, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, not %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypted{Store: s, aead: aead}, nil
+}
+
+func (e *Encrypted) SaveRun(ctx context.Context, run *Run) error {
+	sealed := *run
+	for _, field := range []*string{&sealed.SystemPrompt, &sealed.Prompt, &sealed.RawResponse} {
+		*field = e.seal(*field)
+	}
+	if err := e.Store.SaveRun(ctx, &sealed); err != nil {
+		return err
+	}
+	run.ID = sealed.ID
+	return nil
+}
+
+func (e *Encrypted) RecentRuns(ctx context.Context, limit int) ([]Run, error) {
+	runs, err := e.Store.RecentRuns(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range runs {
+		if err := e.openRun(&runs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return runs, nil
+}
+
+func (e *Encrypted) LastRun(ctx context.Context, postType string) (*Run, error) {
+	run, err := e.Store.LastRun(ctx, postType)
+	if err != nil || run == nil {
+		return nil, err
+	}
+	if err := e.openRun(run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (e *Encrypted) RecentPosts(ctx context.Context, n int) ([]Run, error) {
+	runs, err := e.Store.RecentPosts(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	for i := range runs {
+		if err := e.openRun(&runs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return runs, nil
+}
+
+// openRun decrypts the encrypted fields of a run in place
+func (e *Encrypted) openRun(run *Run) error {
+	for _, field := range []*string{&run.SystemPrompt, &run.Prompt, &run.RawResponse} {
+		var err error
+		if *field, err = e.open(*field); err != nil {
+			return fmt.Errorf("failed to decrypt run %d: %w", run.ID, err)
+		}
+	}
+	return nil
+}
+
+// Prune prunes the wrapped store, if it can be
+func (e *Encrypted) Prune(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	p, ok := e.Store.(Pruner)
+	if !ok {
+		return 0, errors.ErrUnsupported
+	}
+	return p.Prune(ctx, cutoff, keep)
+}
+
+func (e *Encrypted) seal(plaintext string) string {
+	if plaintext == "" {
+		return ""
+	}
+	nonce := make([]byte, e.aead.NonceSize())
+	rand.Read(nonce)
+	sealed := e.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)
+}
+
+func (e *Encrypted) open(field string) (string, error) {
+	encoded, ok := strings.CutPrefix(field, encryptedPrefix)
+	if !ok {
+		return field, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < e.aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:e.aead.NonceSize()], sealed[e.aead.NonceSize():]
+	plaintext, err := e.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
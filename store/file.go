@@ -0,0 +1,407 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lukeocodes/go-trump/lock"
+)
+
+// jsonRun is how a run is encoded in the JSONL and Redis stores
+type jsonRun struct {
+	ID               int64        `json:"id"`
+	PostType         string       `json:"post_type"`
+	Date             string       `json:"date"`
+	StartedAt        time.Time    `json:"started_at"`
+	FinishedAt       time.Time    `json:"finished_at"`
+	Status           string       `json:"status"`
+	Error            string       `json:"error,omitempty"`
+	Text             string       `json:"text,omitempty"`
+	PromptVariant    string       `json:"prompt_variant,omitempty"`
+	Model            string       `json:"model,omitempty"`
+	PromptTokens     int          `json:"prompt_tokens,omitempty"`
+	CompletionTokens int          `json:"completion_tokens,omitempty"`
+	SystemPrompt     string       `json:"system_prompt,omitempty"`
+	Prompt           string       `json:"prompt,omitempty"`
+	RawResponse      string       `json:"raw_response,omitempty"`
+	Results          []jsonResult `json:"results,omitempty"`
+
+	GenerationLatencyMS int64  `json:"generation_latency_ms,omitempty"`
+	CorrelationID       string `json:"correlation_id,omitempty"`
+}
+
+type jsonResult struct {
+	Destination string `json:"destination"`
+	Link        string `json:"link,omitempty"`
+	Error       string `json:"error,omitempty"`
+	CID         string `json:"cid,omitempty"`
+	LatencyMS   int64  `json:"latency_ms,omitempty"`
+}
+
+// fileLock is how a lock is kept in a file store's locks file
+type fileLock struct {
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// File keeps runs in a JSONL file, for setups that don't want a database.
+// The file is rewritten atomically on every save, under an advisory lock so
+// a daemon and a manual run can share it, on platforms with FileLocking;
+// elsewhere, such as Windows, only one process should use it at a time.
+// Locks are kept alongside it, in a JSON file with the extension ".locks".
+type File struct {
+	path  string
+	owner string
+}
+
+// NewFile returns a store keeping runs in the JSONL file at path, which is
+// created on the first save
+func NewFile(path string) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &File{path: path, owner: lock.NewOwner()}, nil
+}
+
+func (f *File) SaveRun(ctx context.Context, run *Run) error {
+	unlock, err := lockFile(f.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock store: %w", err)
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(f.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read store: %w", err)
+	}
+	runs, err := decodeRuns(data)
+	if err != nil {
+		return err
+	}
+
+	id := int64(1)
+	if len(runs) > 0 {
+		id = runs[len(runs)-1].ID + 1
+	}
+	line, err := json.Marshal(toJSONRun(id, run))
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(append(data, line...), '\n')
+	if err := writeAtomic(f.path, data); err != nil {
+		return fmt.Errorf("failed to write store: %w", err)
+	}
+
+	run.ID = id
+	return nil
+}
+
+func (f *File) RecentRuns(ctx context.Context, limit int) ([]Run, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	stored, err := decodeRuns(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	for i := len(stored) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, stored[i].run())
+	}
+	return runs, nil
+}
+
+func (f *File) LastRun(ctx context.Context, postType string) (*Run, error) {
+	runs, err := f.RecentRuns(ctx, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	return lastPosted(runs, postType), nil
+}
+
+func (f *File) RecentPosts(ctx context.Context, n int) ([]Run, error) {
+	runs, err := f.RecentRuns(ctx, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	return latestPosted(runs, "", n), nil
+}
+
+func (f *File) PostedOn(ctx context.Context, postType, date string) (bool, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read store: %w", err)
+	}
+	runs, err := decodeRuns(data)
+	if err != nil {
+		return false, err
+	}
+
+	for _, run := range runs {
+		if run.PostType == postType && run.Date == date && run.Status == StatusPosted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *File) Prune(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	unlock, err := lockFile(f.path + ".lock")
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock store: %w", err)
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read store: %w", err)
+	}
+	runs, err := decodeRuns(data)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := retained(runs, jsonRun.started, cutoff, keep)
+	if len(kept) == len(runs) {
+		return 0, nil
+	}
+	if err := writeAtomic(f.path, encodeRuns(kept)); err != nil {
+		return 0, fmt.Errorf("failed to write store: %w", err)
+	}
+	return len(runs) - len(kept), nil
+}
+
+func (f *File) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	var acquired bool
+	err := f.updateLocks(func(locks map[string]fileLock) bool {
+		now := time.Now()
+		if held, ok := locks[key]; ok && held.ExpiresAt.After(now) {
+			return false
+		}
+		locks[key] = fileLock{Owner: f.owner, ExpiresAt: now.Add(ttl)}
+		acquired = true
+		return true
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return acquired, nil
+}
+
+func (f *File) Unlock(ctx context.Context, key string) error {
+	err := f.updateLocks(func(locks map[string]fileLock) bool {
+		if locks[key].Owner != f.owner {
+			return false
+		}
+		delete(locks, key)
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// updateLocks reads the locks file, under the store's advisory lock, and
+// rewrites it if update reports it changed the locks
+func (f *File) updateLocks(update func(map[string]fileLock) bool) error {
+	unlock, err := lockFile(f.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path := f.path + ".locks"
+	locks := map[string]fileLock{}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &locks); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+	}
+
+	if !update(locks) {
+		return nil
+	}
+	data, err = json.Marshal(locks)
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, data)
+}
+
+func (f *File) Close() error {
+	return nil
+}
+
+func decodeRuns(data []byte) ([]jsonRun, error) {
+	var runs []jsonRun
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var run jsonRun
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("failed to decode store line %d: %w", line, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	return runs, nil
+}
+
+// MarshalJSONL encodes runs in the file store's JSONL format, one per line,
+// as a portable copy of any store's history
+func MarshalJSONL(runs []Run) []byte {
+	stored := make([]jsonRun, len(runs))
+	for i := range runs {
+		stored[i] = toJSONRun(runs[i].ID, &runs[i])
+	}
+	return encodeRuns(stored)
+}
+
+// UnmarshalJSONL decodes runs encoded by MarshalJSONL
+func UnmarshalJSONL(data []byte) ([]Run, error) {
+	stored, err := decodeRuns(data)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, len(stored))
+	for i, r := range stored {
+		runs[i] = r.run()
+	}
+	return runs, nil
+}
+
+// encodeRuns writes runs as JSONL, one per line
+func encodeRuns(runs []jsonRun) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, run := range runs {
+		enc.Encode(run)
+	}
+	return buf.Bytes()
+}
+
+func (r jsonRun) started() time.Time {
+	return r.StartedAt
+}
+
+func toJSONRun(id int64, run *Run) jsonRun {
+	r := jsonRun{
+		ID:               id,
+		PostType:         run.PostType,
+		Date:             run.Date,
+		StartedAt:        run.StartedAt,
+		FinishedAt:       run.FinishedAt,
+		Status:           run.Status,
+		Error:            run.Error,
+		Text:             run.Text,
+		PromptVariant:    run.PromptVariant,
+		Model:            run.Model,
+		PromptTokens:     run.PromptTokens,
+		CompletionTokens: run.CompletionTokens,
+		SystemPrompt:     run.SystemPrompt,
+		Prompt:           run.Prompt,
+		RawResponse:      run.RawResponse,
+
+		GenerationLatencyMS: run.GenerationLatency.Milliseconds(),
+		CorrelationID:       run.CorrelationID,
+	}
+	for _, result := range run.Results {
+		r.Results = append(r.Results, jsonResult{
+			Destination: result.Destination,
+			Link:        result.Link,
+			Error:       result.Error,
+			CID:         result.CID,
+			LatencyMS:   result.Latency.Milliseconds(),
+		})
+	}
+	return r
+}
+
+func (r jsonRun) run() Run {
+	run := Run{
+		ID:               r.ID,
+		PostType:         r.PostType,
+		Date:             r.Date,
+		StartedAt:        r.StartedAt,
+		FinishedAt:       r.FinishedAt,
+		Status:           r.Status,
+		Error:            r.Error,
+		Text:             r.Text,
+		PromptVariant:    r.PromptVariant,
+		Model:            r.Model,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		SystemPrompt:     r.SystemPrompt,
+		Prompt:           r.Prompt,
+		RawResponse:      r.RawResponse,
+
+		GenerationLatency: time.Duration(r.GenerationLatencyMS) * time.Millisecond,
+		CorrelationID:     r.CorrelationID,
+	}
+	for _, result := range r.Results {
+		run.Results = append(run.Results, Result{
+			Destination: result.Destination,
+			Link:        result.Link,
+			Error:       result.Error,
+			CID:         result.CID,
+			Latency:     time.Duration(result.LatencyMS) * time.Millisecond,
+		})
+	}
+	return run
+}
+
+// writeAtomic replaces the file at path with data, so a crash mid-write can't
+// corrupt it
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package store
+
+// FileLocking reports whether file stores take advisory locks, which this
+// platform doesn't have
+const FileLocking = false
+
+// lockFile is a no-op where advisory file locks aren't available, so only
+// one process should use a file store at a time
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
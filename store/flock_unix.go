@@ -0,0 +1,29 @@
+//go:build unix
+
+package store
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileLocking reports whether file stores take advisory locks, so a daemon
+// and a manual run can share one
+const FileLocking = true
+
+// lockFile takes an exclusive advisory lock on the file at path, waiting for
+// any other process holding it, and returns a function that releases it
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
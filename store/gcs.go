@@ -0,0 +1,156 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lukeocodes/go-trump/httpclient"
+	"github.com/lukeocodes/go-trump/lock"
+)
+
+const (
+	gcsAPIURL    = "https://storage.googleapis.com/storage/v1"
+	gcsUploadURL = "https://storage.googleapis.com/upload/storage/v1"
+
+	// metadataTokenURL hands out access tokens for the service account of
+	// the Cloud Run service, function or VM the bot runs on
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// gcsBucket represents a Google Cloud Storage bucket, whose conditional
+// writes compare object generations. It uses the JSON API directly.
+type gcsBucket struct {
+	client      httpclient.Doer
+	name        string
+	accessToken string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewGCS returns a store keeping runs in the object at key in the Google
+// Cloud Storage bucket. Requests are authorized with accessToken or, when
+// it's empty, as the service account from the metadata server. The requests
+// are made with client.
+func NewGCS(client httpclient.Doer, bucket, key, accessToken string) *Object {
+	return &Object{bucket: &gcsBucket{client: client, name: bucket, accessToken: accessToken}, key: key, owner: lock.NewOwner()}
+}
+
+func (b *gcsBucket) get(ctx context.Context, key string) ([]byte, string, error) {
+	u := fmt.Sprintf("%s/b/%s/o/%s?alt=media", gcsAPIURL, url.PathEscape(b.name), url.PathEscape(key))
+	resp, err := b.do(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get GCS object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", gcsError("get", resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read GCS object: %w", err)
+	}
+	return data, resp.Header.Get("X-Goog-Generation"), nil
+}
+
+func (b *gcsBucket) put(ctx context.Context, key string, data []byte, version string) error {
+	// Generation 0 matches only an object that doesn't exist yet
+	if version == "" {
+		version = "0"
+	}
+	query := url.Values{
+		"uploadType":        {"media"},
+		"name":              {key},
+		"ifGenerationMatch": {version},
+	}
+	u := fmt.Sprintf("%s/b/%s/o?%s", gcsUploadURL, url.PathEscape(b.name), query.Encode())
+
+	resp, err := b.do(ctx, "POST", u, data)
+	if err != nil {
+		return fmt.Errorf("failed to put GCS object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return errConflict
+	}
+	if resp.StatusCode != http.StatusOK {
+		return gcsError("put", resp)
+	}
+	return nil
+}
+
+func (b *gcsBucket) do(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	token, err := b.authToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/jsonl")
+	}
+	return b.client.Do(req)
+}
+
+// authToken returns the configured access token, or one from the metadata
+// server, cached until shortly before it expires
+func (b *gcsBucket) authToken(ctx context.Context) (string, error) {
+	if b.accessToken != "" {
+		return b.accessToken, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && time.Until(b.expiresAt) > time.Minute {
+		return b.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", metadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get an access token from the metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get an access token from the metadata server (%d)", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode metadata server token: %w", err)
+	}
+	b.token = token.AccessToken
+	b.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return b.token, nil
+}
+
+func gcsError(op string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("GCS %s error (%d): %s", op, resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration represents a numbered change to a store's schema, embedded from
+// a migrations/<backend>/NNNN_name.sql file
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Migrator is implemented by stores with a schema to keep up to date
+type Migrator interface {
+	// PendingMigrations lists the migrations not yet applied, oldest first
+	PendingMigrations(ctx context.Context) ([]Migration, error)
+
+	// Migrate applies the pending migrations in order, each in its own
+	// transaction, and returns the ones it applied
+	Migrate(ctx context.Context) ([]Migration, error)
+}
+
+// migrations returns a backend's embedded migrations, oldest first
+func migrations(backend string) []Migration {
+	dir := path.Join("migrations", backend)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		panic(fmt.Sprintf("no migrations for %s: %v", backend, err))
+	}
+
+	var all []Migration
+	for _, entry := range entries {
+		version, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		n, err := strconv.Atoi(version)
+		if !ok || err != nil || n < 1 {
+			panic(fmt.Sprintf("invalid migration file name %q, expected NNNN_name.sql", entry.Name()))
+		}
+		data, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		all = append(all, Migration{Version: n, Name: name, SQL: string(data)})
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i := 1; i < len(all); i++ {
+		if all[i].Version == all[i-1].Version {
+			panic(fmt.Sprintf("two %s migrations numbered %d", backend, all[i].Version))
+		}
+	}
+	return all
+}
+
+// pendingMigrations lists the migrations whose versions aren't in applied
+func pendingMigrations(all []Migration, applied map[int]bool) []Migration {
+	var pending []Migration
+	for _, m := range all {
+		if !applied[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
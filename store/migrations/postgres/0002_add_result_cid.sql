@@ -0,0 +1 @@
+ALTER TABLE results ADD COLUMN IF NOT EXISTS cid TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE runs
+	ADD COLUMN IF NOT EXISTS system_prompt TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS prompt TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS raw_response TEXT NOT NULL DEFAULT '';
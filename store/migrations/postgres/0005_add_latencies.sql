@@ -0,0 +1,2 @@
+ALTER TABLE runs ADD COLUMN IF NOT EXISTS generation_latency_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE results ADD COLUMN IF NOT EXISTS latency_ms BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS runs (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_type         TEXT NOT NULL,
+	date              TEXT NOT NULL,
+	started_at        TEXT NOT NULL,
+	finished_at       TEXT NOT NULL,
+	status            TEXT NOT NULL,
+	error             TEXT NOT NULL DEFAULT '',
+	text              TEXT NOT NULL DEFAULT '',
+	prompt_variant    TEXT NOT NULL DEFAULT '',
+	model             TEXT NOT NULL DEFAULT '',
+	prompt_tokens     INTEGER NOT NULL DEFAULT 0,
+	completion_tokens INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS runs_post_type_date ON runs (post_type, date);
+
+CREATE TABLE IF NOT EXISTS results (
+	run_id      INTEGER NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+	destination TEXT NOT NULL,
+	link        TEXT NOT NULL DEFAULT '',
+	error       TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (run_id, destination)
+);
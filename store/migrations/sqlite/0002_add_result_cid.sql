@@ -0,0 +1 @@
+ALTER TABLE results ADD COLUMN cid TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE runs ADD COLUMN system_prompt TEXT NOT NULL DEFAULT '';
+ALTER TABLE runs ADD COLUMN prompt TEXT NOT NULL DEFAULT '';
+ALTER TABLE runs ADD COLUMN raw_response TEXT NOT NULL DEFAULT '';
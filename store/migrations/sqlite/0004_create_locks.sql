@@ -0,0 +1,5 @@
+CREATE TABLE locks (
+	key        TEXT PRIMARY KEY,
+	owner      TEXT NOT NULL,
+	expires_at TEXT NOT NULL
+);
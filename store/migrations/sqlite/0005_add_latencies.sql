@@ -0,0 +1,2 @@
+ALTER TABLE runs ADD COLUMN generation_latency_ms INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE results ADD COLUMN latency_ms INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE runs ADD COLUMN correlation_id TEXT NOT NULL DEFAULT '';
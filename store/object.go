@@ -0,0 +1,219 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// objectWriteAttempts is how many times a save is retried when another
+// instance wrote the object in between reading and writing it
+const objectWriteAttempts = 5
+
+// errConflict is returned by a bucket when the object changed since it was read
+var errConflict = errors.New("object was modified concurrently")
+
+// bucket represents an object storage bucket with conditional writes
+type bucket interface {
+	// get returns an object and its version, or nil and "" when the object
+	// doesn't exist
+	get(ctx context.Context, key string) ([]byte, string, error)
+
+	// put writes an object only if it's still at version, or doesn't exist
+	// when version is "", returning errConflict otherwise
+	put(ctx context.Context, key string, data []byte, version string) error
+}
+
+// Object keeps runs in a single JSONL object in S3 or Google Cloud Storage,
+// for serverless deployments without a disk or database. Every save rewrites
+// the object with a conditional write, so concurrent saves are retried
+// rather than lost.
+//
+// Locks are objects of their own, named after the runs object with ".lock/"
+// and the lock's key appended.
+type Object struct {
+	bucket bucket
+	key    string
+	owner  string
+}
+
+func (o *Object) SaveRun(ctx context.Context, run *Run) error {
+	for attempt := 1; ; attempt++ {
+		id, err := o.appendRun(ctx, run)
+		if err == nil {
+			run.ID = id
+			return nil
+		}
+		if !errors.Is(err, errConflict) || attempt == objectWriteAttempts {
+			return fmt.Errorf("failed to save run: %w", err)
+		}
+	}
+}
+
+func (o *Object) appendRun(ctx context.Context, run *Run) (int64, error) {
+	data, version, err := o.bucket.get(ctx, o.key)
+	if err != nil {
+		return 0, err
+	}
+	runs, err := decodeRuns(data)
+	if err != nil {
+		return 0, err
+	}
+
+	id := int64(1)
+	if len(runs) > 0 {
+		id = runs[len(runs)-1].ID + 1
+	}
+	line, err := json.Marshal(toJSONRun(id, run))
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode run: %w", err)
+	}
+
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+	data = append(append(data, line...), '\n')
+	return id, o.bucket.put(ctx, o.key, data, version)
+}
+
+func (o *Object) Prune(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	for attempt := 1; ; attempt++ {
+		deleted, err := o.prune(ctx, cutoff, keep)
+		if err == nil {
+			return deleted, nil
+		}
+		if !errors.Is(err, errConflict) || attempt == objectWriteAttempts {
+			return 0, fmt.Errorf("failed to prune runs: %w", err)
+		}
+	}
+}
+
+func (o *Object) prune(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	data, version, err := o.bucket.get(ctx, o.key)
+	if err != nil {
+		return 0, err
+	}
+	runs, err := decodeRuns(data)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := retained(runs, jsonRun.started, cutoff, keep)
+	if len(kept) == len(runs) {
+		return 0, nil
+	}
+	return len(runs) - len(kept), o.bucket.put(ctx, o.key, encodeRuns(kept), version)
+}
+
+func (o *Object) RecentRuns(ctx context.Context, limit int) ([]Run, error) {
+	stored, err := o.runs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	for i := len(stored) - 1; i >= 0 && len(runs) < limit; i-- {
+		runs = append(runs, stored[i].run())
+	}
+	return runs, nil
+}
+
+func (o *Object) LastRun(ctx context.Context, postType string) (*Run, error) {
+	runs, err := o.RecentRuns(ctx, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	return lastPosted(runs, postType), nil
+}
+
+func (o *Object) RecentPosts(ctx context.Context, n int) ([]Run, error) {
+	runs, err := o.RecentRuns(ctx, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	return latestPosted(runs, "", n), nil
+}
+
+func (o *Object) PostedOn(ctx context.Context, postType, date string) (bool, error) {
+	runs, err := o.runs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, run := range runs {
+		if run.PostType == postType && run.Date == date && run.Status == StatusPosted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (o *Object) runs(ctx context.Context) ([]jsonRun, error) {
+	data, _, err := o.bucket.get(ctx, o.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	return decodeRuns(data)
+}
+
+// Lock creates the lock's object, or overwrites it if the holder's lock has
+// expired, with conditional writes so only one instance can succeed
+func (o *Object) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	lockKey := o.key + ".lock/" + key
+	data, version, err := o.bucket.get(ctx, lockKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if data != nil {
+		var held fileLock
+		if err := json.Unmarshal(data, &held); err != nil {
+			return false, fmt.Errorf("failed to decode lock: %w", err)
+		}
+		if held.ExpiresAt.After(time.Now()) {
+			return false, nil
+		}
+	}
+
+	data, err = json.Marshal(fileLock{Owner: o.owner, ExpiresAt: time.Now().Add(ttl)})
+	if err != nil {
+		return false, err
+	}
+	err = o.bucket.put(ctx, lockKey, data, version)
+	if errors.Is(err, errConflict) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return true, nil
+}
+
+// Unlock expires the lock's object, if this instance still holds it
+func (o *Object) Unlock(ctx context.Context, key string) error {
+	lockKey := o.key + ".lock/" + key
+	data, version, err := o.bucket.get(ctx, lockKey)
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	var held fileLock
+	if data == nil || json.Unmarshal(data, &held) != nil || held.Owner != o.owner {
+		return nil
+	}
+
+	data, err = json.Marshal(fileLock{Owner: o.owner})
+	if err != nil {
+		return err
+	}
+	if err := o.bucket.put(ctx, lockKey, data, version); err != nil && !errors.Is(err, errConflict) {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+func (o *Object) Close() error {
+	return nil
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lukeocodes/go-trump/lock"
+	"github.com/redis/go-redis/v9"
+)
+
+// unlockScript deletes a key only if this instance still holds it
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Redis keeps runs in a Redis list, newest first, for lightweight deployments
+// and replicated instances that already share a Redis server for locks
+type Redis struct {
+	client *redis.Client
+	prefix string
+	owner  string
+}
+
+// NewRedis connects to the Redis server at url, e.g. redis://localhost:6379/0,
+// prefixing its keys with prefix
+func NewRedis(url, prefix string) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	return &Redis{client: redis.NewClient(opts), prefix: prefix, owner: lock.NewOwner()}, nil
+}
+
+func (r *Redis) SaveRun(ctx context.Context, run *Run) error {
+	id, err := r.client.Incr(ctx, r.prefix+"runs:id").Result()
+	if err != nil {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+
+	data, err := json.Marshal(toJSONRun(id, run))
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+
+	// Posted runs are also marked by post type and date, so PostedOn needn't
+	// scan the list
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, r.prefix+"runs", data)
+		if run.Status == StatusPosted {
+			pipe.Set(ctx, r.postedKey(run.PostType, run.Date), id, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+
+	run.ID = id
+	return nil
+}
+
+func (r *Redis) RecentRuns(ctx context.Context, limit int) ([]Run, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	items, err := r.client.LRange(ctx, r.prefix+"runs", 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+
+	runs := make([]Run, 0, len(items))
+	for _, item := range items {
+		var stored jsonRun
+		if err := json.Unmarshal([]byte(item), &stored); err != nil {
+			return nil, fmt.Errorf("failed to decode run: %w", err)
+		}
+		runs = append(runs, stored.run())
+	}
+	return runs, nil
+}
+
+func (r *Redis) LastRun(ctx context.Context, postType string) (*Run, error) {
+	runs, err := r.RecentRuns(ctx, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	return lastPosted(runs, postType), nil
+}
+
+func (r *Redis) RecentPosts(ctx context.Context, n int) ([]Run, error) {
+	runs, err := r.RecentRuns(ctx, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	return latestPosted(runs, "", n), nil
+}
+
+func (r *Redis) PostedOn(ctx context.Context, postType, date string) (bool, error) {
+	n, err := r.client.Exists(ctx, r.postedKey(postType, date)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to read runs: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *Redis) postedKey(postType, date string) string {
+	return r.prefix + "posted:" + postType + ":" + date
+}
+
+func (r *Redis) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, r.prefix+"lock:"+key, r.owner, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *Redis) Unlock(ctx context.Context, key string) error {
+	if err := unlockScript.Run(ctx, r.client, []string{r.prefix + "lock:" + key}, r.owner).Err(); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// Prune trims the list to keep runs, then pops runs older than cutoff off
+// its oldest end
+func (r *Redis) Prune(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	key := r.prefix + "runs"
+	before, err := r.client.LLen(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune runs: %w", err)
+	}
+	if keep > 0 {
+		if err := r.client.LTrim(ctx, key, 0, int64(keep-1)).Err(); err != nil {
+			return 0, fmt.Errorf("failed to prune runs: %w", err)
+		}
+	}
+
+	for !cutoff.IsZero() {
+		item, err := r.client.LIndex(ctx, key, -1).Result()
+		if errors.Is(err, redis.Nil) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune runs: %w", err)
+		}
+		var oldest jsonRun
+		if err := json.Unmarshal([]byte(item), &oldest); err != nil {
+			return 0, fmt.Errorf("failed to decode run: %w", err)
+		}
+		if !oldest.StartedAt.Before(cutoff) {
+			break
+		}
+		// Only remove the run that was checked, in case another instance
+		// pruned it first
+		if err := r.client.LRem(ctx, key, -1, item).Err(); err != nil {
+			return 0, fmt.Errorf("failed to prune runs: %w", err)
+		}
+	}
+
+	after, err := r.client.LLen(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune runs: %w", err)
+	}
+	return int(max(before-after, 0)), nil
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
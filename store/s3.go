@@ -0,0 +1,80 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/lukeocodes/go-trump/lock"
+)
+
+// s3Bucket represents an S3 bucket, whose conditional writes compare ETags
+type s3Bucket struct {
+	client *s3.Client
+	name   string
+}
+
+// NewS3 returns a store keeping runs in the object at key in the S3 bucket,
+// with the default AWS config
+func NewS3(ctx context.Context, bucket, key string) (*Object, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return &Object{bucket: &s3Bucket{client: s3.NewFromConfig(cfg), name: bucket}, key: key, owner: lock.NewOwner()}, nil
+}
+
+func (b *s3Bucket) get(ctx context.Context, key string) ([]byte, string, error) {
+	out, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	})
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get S3 object: %w", err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read S3 object: %w", err)
+	}
+	return data, aws.ToString(out.ETag), nil
+}
+
+func (b *s3Bucket) put(ctx context.Context, key string, data []byte, version string) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(b.name),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/jsonl"),
+	}
+	if version == "" {
+		input.IfNoneMatch = aws.String("*")
+	} else {
+		input.IfMatch = aws.String(version)
+	}
+
+	_, err := b.client.PutObject(ctx, input)
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "PreconditionFailed", "ConditionalRequestConflict":
+			return errConflict
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to put S3 object: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/lukeocodes/go-trump/lock"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteLegacyVersion is the schema version of databases created before
+// there were migrations
+const sqliteLegacyVersion = 2
+
+// SQLite keeps runs in a SQLite database file
+type SQLite struct {
+	db    *sql.DB
+	owner string
+}
+
+// NewSQLite opens the SQLite database at path, creating the file if it
+// doesn't exist. Its tables are created by Migrate.
+func NewSQLite(path string) (*SQLite, error) {
+	// Wait on a busy database rather than failing, so a daemon and a manual
+	// run can share it
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store: %w", err)
+	}
+
+	return &SQLite{db: db, owner: lock.NewOwner()}, nil
+}
+
+func (s *SQLite) SaveRun(ctx context.Context, run *Run) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO runs
+		(post_type, date, started_at, finished_at, status, error, text, prompt_variant, model, prompt_tokens, completion_tokens,
+		system_prompt, prompt, raw_response, generation_latency_ms, correlation_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.PostType, run.Date, formatTime(run.StartedAt), formatTime(run.FinishedAt), run.Status, run.Error,
+		run.Text, run.PromptVariant, run.Model, run.PromptTokens, run.CompletionTokens,
+		run.SystemPrompt, run.Prompt, run.RawResponse, run.GenerationLatency.Milliseconds(), run.CorrelationID)
+	if err != nil {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+
+	for _, result := range run.Results {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO results (run_id, destination, link, cid, error, latency_ms) VALUES (?, ?, ?, ?, ?, ?)`,
+			id, result.Destination, result.Link, result.CID, result.Error, result.Latency.Milliseconds()); err != nil {
+			return fmt.Errorf("failed to save run result: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save run: %w", err)
+	}
+	run.ID = id
+	return nil
+}
+
+func (s *SQLite) RecentRuns(ctx context.Context, limit int) ([]Run, error) {
+	return s.queryRuns(ctx, `1 = 1`, limit)
+}
+
+func (s *SQLite) LastRun(ctx context.Context, postType string) (*Run, error) {
+	runs, err := s.queryRuns(ctx, `status = ? AND (? = '' OR post_type = ?)`, 1, StatusPosted, postType, postType)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+func (s *SQLite) RecentPosts(ctx context.Context, n int) ([]Run, error) {
+	return s.queryRuns(ctx, `status = ?`, n, StatusPosted)
+}
+
+// queryRuns returns up to limit of the latest runs matching where, newest
+// first, with their results
+func (s *SQLite) queryRuns(ctx context.Context, where string, limit int, args ...any) ([]Run, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT
+		id, post_type, date, started_at, finished_at, status, error, text, prompt_variant, model, prompt_tokens, completion_tokens,
+		system_prompt, prompt, raw_response, generation_latency_ms, correlation_id
+		FROM runs WHERE `+where+` ORDER BY id DESC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []Run
+	byID := map[int64]int{}
+	for rows.Next() {
+		var run Run
+		var startedAt, finishedAt string
+		var generationMS int64
+		if err := rows.Scan(&run.ID, &run.PostType, &run.Date, &startedAt, &finishedAt, &run.Status, &run.Error,
+			&run.Text, &run.PromptVariant, &run.Model, &run.PromptTokens, &run.CompletionTokens,
+			&run.SystemPrompt, &run.Prompt, &run.RawResponse, &generationMS, &run.CorrelationID); err != nil {
+			return nil, fmt.Errorf("failed to read runs: %w", err)
+		}
+		run.StartedAt = parseTime(startedAt)
+		run.FinishedAt = parseTime(finishedAt)
+		run.GenerationLatency = time.Duration(generationMS) * time.Millisecond
+		byID[run.ID] = len(runs)
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read runs: %w", err)
+	}
+	if len(runs) == 0 {
+		return runs, nil
+	}
+
+	results, err := s.db.QueryContext(ctx, `SELECT run_id, destination, link, cid, error, latency_ms FROM results
+		WHERE run_id BETWEEN ? AND ? ORDER BY run_id, destination`, runs[len(runs)-1].ID, runs[0].ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run results: %w", err)
+	}
+	defer results.Close()
+
+	for results.Next() {
+		var id, latencyMS int64
+		var result Result
+		if err := results.Scan(&id, &result.Destination, &result.Link, &result.CID, &result.Error, &latencyMS); err != nil {
+			return nil, fmt.Errorf("failed to read run results: %w", err)
+		}
+		result.Latency = time.Duration(latencyMS) * time.Millisecond
+		if i, ok := byID[id]; ok {
+			runs[i].Results = append(runs[i].Results, result)
+		}
+	}
+	if err := results.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read run results: %w", err)
+	}
+
+	return runs, nil
+}
+
+func (s *SQLite) PostedOn(ctx context.Context, postType, date string) (bool, error) {
+	var posted bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM runs WHERE post_type = ? AND date = ? AND status = ?)`,
+		postType, date, StatusPosted).Scan(&posted)
+	if err != nil {
+		return false, fmt.Errorf("failed to read runs: %w", err)
+	}
+	return posted, nil
+}
+
+func (s *SQLite) Lock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	// Take the lock if nobody holds it, or the holder's lock has expired
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx, `INSERT INTO locks (key, owner, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE locks.expires_at < ?`, key, s.owner, formatTime(now.Add(ttl)), formatTime(now))
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *SQLite) Unlock(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM locks WHERE key = ? AND owner = ?`, key, s.owner); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) Prune(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune runs: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Results go with their runs
+	var deleted int64
+	if !cutoff.IsZero() {
+		res, err := tx.ExecContext(ctx, `DELETE FROM runs WHERE started_at < ?`, formatTime(cutoff))
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune runs: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if keep > 0 {
+		res, err := tx.ExecContext(ctx, `DELETE FROM runs WHERE id NOT IN (SELECT id FROM runs ORDER BY id DESC LIMIT ?)`, keep)
+		if err != nil {
+			return 0, fmt.Errorf("failed to prune runs: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to prune runs: %w", err)
+	}
+	return int(deleted), nil
+}
+
+func (s *SQLite) PendingMigrations(ctx context.Context) ([]Migration, error) {
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pendingMigrations(migrations("sqlite"), applied), nil
+}
+
+func (s *SQLite) Migrate(ctx context.Context) ([]Migration, error) {
+	if err := s.baseline(ctx); err != nil {
+		return nil, fmt.Errorf("failed to migrate store: %w", err)
+	}
+
+	pending, err := s.PendingMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		ok, err := s.migrate(ctx, m)
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply migration %s: %w", m, err)
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+	return applied, nil
+}
+
+// migrate applies a migration and records it, unless another process got
+// there first
+func (s *SQLite) migrate(ctx context.Context, m Migration) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var done bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, m.Version).Scan(&done); err != nil {
+		return false, err
+	}
+	if done {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+		m.Version, m.Name, formatTime(time.Now())); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// baseline creates the migrations table. A database made before there were
+// migrations is brought up to date the old way and its migrations marked as
+// applied, since SQLite can't add a column only if it's missing.
+func (s *SQLite) baseline(ctx context.Context) error {
+	var legacy bool
+	err := s.db.QueryRowContext(ctx, `SELECT
+		EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'runs')
+		AND NOT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`).Scan(&legacy)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return err
+	}
+	if !legacy {
+		return nil
+	}
+
+	if err := s.addColumn(ctx, "results", "cid", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	for _, m := range migrations("sqlite")[:sqliteLegacyVersion] {
+		if _, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Name, formatTime(time.Now())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addColumn adds a column to a table if it doesn't have it already
+func (s *SQLite) addColumn(ctx context.Context, table, column, definition string) error {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// appliedMigrations returns the versions of the migrations already applied
+func (s *SQLite) appliedMigrations(ctx context.Context) (map[int]bool, error) {
+	applied := map[int]bool{}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	return applied, nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// Times are stored as RFC 3339 text in UTC, which sorts chronologically
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
@@ -0,0 +1,151 @@
+// Package store keeps a history of every run of the bot: what it generated,
+// with which prompt and model, and how publishing went at each destination
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Run statuses
+const (
+	StatusPosted  = "posted"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Run represents one attempt at a post, whatever its outcome
+type Run struct {
+	ID         int64
+	PostType   string
+	Date       string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Status     string
+
+	// CorrelationID is the random run_id that the run's log lines, metrics
+	// and audit entries carry, to pick its activity out of every sink
+	CorrelationID string
+
+	// Error says why the run failed or was skipped
+	Error string
+
+	// Text is the generated post, PromptVariant names the prompt that
+	// produced it, and Model and the token counts describe the completion
+	Text             string
+	PromptVariant    string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+
+	// GenerationLatency is how long the model took to complete the post
+	GenerationLatency time.Duration
+
+	// SystemPrompt and Prompt are the messages sent to the model exactly as
+	// rendered, and RawResponse its reply as received, to debug a bad post
+	// after the fact
+	SystemPrompt string
+	Prompt       string
+	RawResponse  string
+
+	// Results lists how publishing went at each destination
+	Results []Result
+}
+
+// Result represents the outcome of publishing a run's post to one destination
+type Result struct {
+	Destination string
+
+	// Link is where the post was published, such as a Bluesky AT URI, and
+	// Error why it wasn't
+	Link  string
+	Error string
+
+	// CID is the content hash of a Bluesky record, which replies and quotes
+	// need alongside its URI
+	CID string
+
+	// Latency is how long publishing to the destination took
+	Latency time.Duration
+}
+
+// Published reports whether the destination got the post
+func (r *Result) Published() bool {
+	return r.Error == ""
+}
+
+// Store represents somewhere runs are kept, and the locks that stop two
+// instances sharing it making the same post
+type Store interface {
+	// SaveRun records a run, setting its ID
+	SaveRun(ctx context.Context, run *Run) error
+
+	// RecentRuns returns up to limit of the latest runs, newest first
+	RecentRuns(ctx context.Context, limit int) ([]Run, error)
+
+	// LastRun returns the latest run of postType that posted, or of any
+	// type when postType is "", or nil if there's none
+	LastRun(ctx context.Context, postType string) (*Run, error)
+
+	// RecentPosts returns up to n of the latest runs that posted, newest
+	// first
+	RecentPosts(ctx context.Context, n int) ([]Run, error)
+
+	// PostedOn reports whether a run of postType posted on date, e.g.
+	// "2025-01-20"
+	PostedOn(ctx context.Context, postType, date string) (bool, error)
+
+	// Lock takes the lock on key for ttl, reporting false if another
+	// instance already holds it
+	Lock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Unlock gives up a lock this instance holds
+	Unlock(ctx context.Context, key string) error
+
+	Close() error
+}
+
+// Pruner is implemented by stores that can delete old runs
+type Pruner interface {
+	// Prune deletes the runs started before cutoff, unless it's zero, and
+	// all but the latest keep runs, unless keep is zero, returning how many
+	// it deleted
+	Prune(ctx context.Context, cutoff time.Time, keep int) (int, error)
+}
+
+// retained filters runs, oldest first, down to those Prune would keep
+func retained[T any](runs []T, startedAt func(T) time.Time, cutoff time.Time, keep int) []T {
+	var kept []T
+	for _, run := range runs {
+		if cutoff.IsZero() || !startedAt(run).Before(cutoff) {
+			kept = append(kept, run)
+		}
+	}
+	if keep > 0 && len(kept) > keep {
+		kept = kept[len(kept)-keep:]
+	}
+	return kept
+}
+
+// latestPosted filters runs, newest first, down to the first n that posted,
+// of postType unless it's ""
+func latestPosted(runs []Run, postType string, n int) []Run {
+	var posted []Run
+	for _, run := range runs {
+		if len(posted) == n {
+			break
+		}
+		if run.Status == StatusPosted && (postType == "" || run.PostType == postType) {
+			posted = append(posted, run)
+		}
+	}
+	return posted
+}
+
+// lastPosted returns the first run of latestPosted, or nil
+func lastPosted(runs []Run, postType string) *Run {
+	if posted := latestPosted(runs, postType, 1); len(posted) > 0 {
+		return &posted[0]
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// backends opens a store of each kind that keeps its data in the directory
+// path, so opening one twice gives two instances sharing it
+var backends = []struct {
+	name string
+	open func(t *testing.T, path string) Store
+}{
+	{"file", func(t *testing.T, path string) Store {
+		t.Helper()
+		s, err := NewFile(filepath.Join(path, "runs.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}},
+	{"sqlite", func(t *testing.T, path string) Store {
+		t.Helper()
+		s, err := NewSQLite(filepath.Join(path, "go-trump.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		if _, err := s.Migrate(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}},
+}
+
+// testRun returns a run of postType on date with status, started minute
+// minutes after 09:00 UTC that day
+func testRun(postType, date, status string, minute int) Run {
+	day, _ := time.Parse(time.DateOnly, date)
+	started := day.Add(9*time.Hour + time.Duration(minute)*time.Minute)
+	run := Run{
+		PostType:      postType,
+		Date:          date,
+		StartedAt:     started,
+		FinishedAt:    started.Add(3 * time.Second),
+		Status:        status,
+		CorrelationID: "run-" + date,
+	}
+	if status == StatusPosted {
+		run.Text = "123 days to go " + date
+		run.PromptVariant = "schedule " + postType
+		run.Model = "gpt-4o-mini"
+		run.PromptTokens = 120
+		run.CompletionTokens = 40
+		run.GenerationLatency = 1500 * time.Millisecond
+		run.SystemPrompt = "You write countdown posts."
+		run.Prompt = "Write today's post."
+		run.RawResponse = `{"choices":[]}`
+		run.Results = []Result{
+			{Destination: "bluesky", Link: "at://did:plc:abc/app.bsky.feed.post/1", CID: "bafy", Latency: 250 * time.Millisecond},
+			{Destination: "threads", Error: "unauthorized", Latency: 80 * time.Millisecond},
+		}
+	} else {
+		run.Error = "generation failed"
+	}
+	return run
+}
+
+func saveRuns(t *testing.T, s Store, runs ...Run) []Run {
+	t.Helper()
+	for i := range runs {
+		if err := s.SaveRun(context.Background(), &runs[i]); err != nil {
+			t.Fatalf("SaveRun() error = %v", err)
+		}
+	}
+	return runs
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			s := backend.open(t, t.TempDir())
+
+			if runs, err := s.RecentRuns(ctx, 10); err != nil || len(runs) != 0 {
+				t.Fatalf("RecentRuns() of an empty store = %v, %v", runs, err)
+			}
+			if run, err := s.LastRun(ctx, ""); err != nil || run != nil {
+				t.Fatalf("LastRun() of an empty store = %v, %v", run, err)
+			}
+
+			saved := saveRuns(t, s,
+				testRun("daily", "2026-10-13", StatusPosted, 0),
+				testRun("evening", "2026-10-13", StatusPosted, 1),
+				testRun("daily", "2026-10-14", StatusFailed, 0),
+				testRun("daily", "2026-10-14", StatusPosted, 2),
+				testRun("evening", "2026-10-14", StatusSkipped, 3),
+			)
+			for i := 1; i < len(saved); i++ {
+				if saved[i].ID <= saved[i-1].ID {
+					t.Fatalf("SaveRun() set IDs %d then %d, want increasing", saved[i-1].ID, saved[i].ID)
+				}
+			}
+
+			runs, err := s.RecentRuns(ctx, 3)
+			if err != nil {
+				t.Fatalf("RecentRuns() error = %v", err)
+			}
+			if want := []Run{saved[4], saved[3], saved[2]}; !reflect.DeepEqual(runs, want) {
+				t.Errorf("RecentRuns(3) = %+v, want %+v", runs, want)
+			}
+
+			lastTests := []struct {
+				postType string
+				want     Run
+			}{
+				{"", saved[3]},
+				{"daily", saved[3]},
+				{"evening", saved[1]},
+			}
+			for _, tt := range lastTests {
+				run, err := s.LastRun(ctx, tt.postType)
+				if err != nil || run == nil || !reflect.DeepEqual(*run, tt.want) {
+					t.Errorf("LastRun(%q) = %+v, %v, want %+v", tt.postType, run, err, tt.want)
+				}
+			}
+			if run, err := s.LastRun(ctx, "weekly_recap"); err != nil || run != nil {
+				t.Errorf("LastRun(weekly_recap) = %+v, %v, want nil", run, err)
+			}
+
+			posts, err := s.RecentPosts(ctx, 2)
+			if err != nil {
+				t.Fatalf("RecentPosts() error = %v", err)
+			}
+			if want := []Run{saved[3], saved[1]}; !reflect.DeepEqual(posts, want) {
+				t.Errorf("RecentPosts(2) = %+v, want %+v", posts, want)
+			}
+
+			postedTests := []struct {
+				postType string
+				date     string
+				want     bool
+			}{
+				{"daily", "2026-10-13", true},
+				{"daily", "2026-10-14", true},
+				{"evening", "2026-10-13", true},
+				{"evening", "2026-10-14", false},
+				{"daily", "2026-10-15", false},
+				{"weekly_recap", "2026-10-13", false},
+			}
+			for _, tt := range postedTests {
+				if got, err := s.PostedOn(ctx, tt.postType, tt.date); err != nil || got != tt.want {
+					t.Errorf("PostedOn(%q, %q) = %v, %v, want %v", tt.postType, tt.date, got, err, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFileConcurrentSaves(t *testing.T) {
+	if !FileLocking {
+		t.Skip("file stores don't lock on this platform")
+	}
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "runs.jsonl")
+
+	// Each writer is its own store, as separate processes would be
+	const writers, saves = 8, 5
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := NewFile(path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for i := range saves {
+				run := testRun("daily", fmt.Sprintf("2026-10-%02d", 1+i), StatusPosted, w)
+				if err := s.SaveRun(ctx, &run); err != nil {
+					t.Errorf("SaveRun() error = %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	s, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := s.RecentRuns(ctx, writers*saves+1)
+	if err != nil {
+		t.Fatalf("RecentRuns() error = %v", err)
+	}
+	if len(runs) != writers*saves {
+		t.Fatalf("RecentRuns() = %d runs, want %d", len(runs), writers*saves)
+	}
+	for i, run := range runs {
+		if want := int64(writers*saves - i); run.ID != want {
+			t.Errorf("run %d has ID %d, want %d", i, run.ID, want)
+		}
+	}
+}
+
+func TestFileLocksFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "runs.jsonl")
+	s, err := NewFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readLocks := func() map[string]fileLock {
+		t.Helper()
+		locks := map[string]fileLock{}
+		data, err := os.ReadFile(path + ".locks")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &locks); err != nil {
+			t.Fatal(err)
+		}
+		return locks
+	}
+
+	if ok, err := s.Lock(ctx, "2026-10-14/daily", time.Minute); err != nil || !ok {
+		t.Fatalf("Lock() = %v, %v, want true", ok, err)
+	}
+	held, ok := readLocks()["2026-10-14/daily"]
+	if !ok || held.Owner != s.owner || time.Until(held.ExpiresAt) <= 0 {
+		t.Errorf("locks file has %+v, want the lock held by %s until after now", held, s.owner)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Errorf("taking a lock created the runs file")
+	}
+
+	if err := s.Unlock(ctx, "2026-10-14/daily"); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if locks := readLocks(); len(locks) != 0 {
+		t.Errorf("locks file has %v after Unlock(), want none", locks)
+	}
+
+	if err := os.WriteFile(path+".locks", []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Lock(ctx, "2026-10-14/daily", time.Minute); err == nil {
+		t.Errorf("Lock() with a corrupt locks file succeeded")
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	ctx := context.Background()
+	cutoff := time.Date(2026, time.October, 13, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		cutoff      time.Time
+		keep        int
+		wantDeleted int
+		wantDates   []string
+	}{
+		{"nothing", time.Time{}, 0, 0, []string{"2026-10-14", "2026-10-13", "2026-10-12", "2026-10-11"}},
+		{"before cutoff", cutoff, 0, 2, []string{"2026-10-14", "2026-10-13"}},
+		{"keep latest", time.Time{}, 3, 1, []string{"2026-10-14", "2026-10-13", "2026-10-12"}},
+		{"cutoff and keep", cutoff, 1, 3, []string{"2026-10-14"}},
+		{"keep more than there are", time.Time{}, 10, 0, []string{"2026-10-14", "2026-10-13", "2026-10-12", "2026-10-11"}},
+	}
+
+	for _, backend := range backends {
+		for _, tt := range tests {
+			t.Run(backend.name+"/"+tt.name, func(t *testing.T) {
+				s := backend.open(t, t.TempDir())
+				saveRuns(t, s,
+					testRun("daily", "2026-10-11", StatusPosted, 0),
+					testRun("daily", "2026-10-12", StatusPosted, 0),
+					testRun("daily", "2026-10-13", StatusPosted, 0),
+					testRun("daily", "2026-10-14", StatusPosted, 0),
+				)
+
+				deleted, err := s.(Pruner).Prune(ctx, tt.cutoff, tt.keep)
+				if err != nil {
+					t.Fatalf("Prune() error = %v", err)
+				}
+				if deleted != tt.wantDeleted {
+					t.Errorf("Prune() deleted %d, want %d", deleted, tt.wantDeleted)
+				}
+
+				runs, err := s.RecentRuns(ctx, 10)
+				if err != nil {
+					t.Fatalf("RecentRuns() error = %v", err)
+				}
+				var dates []string
+				for _, run := range runs {
+					dates = append(dates, run.Date)
+				}
+				if !reflect.DeepEqual(dates, tt.wantDates) {
+					t.Errorf("after Prune() runs = %v, want %v", dates, tt.wantDates)
+				}
+			})
+		}
+	}
+}
+
+func TestStoreLocks(t *testing.T) {
+	ctx := context.Background()
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			dir := t.TempDir()
+			a := backend.open(t, dir)
+			b := backend.open(t, dir)
+
+			steps := []struct {
+				name string
+				do   func() (bool, error)
+				want bool
+			}{
+				{"a takes the lock", func() (bool, error) { return a.Lock(ctx, "2026-10-14/daily", time.Minute) }, true},
+				{"b can't take it", func() (bool, error) { return b.Lock(ctx, "2026-10-14/daily", time.Minute) }, false},
+				{"a can't take it twice", func() (bool, error) { return a.Lock(ctx, "2026-10-14/daily", time.Minute) }, false},
+				{"b takes another key", func() (bool, error) { return b.Lock(ctx, "2026-10-14/evening", time.Minute) }, true},
+				{"b's unlock leaves a's lock", func() (bool, error) { return true, b.Unlock(ctx, "2026-10-14/daily") }, true},
+				{"so b still can't take it", func() (bool, error) { return b.Lock(ctx, "2026-10-14/daily", time.Minute) }, false},
+				{"a unlocks", func() (bool, error) { return true, a.Unlock(ctx, "2026-10-14/daily") }, true},
+				{"then b takes it", func() (bool, error) { return b.Lock(ctx, "2026-10-14/daily", -time.Second) }, true},
+				{"and a takes it once it expired", func() (bool, error) { return a.Lock(ctx, "2026-10-14/daily", time.Minute) }, true},
+			}
+			for _, step := range steps {
+				got, err := step.do()
+				if err != nil {
+					t.Fatalf("%s: error = %v", step.name, err)
+				}
+				if got != step.want {
+					t.Fatalf("%s: got %v, want %v", step.name, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteMigrate(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "go-trump.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	all := migrations("sqlite")
+	for i, m := range all {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d is numbered %d, want %d", i, m.Version, i+1)
+		}
+	}
+
+	pending, err := s.PendingMigrations(ctx)
+	if err != nil {
+		t.Fatalf("PendingMigrations() error = %v", err)
+	}
+	if !reflect.DeepEqual(pending, all) {
+		t.Errorf("PendingMigrations() of a new database = %v, want %v", pending, all)
+	}
+
+	applied, err := s.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if !reflect.DeepEqual(applied, all) {
+		t.Errorf("Migrate() applied %v, want %v", applied, all)
+	}
+
+	if applied, err := s.Migrate(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("Migrate() again applied %v, %v, want none", applied, err)
+	}
+	if pending, err := s.PendingMigrations(ctx); err != nil || len(pending) != 0 {
+		t.Errorf("PendingMigrations() after Migrate() = %v, %v, want none", pending, err)
+	}
+}
+
+func TestSQLiteMigrateLegacy(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "go-trump.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// A database from before there were migrations has the first one's
+	// tables, without the second's column or a migrations table
+	if _, err := s.db.ExecContext(ctx, migrations("sqlite")[0].SQL); err != nil {
+		t.Fatalf("failed to create the legacy schema: %v", err)
+	}
+
+	applied, err := s.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if len(applied) == 0 || applied[0].Version != sqliteLegacyVersion+1 {
+		t.Errorf("Migrate() of a legacy database applied %v, want from version %d", applied, sqliteLegacyVersion+1)
+	}
+	saveRuns(t, s, testRun("daily", "2026-10-14", StatusPosted, 0))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/lukeocodes/go-trump/store"
+)
+
+// RunSummary represents the outcome of a run for automation wrapping the
+// bot, written as a line of JSON to summary_file
+type RunSummary struct {
+	RunID    string `json:"run_id"`
+	PostType string `json:"post_type"`
+	Date     string `json:"date"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+
+	// Text is the post as generated, before it's adapted for each
+	// destination, and DayCount the days left until Event
+	Text     string `json:"text,omitempty"`
+	Event    string `json:"event,omitempty"`
+	DayCount *int   `json:"day_count,omitempty"`
+
+	Model            string `json:"model,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+
+	Destinations []DestinationSummary `json:"destinations,omitempty"`
+	Durations    RunDurations         `json:"durations"`
+}
+
+// DestinationSummary represents how publishing went at one destination
+type DestinationSummary struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Link   string `json:"link,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RunDurations represents how long a run and its stages took, in
+// milliseconds. Generation and publishing add up every attempt.
+type RunDurations struct {
+	Total      int64 `json:"total_ms"`
+	Generation int64 `json:"generation_ms"`
+	Publishing int64 `json:"publishing_ms"`
+}
+
+// runStats collects what a run's summary reports beyond its record
+type runStats struct {
+	event    string
+	dayCount *int
+
+	generation time.Duration
+	publishing time.Duration
+}
+
+// noteGenerated notes what the post counts down to
+func (s *runStats) noteGenerated(post *OutgoingPost) {
+	if post.Event != "" {
+		s.event, s.dayCount = post.Event, &post.DaysLeft
+	}
+}
+
+// writeSummary appends the summary of a finished run to summary_file, or
+// writes it to stdout when that's "-". Failing to is only logged.
+func writeSummary(ctx context.Context, runID string, rec *store.Run, stats *runStats) {
+	path := conf.SummaryFile
+	if path == "" {
+		return
+	}
+
+	summary := RunSummary{
+		RunID:            runID,
+		PostType:         rec.PostType,
+		Date:             rec.Date,
+		Status:           rec.Status,
+		Error:            rec.Error,
+		Text:             rec.Text,
+		Event:            stats.event,
+		DayCount:         stats.dayCount,
+		Model:            rec.Model,
+		PromptTokens:     rec.PromptTokens,
+		CompletionTokens: rec.CompletionTokens,
+		Durations: RunDurations{
+			Total:      rec.FinishedAt.Sub(rec.StartedAt).Milliseconds(),
+			Generation: stats.generation.Milliseconds(),
+			Publishing: stats.publishing.Milliseconds(),
+		},
+	}
+	for _, result := range rec.Results {
+		dest := DestinationSummary{Name: result.Destination, Status: store.StatusPosted, Link: result.Link}
+		if !result.Published() {
+			dest.Status, dest.Error = store.StatusFailed, result.Error
+		}
+		summary.Destinations = append(summary.Destinations, dest)
+	}
+
+	line, err := json.Marshal(summary)
+	if err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to marshal the run summary", "err", err)
+		return
+	}
+	line = append(line, '\n')
+
+	if path == "-" {
+		os.Stdout.Write(line)
+		return
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to open the run summary file", "err", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(line); err != nil {
+		logger(logStore).ErrorContext(ctx, "Failed to write the run summary", "err", err)
+	}
+}
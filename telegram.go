@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// destinationTelegram is the destination type of a Telegram channel
+const destinationTelegram = "telegram"
+
+const telegramAPIURL = "https://api.telegram.org/bot"
+
+// Telegram's limits on the length of a message and of a photo caption
+const (
+	telegramMaxMessageLength = 4096
+	telegramMaxCaptionLength = 1024
+)
+
+// telegramConfig represents a telegram destination's own settings
+type telegramConfig struct {
+	// ChatID is the channel to post to, e.g. "@mychannel", and BotTokenEnv
+	// names the environment variable holding the bot token (default
+	// TELEGRAM_BOT_TOKEN)
+	ChatID      string `json:"chat_id"`
+	BotTokenEnv string `json:"bot_token_env"`
+}
+
+// telegramSettings decodes and checks a telegram destination's own settings
+func telegramSettings(destination *events.Destination) (telegramConfig, error) {
+	var c telegramConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if c.ChatID == "" {
+		return c, fmt.Errorf("telegram destination %q needs a chat_id", destination.Name)
+	}
+	if c.BotTokenEnv == "" {
+		c.BotTokenEnv = "TELEGRAM_BOT_TOKEN"
+	}
+	return c, nil
+}
+
+// telegramPublisher publishes to a Telegram channel through the Bot API
+type telegramPublisher struct {
+	adapter
+
+	name        string
+	chatID      string
+	botTokenEnv string
+}
+
+func init() {
+	registerPublisher(destinationTelegram, validateSettings(telegramSettings), factoryOf(newTelegramPublisher))
+}
+
+func newTelegramPublisher(destination *events.Destination) (*telegramPublisher, error) {
+	settings, err := telegramSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &telegramPublisher{
+		adapter:     newAdapter(destination, "Telegram", telegramMaxMessageLength, events.HashtagsKeep),
+		name:        destination.Name,
+		chatID:      settings.ChatID,
+		botTokenEnv: settings.BotTokenEnv,
+	}, nil
+}
+
+func (p *telegramPublisher) Name() string {
+	return p.name
+}
+
+// Adapt fits the post to the limit of a message, or the shorter one of a
+// photo caption
+func (p *telegramPublisher) Adapt(ctx context.Context, post *OutgoingPost) (*OutgoingPost, error) {
+	limit := p.maxLength
+	if post.Image != "" && limit > telegramMaxCaptionLength {
+		limit = telegramMaxCaptionLength
+	}
+	return p.adapt(ctx, post, limit), nil
+}
+
+// CheckCredentials asks the Bot API who the bot is, which fails for an
+// invalid token
+func (p *telegramPublisher) CheckCredentials(ctx context.Context) error {
+	token := config.Getenv(p.botTokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s environment variable not set", p.botTokenEnv)
+	}
+
+	ctx, cancel := httpRequest(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", telegramAPIURL+token+"/getMe", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Telegram request: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// The request URL contains the bot token, so don't repeat it
+		return fmt.Errorf("Telegram request failed: %w", errors.Unwrap(err))
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("telegram error (%d): the bot token was rejected", resp.StatusCode)}
+	}
+	return nil
+}
+
+// Publish sends the text as a message, or as the caption of a photo message
+// when the post has an image
+func (p *telegramPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	token := config.Getenv(p.botTokenEnv)
+	if token == "" {
+		return Published{}, fmt.Errorf("%s environment variable not set", p.botTokenEnv)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("chat_id", p.chatID)
+
+	method := "sendMessage"
+	if post.Image != "" {
+		method = "sendPhoto"
+		form.WriteField("caption", post.Text)
+
+		image, err := os.ReadFile(post.Image)
+		if err != nil {
+			return Published{}, fmt.Errorf("failed to read image: %w", err)
+		}
+		part, err := form.CreateFormFile("photo", filepath.Base(post.Image))
+		if err != nil {
+			return Published{}, fmt.Errorf("failed to attach image: %w", err)
+		}
+		part.Write(image)
+	} else {
+		form.WriteField("text", post.Text)
+	}
+	if err := form.Close(); err != nil {
+		return Published{}, fmt.Errorf("failed to build Telegram request: %w", err)
+	}
+
+	// Sending isn't cancelled by a shutdown signal once it starts
+	ctx, cancel := sendRequest(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", telegramAPIURL+token+"/"+method, &body)
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to create Telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// The request URL contains the bot token, so don't repeat it
+		return Published{}, fmt.Errorf("Telegram request failed: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			MessageID int `json:"message_id"`
+		} `json:"result"`
+	}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(bodyBytes, &result)
+	if resp.StatusCode != http.StatusOK || !result.OK {
+		return Published{}, &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("telegram error (%d): %s", resp.StatusCode, result.Description)}
+	}
+
+	// Messages in public channels have a link
+	if channel, ok := strings.CutPrefix(p.chatID, "@"); ok {
+		return Published{Link: fmt.Sprintf("https://t.me/%s/%d", channel, result.Result.MessageID)}, nil
+	}
+	return Published{}, nil
+}
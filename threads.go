@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// destinationThreads is the destination type of a Threads account
+const destinationThreads = "threads"
+
+const threadsAPIURL = "https://graph.threads.net/v1.0"
+
+// threadsMaxLength is the most characters a Threads post may have
+const threadsMaxLength = 500
+
+// threadsConfig represents a threads destination's own settings
+type threadsConfig struct {
+	// AccessTokenEnv names the environment variable holding the access token
+	// (default THREADS_ACCESS_TOKEN), and UserID the user to post as (default
+	// "me", the token's owner)
+	AccessTokenEnv string `json:"access_token_env"`
+	UserID         string `json:"user_id"`
+}
+
+// threadsSettings decodes a threads destination's own settings
+func threadsSettings(destination *events.Destination) (threadsConfig, error) {
+	var c threadsConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if c.AccessTokenEnv == "" {
+		c.AccessTokenEnv = "THREADS_ACCESS_TOKEN"
+	}
+	if c.UserID == "" {
+		c.UserID = "me"
+	}
+	return c, nil
+}
+
+// threadsPublisher publishes to Threads through the Graph API's two-step
+// flow: create a media container, then publish it
+type threadsPublisher struct {
+	adapter
+
+	name           string
+	userID         string
+	accessTokenEnv string
+}
+
+func init() {
+	registerPublisher(destinationThreads, validateSettings(threadsSettings), factoryOf(newThreadsPublisher))
+}
+
+func newThreadsPublisher(destination *events.Destination) (*threadsPublisher, error) {
+	settings, err := threadsSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &threadsPublisher{
+		adapter:        newAdapter(destination, "Threads", threadsMaxLength, events.HashtagsFirst),
+		name:           destination.Name,
+		userID:         settings.UserID,
+		accessTokenEnv: settings.AccessTokenEnv,
+	}, nil
+}
+
+func (p *threadsPublisher) Name() string {
+	return p.name
+}
+
+// Publish posts the text, with the image when it is publicly served, as
+// Threads fetches images by URL
+func (p *threadsPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	accessToken := config.Getenv(p.accessTokenEnv)
+	if accessToken == "" {
+		return Published{}, fmt.Errorf("%s environment variable not set", p.accessTokenEnv)
+	}
+
+	params := url.Values{
+		"media_type":   {"TEXT"},
+		"text":         {post.Text},
+		"access_token": {accessToken},
+	}
+	if post.ImageURL != "" {
+		params.Set("media_type", "IMAGE")
+		params.Set("image_url", post.ImageURL)
+		if post.ImageAlt != "" {
+			params.Set("alt_text", post.ImageAlt)
+		}
+	}
+
+	// Create the media container
+	var container struct {
+		ID string `json:"id"`
+	}
+	if err := p.call(ctx, "POST", p.userID+"/threads", params, &container); err != nil {
+		return Published{}, fmt.Errorf("failed to create Threads container: %w", err)
+	}
+
+	// Image containers are processed asynchronously
+	if post.ImageURL != "" {
+		if err := p.waitForContainer(ctx, container.ID, accessToken); err != nil {
+			return Published{}, err
+		}
+	}
+
+	// Publishing isn't cancelled by a shutdown signal once it starts
+	var published struct {
+		ID string `json:"id"`
+	}
+	publishParams := url.Values{
+		"creation_id":  {container.ID},
+		"access_token": {accessToken},
+	}
+	// Publishing isn't cancelled by a shutdown signal once it starts
+	ctx, cancel := detach(ctx)
+	defer cancel()
+	if err := p.call(ctx, "POST", p.userID+"/threads_publish", publishParams, &published); err != nil {
+		return Published{}, fmt.Errorf("failed to publish Threads container: %w", err)
+	}
+
+	// The permalink is only a nicety, so failing to look it up isn't fatal
+	var media struct {
+		Permalink string `json:"permalink"`
+	}
+	p.call(ctx, "GET", published.ID, url.Values{"fields": {"permalink"}, "access_token": {accessToken}}, &media)
+
+	return Published{Link: media.Permalink}, nil
+}
+
+// waitForContainer polls a media container until Threads has finished
+// processing it
+func (p *threadsPublisher) waitForContainer(ctx context.Context, id, accessToken string) error {
+	params := url.Values{
+		"fields":       {"status,error_message"},
+		"access_token": {accessToken},
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	for {
+		var status struct {
+			Status       string `json:"status"`
+			ErrorMessage string `json:"error_message"`
+		}
+		if err := p.call(ctx, "GET", id, params, &status); err != nil {
+			return fmt.Errorf("failed to check Threads container: %w", err)
+		}
+
+		switch status.Status {
+		case "FINISHED", "PUBLISHED":
+			return nil
+		case "ERROR", "EXPIRED":
+			return fmt.Errorf("Threads container %s: %s", strings.ToLower(status.Status), status.ErrorMessage)
+		}
+
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for Threads to process the image")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
+
+// call makes a Graph API request, decoding a successful response into out
+func (p *threadsPublisher) call(ctx context.Context, method, path string, params url.Values, out interface{}) error {
+	ctx, cancel := httpRequest(ctx)
+	defer cancel()
+
+	endpoint := threadsAPIURL + "/" + path
+	var body io.Reader
+	if method == "GET" {
+		endpoint += "?" + params.Encode()
+	} else {
+		body = strings.NewReader(params.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResponse struct {
+			Error struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errResponse)
+		return &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("threads error (%d): %s - %s", resp.StatusCode, errResponse.Error.Type, errResponse.Error.Message)}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+)
+
+// blueskyRequest bounds a request to Bluesky
+func blueskyRequest(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, conf.Timeouts.Bluesky)
+}
+
+// openAIRequest bounds a request to OpenAI, which gets longer than others by
+// default as generating a completion can be slow
+func openAIRequest(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, conf.Timeouts.OpenAI)
+}
+
+// httpRequest bounds any other outbound request, like alerts and on this day
+func httpRequest(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, conf.Timeouts.HTTP)
+}
+
+// detach returns a context that isn't cancelled with ctx, so a send that has
+// started finishes rather than being cut off by a shutdown signal, but that
+// keeps ctx's deadline, such as the publish deadline, so a hung destination
+// can't outlast it
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return context.WithCancel(detached)
+}
+
+// sendRequest bounds a request as httpRequest does, detached from ctx's
+// cancellation but not its deadline
+func sendRequest(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached, cancelDetached := detach(ctx)
+	ctx, cancel := httpRequest(detached)
+	return ctx, func() {
+		cancel()
+		cancelDetached()
+	}
+}
+
+// publishDeadline bounds publishing a post to every destination. It covers
+// rate limit waits as well as the requests themselves.
+func publishDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, conf.Timeouts.Publish)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+)
+
+func TestSendRequest(t *testing.T) {
+	saved := conf
+	t.Cleanup(func() { conf = saved })
+	conf = config.Default()
+	conf.Timeouts.HTTP = time.Hour
+
+	tests := []struct {
+		name         string
+		deadline     time.Duration
+		wantDeadline time.Duration
+	}{
+		{"no deadline", 0, time.Hour},
+		{"publish deadline sooner", time.Minute, time.Minute},
+		{"request timeout sooner", 2 * time.Hour, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent, cancelParent := context.WithCancel(context.Background())
+			if tt.deadline > 0 {
+				parent, cancelParent = context.WithTimeout(context.Background(), tt.deadline)
+			}
+
+			ctx, cancel := sendRequest(parent)
+			defer cancel()
+			cancelParent()
+			if err := ctx.Err(); err != nil {
+				t.Errorf("sendRequest() context err = %v after the parent was cancelled, want nil", err)
+			}
+
+			deadline, ok := ctx.Deadline()
+			if want := time.Now().Add(tt.wantDeadline); !ok || deadline.Sub(want).Abs() > time.Second {
+				t.Errorf("sendRequest() deadline = %v, want about %v", deadline, want)
+			}
+		})
+	}
+}
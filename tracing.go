@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of the pipeline. Until setupTracing installs an
+// exporter, they're discarded.
+var tracer = otel.Tracer("github.com/lukeocodes/go-trump")
+
+// shutdownTracing flushes the spans not yet exported, before the bot exits,
+// and forceFlushTracing flushes them without stopping the exporter
+var (
+	shutdownTracing   = func(context.Context) error { return nil }
+	forceFlushTracing = func(context.Context) error { return nil }
+)
+
+// setupTracing exports spans over OTLP/HTTP when OTEL_EXPORTER_OTLP_ENDPOINT
+// or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set. The exporter reads the other
+// standard OTEL_ variables itself, such as OTEL_EXPORTER_OTLP_HEADERS, and
+// OTEL_SERVICE_NAME overrides the service name, go-trump. They're read without
+// EnvPrefix, as the SDK reads them.
+func setupTracing(ctx context.Context) error {
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "go-trump")),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to describe the service for tracing: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	shutdownTracing, forceFlushTracing = provider.Shutdown, provider.ForceFlush
+	return nil
+}
+
+// startSpan starts a span of the pipeline, as a child of the span in ctx
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marking it failed with err unless err is nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// runMu serialises triggered runs, which share the global clock and state file
+var runMu sync.Mutex
+
+// RunResult represents the outcome of a run triggered by an invocation or request
+type RunResult struct {
+	PostType string `json:"post_type"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+}
+
+// triggerRun makes a single post of postType for an external trigger. Skipped
+// posts and a finished countdown are successful outcomes; anything else alerts
+// the operator and is returned as an error. force reposts a post type that was
+// already posted today.
+func triggerRun(ctx context.Context, postType string, force bool) (RunResult, error) {
+	if postType == "" {
+		postType = oneShotPostType()
+	}
+
+	runMu.Lock()
+	defer runMu.Unlock()
+
+	now = time.Now()
+
+	err := run(ctx, postType, force)
+	switch {
+	case errors.Is(err, errCountdownOver), errors.Is(err, errSkipPost):
+		logger(logServer).InfoContext(ctx, "Skipped triggered post", "post_type", postType, "reason", err)
+		return RunResult{PostType: postType, Status: "skipped", Message: err.Error()}, nil
+	case err != nil:
+		logger(logServer).ErrorContext(ctx, "Triggered post failed", "post_type", postType, "err", err)
+		alertOperator("Triggered post failed", err.Error())
+		return RunResult{PostType: postType, Status: "failed", Message: err.Error()}, err
+	}
+
+	return RunResult{PostType: postType, Status: "posted"}, nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// validationReport prints the outcome of each check made by config validate
+// and counts the failures
+type validationReport struct {
+	failures int
+	warnings int
+}
+
+func (r *validationReport) ok(check, format string, args ...any) {
+	fmt.Printf("ok    %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+func (r *validationReport) warn(check, format string, args ...any) {
+	r.warnings++
+	fmt.Printf("warn  %s: %s\n", check, fmt.Sprintf(format, args...))
+}
+
+func (r *validationReport) fail(check string, err error) {
+	r.failures++
+	fmt.Printf("FAIL  %s: %v\n", check, err)
+}
+
+// result prints the totals, and returns an error if any check failed
+func (r *validationReport) result() error {
+	fmt.Printf("\n%d failed, %d warnings\n", r.failures, r.warnings)
+	if r.failures > 0 {
+		return fmt.Errorf("%d checks failed", r.failures)
+	}
+	return nil
+}
+
+// promptTemplate represents a prompt template that a post could use
+type promptTemplate struct {
+	name string
+	tmpl string
+	data func(*PromptData)
+}
+
+// runConfigValidate checks everything a run depends on and prints a report,
+// so a mistake shows up before the next scheduled post rather than at it.
+// With online set, it also signs in to OpenAI, the store and every
+// destination that can check its credentials without publishing.
+func runConfigValidate(ctx context.Context, path string, online bool) error {
+	var r validationReport
+
+	switch {
+	case path != "" && confProfile != "":
+		r.ok("settings", "loaded from %s, profile %s", path, confProfile)
+	case path != "":
+		r.ok("settings", "loaded from %s", path)
+	default:
+		r.ok("settings", "loaded from the environment")
+	}
+
+	cfg, err := loadEvents()
+	if err != nil {
+		r.fail("events", err)
+		return r.result()
+	}
+	validateEvents(&r, cfg)
+	validateTemplates(ctx, &r, cfg)
+
+	entries, err := loadSchedules()
+	if err != nil {
+		r.fail("schedules", err)
+	}
+	for _, entry := range entries {
+		r.ok("schedule "+entry.Name, "next post at %s", entry.Next(time.Now()).Format(time.RFC1123))
+	}
+
+	pubs, primary, err := publishers(cfg)
+	if err != nil {
+		r.fail("destinations", err)
+	}
+	for _, pub := range pubs {
+		check := "destination " + pub.Name()
+		if !online {
+			r.ok(check, "configured")
+			continue
+		}
+		checker, ok := unwrapPublisher(pub).(credentialChecker)
+		if !ok {
+			r.ok(check, "configured, its credentials can't be checked without publishing")
+			continue
+		}
+		if err := checker.CheckCredentials(ctx); err != nil {
+			if primary[pub.Name()] {
+				r.fail(check, err)
+			} else {
+				r.warn(check, "%v", err)
+			}
+			continue
+		}
+		r.ok(check, "signed in")
+	}
+
+	if online {
+		validateProviders(ctx, &r)
+	}
+
+	return r.result()
+}
+
+// validateEvents reports the next event and warns about events whose dates
+// have passed, which will never be counted down to
+func validateEvents(r *validationReport, cfg *events.File) {
+	upcoming := events.Upcoming(cfg.Events, now)
+
+	active := map[string]bool{}
+	for _, e := range upcoming {
+		active[e.Name] = true
+	}
+	for _, e := range cfg.Events {
+		if e.IsEnabled() && !active[e.Name] && !e.CountsUp() {
+			r.warn("event "+e.Name, "%s has passed, so it is never posted about", e.Time().Format("January 2, 2006"))
+		}
+	}
+
+	if len(upcoming) == 0 {
+		r.warn("events", "no event is upcoming, so the countdown is over")
+		return
+	}
+	next := newCountdown(&upcoming[0], cfg)
+	r.ok("events", "%d events, next is %s on %s", len(cfg.Events), next.Name, next.TargetDate)
+}
+
+// validateTemplates renders every prompt template a post could use against
+// today's countdown, so a typo fails here rather than on the day it's used
+func validateTemplates(ctx context.Context, r *validationReport, cfg *events.File) {
+	upcoming := events.Upcoming(cfg.Events, now)
+	if len(upcoming) == 0 {
+		final, ok := events.Last(cfg.Events)
+		if !ok {
+			return
+		}
+		upcoming = []events.Event{*final}
+	}
+
+	// Rendering needn't look up a fact for today
+	offline := *cfg
+	offline.OnThisDay = false
+	data := newPromptData(ctx, &offline, upcoming)
+
+	templates := []promptTemplate{{name: "prompt", tmpl: schedulePrompt(cfg, &events.Schedule{Name: events.PostTypeDaily})}}
+	for i := range cfg.Schedules {
+		schedule := &cfg.Schedules[i]
+		templates = append(templates, promptTemplate{name: "schedule " + schedule.Name, tmpl: schedulePrompt(cfg, schedule)})
+	}
+	for _, milestone := range cfg.Milestones {
+		label := milestone.Label(data.milestoneNumber)
+		templates = append(templates, promptTemplate{
+			name: "milestone " + label,
+			tmpl: cmp.Or(milestone.Prompt, defaultMilestonePrompt),
+			data: func(d *PromptData) { d.Milestone = label },
+		})
+	}
+	for _, special := range cfg.SpecialDays {
+		templates = append(templates, promptTemplate{
+			name: "special day " + special.Name,
+			tmpl: special.Prompt,
+			data: func(d *PromptData) { d.Special = special.Name },
+		})
+	}
+	if conf.Generator == generatorMock {
+		templates = append(templates, promptTemplate{name: "mock_text", tmpl: cmp.Or(conf.MockText, defaultMockText)})
+	}
+	if cfg.OnComplete != nil && cfg.OnComplete.Prompt != "" {
+		templates = append(templates, promptTemplate{
+			name: "on_complete",
+			tmpl: cfg.OnComplete.Prompt,
+			data: func(d *PromptData) { d.WrapUpDay, d.WrapUpDays = 1, cfg.OnComplete.SeriesDays() },
+		})
+	}
+
+	failed := false
+	for _, t := range templates {
+		d := data
+		if t.data != nil {
+			t.data(&d)
+		}
+		if _, err := renderPrompt(t.tmpl, d); err != nil {
+			r.fail("template "+t.name, err)
+			failed = true
+		}
+	}
+	if !failed {
+		r.ok("templates", "%d prompt templates render", len(templates))
+	}
+}
+
+// validateProviders checks OpenAI accepts the API key and the store can be read
+func validateProviders(ctx context.Context, r *validationReport) {
+	ctx, cancel := httpRequest(ctx)
+	defer cancel()
+
+	if conf.Generator == generatorMock {
+		r.ok("openai", "not used by the mock generator")
+	} else if key := config.Getenv("OPENAI_API_KEY"); key == "" {
+		r.fail("openai", errors.New("OPENAI_API_KEY environment variable not set"))
+	} else if status := probe(ctx, openAIClient, openAIModelsURL, key); status != "ok" {
+		r.fail("openai", errors.New(status))
+	} else {
+		r.ok("openai", "API key accepted")
+	}
+
+	s, err := openStore(ctx)
+	switch {
+	case err != nil:
+		r.fail("store", err)
+	case s == nil:
+		r.ok("store", "none")
+	default:
+		if _, err := s.RecentRuns(ctx, 1); err != nil {
+			r.fail("store", err)
+		} else {
+			r.ok("store", "%s is readable", conf.Store.Backend)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+// Package vcr records the HTTP requests a test makes of real APIs, and their
+// responses, in a cassette file, then replays them in later runs of the test
+// without the network or credentials. A replayed request that doesn't match
+// what was recorded fails the test, so a change to the shape of a request
+// shows up as soon as the tests run.
+//
+// Tests replay by default. Run them with VCR_MODE=record, and real
+// credentials, to record their cassettes afresh.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// ModeEnv is the environment variable that picks the mode, "record" or
+// "replay"
+const ModeEnv = "VCR_MODE"
+
+// volatile replaces the values of volatile fields in what's recorded
+const volatile = "<volatile>"
+
+// Cassette represents the interactions recorded for a test
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction represents a request and the response it got
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request represents a recorded request
+type Request struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"`
+	Body
+}
+
+// Response represents a recorded response
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body
+}
+
+// Body represents a recorded body: JSON as it is, other text as a string and
+// anything else, such as an image, as base64
+type Body struct {
+	JSON   json.RawMessage `json:"json,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	Binary []byte          `json:"binary,omitempty"`
+}
+
+// bytes returns the body as it was sent
+func (b Body) bytes() []byte {
+	switch {
+	case b.JSON != nil:
+		return b.JSON
+	case b.Text != "":
+		return []byte(b.Text)
+	}
+	return b.Binary
+}
+
+// Recorder is a transport that records the requests made through it in its
+// cassette, or replays the responses recorded there
+type Recorder struct {
+	// Sanitize redacts credentials from URLs and bodies before they're
+	// recorded or compared. Headers other than Content-Type are never
+	// recorded.
+	Sanitize func(string) string
+
+	// Volatile names the JSON fields, such as createdAt, whose values
+	// differ every time and so aren't recorded or compared
+	Volatile []string
+
+	// Next makes the requests while recording (default
+	// http.DefaultTransport)
+	Next http.RoundTripper
+
+	t      testing.TB
+	path   string
+	record bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New returns a recorder for the cassette at path, such as
+// testdata/cassettes/post.json. When replaying, the test fails if the cassette
+// is missing or the test didn't make every request it recorded. When
+// recording, the cassette is written once the test ends.
+func New(t testing.TB, path string) *Recorder {
+	r := &Recorder{t: t, path: path, record: os.Getenv(ModeEnv) == "record"}
+	if r.record {
+		t.Cleanup(r.save)
+		return r
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("vcr: failed to read cassette, record it with %s=record: %v", ModeEnv, err)
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		t.Fatalf("vcr: failed to decode cassette %s: %v", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	t.Cleanup(r.checkUsed)
+	return r
+}
+
+// Recording reports whether the recorder is recording real requests, for
+// tests that need real credentials to
+func (r *Recorder) Recording() bool {
+	return r.record
+}
+
+// Client returns a client that makes its requests through the recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := Request{
+		Method:      req.Method,
+		URL:         r.sanitize(req.URL.String()),
+		ContentType: req.Header.Get("Content-Type"),
+		Body:        r.body(body),
+	}
+
+	if r.record {
+		return r.recordRequest(req, recorded)
+	}
+	return r.replay(req, recorded)
+}
+
+// recordRequest makes req and records it with its response
+func (r *Recorder) recordRequest(req *http.Request, recorded Request) (*http.Response, error) {
+	next := r.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: Response{
+			Status:      resp.StatusCode,
+			ContentType: resp.Header.Get("Content-Type"),
+			Body:        r.body(body),
+		},
+	})
+	return resp, nil
+}
+
+// replay responds to req as the first unused interaction that matches it was
+// responded to
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var mismatch *Request
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Request.Method != recorded.Method || interaction.Request.URL != recorded.URL {
+			continue
+		}
+		if interaction.Request.ContentType != recorded.ContentType || !sameBody(interaction.Request.Body, recorded.Body) {
+			if mismatch == nil {
+				mismatch = &r.cassette.Interactions[i].Request
+			}
+			continue
+		}
+
+		r.used[i] = true
+		resp := interaction.Response
+		header := http.Header{}
+		if resp.ContentType != "" {
+			header.Set("Content-Type", resp.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+			StatusCode:    resp.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(resp.bytes())),
+			ContentLength: int64(len(resp.bytes())),
+			Request:       req,
+		}, nil
+	}
+
+	var err error
+	if mismatch != nil {
+		err = fmt.Errorf("vcr: %s %s doesn't match the recorded request\n got: %s %s\nwant: %s %s",
+			recorded.Method, recorded.URL, recorded.ContentType, recorded.bytes(), mismatch.ContentType, mismatch.bytes())
+	} else {
+		err = fmt.Errorf("vcr: %s %s wasn't recorded in %s", recorded.Method, recorded.URL, r.path)
+	}
+	r.t.Error(err)
+	return nil, err
+}
+
+// body sanitizes a body to be recorded or compared
+func (r *Recorder) body(data []byte) Body {
+	if len(data) == 0 {
+		return Body{}
+	}
+	if !utf8.Valid(data) {
+		return Body{Binary: data}
+	}
+	text := r.sanitize(string(data))
+
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return Body{Text: text}
+	}
+	var normalized bytes.Buffer
+	enc := json.NewEncoder(&normalized)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(r.blank(value)); err != nil {
+		return Body{Text: text}
+	}
+	return Body{JSON: bytes.TrimSpace(normalized.Bytes())}
+}
+
+// blank replaces the values of the volatile fields in a decoded JSON value
+func (r *Recorder) blank(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if r.isVolatile(key) {
+				v[key] = volatile
+			} else {
+				v[key] = r.blank(field)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = r.blank(v[i])
+		}
+	}
+	return value
+}
+
+func (r *Recorder) isVolatile(field string) bool {
+	for _, name := range r.Volatile {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recorder) sanitize(s string) string {
+	if r.Sanitize == nil {
+		return s
+	}
+	return r.Sanitize(s)
+}
+
+// sameBody reports whether two recorded bodies are the same, comparing JSON
+// by its values rather than its formatting
+func sameBody(a, b Body) bool {
+	if a.JSON == nil || b.JSON == nil {
+		return bytes.Equal(a.bytes(), b.bytes())
+	}
+	var av, bv any
+	if json.Unmarshal(a.JSON, &av) != nil || json.Unmarshal(b.JSON, &bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
+
+// save writes the recorded cassette
+func (r *Recorder) save() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.t.Failed() || r.t.Skipped() {
+		r.t.Logf("vcr: not saving %s, as the test didn't pass", r.path)
+		return
+	}
+
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(r.cassette)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(r.path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(r.path, data.Bytes(), 0o644)
+	}
+	if err != nil {
+		r.t.Errorf("vcr: failed to save cassette: %v", err)
+	}
+}
+
+// checkUsed fails the test if it didn't make every request recorded
+func (r *Recorder) checkUsed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var missing []error
+	for i, used := range r.used {
+		if !used {
+			req := r.cassette.Interactions[i].Request
+			missing = append(missing, fmt.Errorf("%s %s", req.Method, req.URL))
+		}
+	}
+	if len(missing) > 0 {
+		r.t.Errorf("vcr: recorded requests weren't made: %v", errors.Join(missing...))
+	}
+}
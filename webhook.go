@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lukeocodes/go-trump/config"
+	"github.com/lukeocodes/go-trump/events"
+)
+
+// destinationWebhook is the destination type of downstream automations
+const destinationWebhook = "webhook"
+
+// webhookConfig represents a webhook destination's own settings
+type webhookConfig struct {
+	// URLs are where the payload is posted, signed with the secret in the
+	// environment variable named by SecretEnv (default WEBHOOK_SECRET)
+	URLs      []string `json:"urls"`
+	SecretEnv string   `json:"secret_env"`
+}
+
+// webhookSettings decodes and checks a webhook destination's own settings
+func webhookSettings(destination *events.Destination) (webhookConfig, error) {
+	var c webhookConfig
+	if err := destination.Decode(&c); err != nil {
+		return c, err
+	}
+	if len(c.URLs) == 0 {
+		return c, fmt.Errorf("webhook destination %q needs urls", destination.Name)
+	}
+	if c.SecretEnv == "" {
+		c.SecretEnv = "WEBHOOK_SECRET"
+	}
+	return c, nil
+}
+
+// WebhookPayload represents the JSON body webhook destinations receive
+type WebhookPayload struct {
+	Text        string            `json:"text"`
+	PostType    string            `json:"post_type"`
+	Date        string            `json:"date"`
+	Event       string            `json:"event,omitempty"`
+	DaysLeft    int               `json:"days_left"`
+	ImageURL    string            `json:"image_url,omitempty"`
+	URIs        map[string]string `json:"uris"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	SentAt      time.Time         `json:"sent_at"`
+}
+
+// webhookPublisher posts a signed JSON payload to configured URLs, for
+// downstream automations. It goes after the other destinations so it can
+// include where the post was published.
+type webhookPublisher struct {
+	adapter
+
+	name      string
+	urls      []string
+	secretEnv string
+}
+
+func init() {
+	registerPublisher(destinationWebhook, validateSettings(webhookSettings), factoryOf(newWebhookPublisher))
+}
+
+func newWebhookPublisher(destination *events.Destination) (*webhookPublisher, error) {
+	settings, err := webhookSettings(destination)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookPublisher{
+		adapter:   newAdapter(destination, "webhook", 0, events.HashtagsKeep),
+		name:      destination.Name,
+		urls:      settings.URLs,
+		secretEnv: settings.SecretEnv,
+	}, nil
+}
+
+func (p *webhookPublisher) Name() string {
+	return p.name
+}
+
+func (p *webhookPublisher) ReportsLinks() bool {
+	return true
+}
+
+// Publish posts the payload to every URL. Requests are signed with an HMAC
+// SHA-256 of the timestamp and body, in the X-Go-Trump-Timestamp and
+// X-Go-Trump-Signature headers, when a secret is configured.
+func (p *webhookPublisher) Publish(ctx context.Context, post *OutgoingPost) (Published, error) {
+	uris := post.Links
+	if uris == nil {
+		uris = map[string]string{}
+	}
+
+	sentAt := time.Now().UTC()
+	bodyBytes, err := json.Marshal(WebhookPayload{
+		Text:        post.Text,
+		PostType:    post.PostType,
+		Date:        post.Date,
+		Event:       post.Event,
+		DaysLeft:    post.DaysLeft,
+		ImageURL:    post.ImageURL,
+		URIs:        uris,
+		GeneratedAt: post.GeneratedAt,
+		SentAt:      sentAt,
+	})
+	if err != nil {
+		return Published{}, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(sentAt.Unix(), 10)
+	var signature string
+	if secret := config.Getenv(p.secretEnv); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(bodyBytes)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var errs []error
+	for _, url := range p.urls {
+		if err := p.send(ctx, url, bodyBytes, timestamp, signature); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return Published{}, errors.Join(errs...)
+}
+
+func (p *webhookPublisher) send(ctx context.Context, url string, body []byte, timestamp, signature string) error {
+	ctx, cancel := sendRequest(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Go-Trump-Timestamp", timestamp)
+	if signature != "" {
+		req.Header.Set("X-Go-Trump-Signature", signature)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("webhook error (%d) from %s", resp.StatusCode, url)}
+	}
+	return nil
+}